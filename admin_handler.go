@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/afman42/go-web-code-interactive/internal/judge"
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+	"github.com/afman42/go-web-code-interactive/internal/problempkg"
+)
+
+// MaxPackageSize bounds the zip archive of an imported problem package
+const MaxPackageSize = 32 << 20
+
+// Generated cases of a stress run
+const (
+	DefaultStressCases = 1000
+	MaxStressCases     = 10000
+)
+
+// StressRequest is a solution to stress test against generated cases, with
+// the seeds seed, seed+1, ... seed+cases-1
+type StressRequest struct {
+	Language string `json:"lang"`
+	Code     string `json:"txt"`
+	Cases    int    `json:"cases"`
+	Seed     int64  `json:"seed"`
+}
+
+// VerificationResponse is returned when a problem's reference solutions do
+// not pass its own tests
+type VerificationResponse struct {
+	StatusCode int                     `json:"statusCode"`
+	Message    string                  `json:"message"`
+	Results    map[string]judge.Result `json:"results"`
+}
+
+// requireAdmin checks the bearer token of the request against ADMIN_TOKEN.
+// The admin API stays disabled while no token is configured.
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if adminToken == "" {
+		writeError(w, http.StatusForbidden, "admin API is disabled")
+		return false
+	}
+	if !isAdmin(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, "invalid admin token")
+		return false
+	}
+	return true
+}
+
+// isAdmin reports whether the request carries the admin token
+func isAdmin(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+}
+
+// saveProblem generates the tests of a problem's generator seeds, verifies
+// the problem with its reference solutions and stores it as a new version,
+// answering the request either way. With create the problem must not exist
+// yet; that is checked as it is stored, since verifying takes a while.
+func saveProblem(w http.ResponseWriter, p problem.Problem, create bool) {
+	p, err := problem.Prepare(p)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	warm := false
+	if p, err = judge.Expand(p, judgePreparer(&warm)); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if results, err := judge.Verify(p, judgePreparer(&warm)); err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, VerificationResponse{
+			StatusCode: http.StatusUnprocessableEntity,
+			Message:    err.Error(),
+			Results:    results,
+		})
+		return
+	}
+	var save func(problem.Problem) error
+	if problemStore != nil {
+		save = problemStore.Save
+	}
+	p, err = problemBank.Put(p, create, save)
+	if err == problem.ErrExists {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	status := http.StatusOK
+	if create {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, p)
+}
+
+// adminProblems handles GET (list) and POST (create) on /api/admin/problems
+func adminProblems(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Allow", "GET, POST, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, problemBank.List())
+	case http.MethodPost:
+		var p problem.Problem
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if _, exists := problemBank.Get(p.ID); exists {
+			writeError(w, http.StatusConflict, problem.ErrExists.Error())
+			return
+		}
+		saveProblem(w, p, true)
+	default:
+		methodNotAllowed(w, "GET, POST, OPTIONS")
+	}
+}
+
+// adminProblemImport handles POST /api/admin/problems/import with a problem
+// package as a zip archive body. Problems the package does not name take
+// ?id=. The problem is verified like any other save, creating it or adding a
+// new version. An import racing a create of the same problem gets a 409.
+func adminProblemImport(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, "POST")
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxPackageSize))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, "problem package is too large")
+		return
+	}
+	fsys, err := problempkg.OpenZip(data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	p, err := problempkg.Read(fsys, r.URL.Query().Get("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	_, exists := problemBank.Get(p.ID)
+	saveProblem(w, p, !exists)
+}
+
+// adminProblemPackage handles GET /api/admin/problems/{id}/package, which
+// exports a problem as a zip archive, an earlier version with ?version=N
+func adminProblemPackage(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, "GET")
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	id := r.PathValue("id")
+	p, ok := problemBank.Get(id)
+	if v := r.URL.Query().Get("version"); v != "" {
+		version, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid version")
+			return
+		}
+		p, ok = problemBank.Version(id, version)
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "problem not found")
+		return
+	}
+	var archive bytes.Buffer
+	if err := problempkg.WriteZip(&archive, p); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+p.ID+"-v"+strconv.Itoa(p.Version)+`.zip"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive.Bytes())
+}
+
+// adminProblemByID handles GET, PUT and DELETE on /api/admin/problems/{id}.
+// GET returns an earlier version with ?version=N.
+func adminProblemByID(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Allow", "GET, PUT, DELETE, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	id := r.PathValue("id")
+	switch r.Method {
+	case http.MethodGet:
+		p, ok := problemBank.Get(id)
+		if v := r.URL.Query().Get("version"); v != "" {
+			version, err := strconv.Atoi(v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid version")
+				return
+			}
+			p, ok = problemBank.Version(id, version)
+		}
+		if !ok {
+			writeError(w, http.StatusNotFound, "problem not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, p)
+	case http.MethodPut:
+		var p problem.Problem
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if p.ID != "" && p.ID != id {
+			writeError(w, http.StatusBadRequest, "problem id does not match the URL")
+			return
+		}
+		if _, exists := problemBank.Get(id); !exists {
+			writeError(w, http.StatusNotFound, "problem not found")
+			return
+		}
+		p.ID = id
+		saveProblem(w, p, false)
+	case http.MethodDelete:
+		var save func(string) error
+		if problemStore != nil {
+			save = problemStore.Delete
+		}
+		existed, err := problemBank.Delete(id, save)
+		if !existed {
+			writeError(w, http.StatusNotFound, "problem not found")
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w, "GET, PUT, DELETE, OPTIONS")
+	}
+}
+
+// adminProblemVersions handles GET /api/admin/problems/{id}/versions
+func adminProblemVersions(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, "GET")
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	versions := problemBank.Versions(r.PathValue("id"))
+	if len(versions) == 0 {
+		writeError(w, http.StatusNotFound, "problem not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, versions)
+}
+
+// adminProblemStress handles POST /api/admin/problems/{id}/stress, which runs
+// a solution against generated cases and reports the smallest failing input
+func adminProblemStress(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, "POST")
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	p, ok := problemBank.Get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "problem not found")
+		return
+	}
+	req := StressRequest{Cases: DefaultStressCases, Seed: 1}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Cases <= 0 || req.Cases > MaxStressCases {
+		writeError(w, http.StatusBadRequest, "cases must be between 1 and "+strconv.Itoa(MaxStressCases))
+		return
+	}
+	if p.Generator == nil {
+		writeError(w, http.StatusBadRequest, "problem has no generator")
+		return
+	}
+	if !slices.Contains(p.Languages(), req.Language) {
+		writeError(w, http.StatusBadRequest, "unsupported language")
+		return
+	}
+	warm := false
+	report, err := judge.Stress(p, req.Language, req.Code, stressSeeds(req.Seed, req.Cases), judgePreparer(&warm))
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// stressSeeds returns the seeds of a stress run
+func stressSeeds(seed int64, cases int) []int64 {
+	seeds := make([]int64, cases)
+	for i := range seeds {
+		seeds[i] = seed + int64(i)
+	}
+	return seeds
+}
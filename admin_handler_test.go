@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/afman42/go-web-code-interactive/internal/judge"
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+	"github.com/afman42/go-web-code-interactive/internal/problempkg"
+)
+
+// adminProblem is a node-only problem whose harness reports through the
+// results file
+func adminProblem(reference string) map[string]any {
+	return map[string]any{
+		"id":        "admin-double",
+		"title":     "Double",
+		"statement": "Double a number",
+		"function":  "double",
+		"harness": map[string]string{
+			"node": `require("fs").appendFileSync(process.env.STQ_RESULTS, JSON.stringify({v: 1, type: "result", test: 0, value: double({{args}})}) + "\n");`,
+		},
+		"tests": []map[string]any{
+			{"args": []int{2}, "expected": 4},
+			{"args": []int{5}, "expected": 10, "hidden": true},
+		},
+		"reference": map[string]string{"node": reference},
+	}
+}
+
+func TestAdminProblemHandlers(t *testing.T) {
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node is not installed")
+	}
+	store, err := problem.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, oldStore := adminToken, problemStore
+	adminToken, problemStore = "secret", store
+	defer func() {
+		adminToken, problemStore = oldToken, oldStore
+		problemBank.Delete("admin-double", nil)
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/admin/problems", adminProblems)
+	mux.HandleFunc("/api/admin/problems/{id}", adminProblemByID)
+	mux.HandleFunc("/api/admin/problems/{id}/versions", adminProblemVersions)
+	mux.HandleFunc("/api/admin/problems/import", adminProblemImport)
+	mux.HandleFunc("/api/admin/problems/{id}/package", adminProblemPackage)
+	mux.HandleFunc("/api/admin/problems/{id}/stress", adminProblemStress)
+	send := func(method, url, token string, body any) *httptest.ResponseRecorder {
+		var payload bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&payload).Encode(body)
+		}
+		req := httptest.NewRequest(method, url, &payload)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		if rr := send("GET", "/api/admin/problems", "", nil); rr.Code != http.StatusUnauthorized {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusUnauthorized)
+		}
+		if rr := send("GET", "/api/admin/problems", "wrong", nil); rr.Code != http.StatusUnauthorized {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusUnauthorized)
+		}
+		adminToken = ""
+		defer func() { adminToken = "secret" }()
+		if rr := send("GET", "/api/admin/problems", "", nil); rr.Code != http.StatusForbidden {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusForbidden)
+		}
+	})
+
+	t.Run("Create Rejects Failing Reference", func(t *testing.T) {
+		rr := send("POST", "/api/admin/problems", "secret", adminProblem("function double(n) { return n + 2 }"))
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusUnprocessableEntity, rr.Body.String())
+		}
+		var res VerificationResponse
+		json.Unmarshal(rr.Body.Bytes(), &res)
+		if res.Results["node"].Passed != 1 || len(res.Results["node"].Tests) != 2 {
+			t.Errorf("Expected the reference run of every test, got %s", rr.Body.String())
+		}
+		if _, ok := problemBank.Get("admin-double"); ok {
+			t.Error("Expected the rejected problem not to be saved")
+		}
+	})
+
+	t.Run("Create, Update and Delete", func(t *testing.T) {
+		rr := send("POST", "/api/admin/problems", "secret", adminProblem("function double(n) { return n * 2 }"))
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+		}
+		if rr := send("POST", "/api/admin/problems", "secret", adminProblem("function double(n) { return n * 2 }")); rr.Code != http.StatusConflict {
+			t.Errorf("Expected a conflict for an existing id, got %v", rr.Code)
+		}
+
+		updated := adminProblem("const double = (n) => n + n;")
+		updated["title"] = "Double It"
+		rr = send("PUT", "/api/admin/problems/admin-double", "secret", updated)
+		var p problem.Problem
+		json.Unmarshal(rr.Body.Bytes(), &p)
+		if rr.Code != http.StatusOK || p.Version != 2 {
+			t.Fatalf("Expected version 2, got %v: %s", rr.Code, rr.Body.String())
+		}
+		if current, _ := problemBank.Get("admin-double"); current.Title != "Double It" {
+			t.Errorf("Expected the update to be current, got %+v", current)
+		}
+
+		rr = send("GET", "/api/admin/problems/admin-double?version=1", "secret", nil)
+		if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"title":"Double"`) {
+			t.Errorf("Expected version 1, got %v: %s", rr.Code, rr.Body.String())
+		}
+
+		if rr := send("DELETE", "/api/admin/problems/admin-double", "secret", nil); rr.Code != http.StatusNoContent {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+		}
+		if rr := send("GET", "/api/admin/problems/admin-double", "secret", nil); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected the deleted problem to be gone, got %v", rr.Code)
+		}
+		rr = send("GET", "/api/admin/problems/admin-double/versions", "secret", nil)
+		var versions []problem.Problem
+		json.Unmarshal(rr.Body.Bytes(), &versions)
+		if len(versions) != 2 {
+			t.Errorf("Expected both versions to stay available, got %s", rr.Body.String())
+		}
+
+		restored := problem.NewBank()
+		if err := store.Restore(restored); err != nil || len(restored.Versions("admin-double")) != 2 {
+			t.Errorf("Expected the versions to be stored, got %v", err)
+		}
+	})
+
+	t.Run("Concurrent Creates", func(t *testing.T) {
+		defer problemBank.Delete("admin-race", nil)
+		codes := make(chan int, 2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				p := adminProblem("function double(n) { return n * 2 }")
+				p["id"] = "admin-race"
+				codes <- send("POST", "/api/admin/problems", "secret", p).Code
+			}()
+		}
+		got := map[int]int{}
+		got[<-codes]++
+		got[<-codes]++
+		if got[http.StatusCreated] != 1 || got[http.StatusConflict] != 1 {
+			t.Errorf("Expected one create and one conflict, but got %v", got)
+		}
+		if versions := problemBank.Versions("admin-race"); len(versions) != 1 {
+			t.Errorf("Expected a single version, but got %d", len(versions))
+		}
+	})
+
+	t.Run("Update Unknown", func(t *testing.T) {
+		rr := send("PUT", "/api/admin/problems/missing", "secret", adminProblem("function double(n) { return n * 2 }"))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected a mismatched id to be rejected, got %v", rr.Code)
+		}
+		body := adminProblem("function double(n) { return n * 2 }")
+		delete(body, "id")
+		if rr := send("PUT", "/api/admin/problems/missing", "secret", body); rr.Code != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("Package Import and Export", func(t *testing.T) {
+		var p problem.Problem
+		data, _ := json.Marshal(adminProblem("function double(n) { return n * 2 }"))
+		json.Unmarshal(data, &p)
+		p.ID = ""
+		var archive bytes.Buffer
+		if err := problempkg.WriteZip(&archive, p); err != nil {
+			t.Fatal(err)
+		}
+		importZip := func(body []byte) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("POST", "/api/admin/problems/import?id=admin-double", bytes.NewReader(body))
+			req.Header.Set("Authorization", "Bearer secret")
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+			return rr
+		}
+		if rr := importZip([]byte("not a zip")); rr.Code != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+		}
+		rr := importZip(archive.Bytes())
+		if rr.Code != http.StatusCreated && rr.Code != http.StatusOK {
+			t.Fatalf("Expected the package to be imported, got %v: %s", rr.Code, rr.Body.String())
+		}
+		imported, _ := problemBank.Get("admin-double")
+		if len(imported.Tests) != 2 || !imported.Tests[1].Hidden || imported.Reference["node"] == "" {
+			t.Errorf("Expected the tests and reference to be imported, got %+v", imported)
+		}
+
+		rr = send("GET", "/api/admin/problems/admin-double/package", "secret", nil)
+		if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/zip" {
+			t.Fatalf("Expected a zip archive, got %v: %s", rr.Code, rr.Body.String())
+		}
+		fsys, err := problempkg.OpenZip(rr.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exported, err := problempkg.Read(fsys, ""); err != nil || exported.ID != "admin-double" || exported.Version != 0 {
+			t.Errorf("Expected the exported package to read back, got %+v, %v", exported, err)
+		}
+		if rr := importZip(rr.Body.Bytes()); rr.Code != http.StatusOK {
+			t.Errorf("Expected a reimport to add a version, got %v: %s", rr.Code, rr.Body.String())
+		}
+		if rr := send("GET", "/api/admin/problems/missing/package", "secret", nil); rr.Code != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("Generator and Stress", func(t *testing.T) {
+		body := adminProblem("function double(n) { return n * 2 }")
+		body["generator"] = map[string]any{
+			"language": "node",
+			"source":   `const fs = require("fs"); JSON.parse(fs.readFileSync(0, "utf8")).seeds.forEach((s, i) => fs.appendFileSync(process.env.STQ_RESULTS, JSON.stringify({v: 1, type: "input", test: i, value: [s]}) + "\n"));`,
+			"seeds":    []int{7, 8},
+		}
+		rr := send("PUT", "/api/admin/problems/admin-double", "secret", body)
+		var p problem.Problem
+		json.Unmarshal(rr.Body.Bytes(), &p)
+		if rr.Code != http.StatusOK || len(p.Tests) != 4 || string(p.Tests[3].Expected) != "16" || !p.Tests[3].Hidden {
+			t.Fatalf("Expected two generated tests, got %v: %s", rr.Code, rr.Body.String())
+		}
+
+		rr = send("POST", "/api/admin/problems/admin-double/stress", "secret", map[string]any{
+			"lang": "node", "txt": "function double(n) { return n > 5 ? n : n * 2 }", "cases": 10,
+		})
+		var report judge.StressReport
+		json.Unmarshal(rr.Body.Bytes(), &report)
+		if rr.Code != http.StatusOK || report.Cases != 10 || report.Failed != 5 || report.Smallest == nil || report.Smallest.Input != "6" || *report.Seed != 6 {
+			t.Errorf("Expected the smallest failing input 6, got %v: %s", rr.Code, rr.Body.String())
+		}
+		if rr := send("POST", "/api/admin/problems/admin-double/stress", "secret", map[string]any{"lang": "node", "cases": MaxStressCases + 1}); rr.Code != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+		}
+	})
+}
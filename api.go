@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/afman42/go-web-code-interactive/internal/pool"
+	"github.com/afman42/go-web-code-interactive/utils"
+)
+
+// ErrorResponse is the JSON body returned for failed API requests
+type ErrorResponse struct {
+	StatusCode int    `json:"statusCode"`
+	Message    string `json:"message"`
+}
+
+// withMiddleware wraps a handler with request logging and applies rate
+// limiting to POST requests (code execution)
+func withMiddleware(h http.HandlerFunc) http.Handler {
+	logged := utils.WrapHandlerWithLogging(h)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			rateLimiter.RateLimitHandler(logged).ServeHTTP(w, r)
+			return
+		}
+		logged.ServeHTTP(w, r)
+	})
+}
+
+func setCORSHeaders(w http.ResponseWriter) {
+	w.Header().Set("Access-Control-Allow-Origin", IPCors)
+	w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, GET, POST, PUT, DELETE")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Requested-With, Authorization")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, ErrorResponse{StatusCode: status, Message: message})
+}
+
+func methodNotAllowed(w http.ResponseWriter, allow string) {
+	w.Header().Set("Allow", allow)
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+}
+
+// poolMetrics handles GET /api/metrics/pool
+func poolMetrics(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, "GET")
+		return
+	}
+	metrics := []pool.Metrics{}
+	for _, lang := range []string{"node", "php"} {
+		if p, ok := workerPools[lang]; ok {
+			metrics = append(metrics, p.Metrics())
+		}
+	}
+	writeJSON(w, http.StatusOK, metrics)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/afman42/go-web-code-interactive/internal/judge"
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+	"github.com/afman42/go-web-code-interactive/internal/problempkg"
+	"github.com/afman42/go-web-code-interactive/internal/similarity"
+)
+
+// runCommand runs the subcommand named by the first argument. It reports
+// false when there is none, so the server starts instead.
+//
+//	import [-no-verify] <dir|file.zip>...
+//	export [-version N] <id> <dir|file.zip>
+//	stress [-cases N] [-seed S] <id> <lang> <file>
+//	similarity [-threshold T] [-lang L] [-contest ID] <id>
+func runCommand(args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+	switch args[0] {
+	case "import":
+		return true, importCommand(args[1:])
+	case "export":
+		return true, exportCommand(args[1:])
+	case "stress":
+		return true, stressCommand(args[1:])
+	case "similarity":
+		return true, similarityCommand(args[1:])
+	}
+	return true, fmt.Errorf("unknown command %q, expected import, export, stress or similarity", args[0])
+}
+
+// importCommand saves problem packages as new versions in the problem store.
+// The tests of the generator's seeds are generated with the reference
+// solution, which must then pass every test unless -no-verify is given.
+func importCommand(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	noVerify := flags.Bool("no-verify", false, "save without checking the reference solutions against the tests")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("usage: import [-no-verify] <dir|file.zip>...")
+	}
+	if err := loadProblems(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(TempDir, os.ModePerm); err != nil {
+		return err
+	}
+	for _, name := range flags.Args() {
+		fsys, id, err := problempkg.Open(name)
+		if err != nil {
+			return err
+		}
+		p, err := problempkg.Read(fsys, id)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		if p, err = problem.Prepare(p); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		warm := false
+		if p, err = judge.Expand(p, judgePreparer(&warm)); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		if !*noVerify {
+			if _, err := judge.Verify(p, judgePreparer(&warm)); err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+		}
+		p, err = problemBank.Put(p, false, problemStore.Save)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		fmt.Printf("imported %s version %d\n", p.ID, p.Version)
+	}
+	return nil
+}
+
+// exportCommand writes a problem as a package, into a zip archive when the
+// target ends in .zip and into a directory otherwise
+func exportCommand(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	version := flags.Int("version", 0, "version to export instead of the current one")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("usage: export [-version N] <id> <dir|file.zip>")
+	}
+	if err := loadProblems(); err != nil {
+		return err
+	}
+	id, target := flags.Arg(0), flags.Arg(1)
+	p, ok := problemBank.Get(id)
+	if *version > 0 {
+		p, ok = problemBank.Version(id, *version)
+	}
+	if !ok {
+		return fmt.Errorf("problem %s not found", id)
+	}
+	if !strings.HasSuffix(target, ".zip") {
+		return problempkg.WriteDir(target, p)
+	}
+	f, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if err := problempkg.WriteZip(f, p); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// stressCommand runs the solution in file against generated cases of a
+// problem and prints the report
+func stressCommand(args []string) error {
+	flags := flag.NewFlagSet("stress", flag.ContinueOnError)
+	cases := flags.Int("cases", DefaultStressCases, "number of generated cases")
+	seed := flags.Int64("seed", 1, "seed of the first case")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 3 || *cases <= 0 {
+		return errors.New("usage: stress [-cases N] [-seed S] <id> <lang> <file>")
+	}
+	if err := loadProblems(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(TempDir, os.ModePerm); err != nil {
+		return err
+	}
+	p, ok := problemBank.Get(flags.Arg(0))
+	if !ok {
+		return fmt.Errorf("problem %s not found", flags.Arg(0))
+	}
+	code, err := os.ReadFile(flags.Arg(2))
+	if err != nil {
+		return err
+	}
+	warm := false
+	report, err := judge.Stress(p, flags.Arg(1), string(code), stressSeeds(*seed, *cases), judgePreparer(&warm))
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	if report.Verdict != judge.Accepted {
+		return fmt.Errorf("stress test failed: %s", report.Verdict)
+	}
+	return nil
+}
+
+// similarityCommand compares the accepted submissions to a problem in the
+// submission history and prints the pairs that look alike
+func similarityCommand(args []string) error {
+	flags := flag.NewFlagSet("similarity", flag.ContinueOnError)
+	threshold := flags.Float64("threshold", similarity.DefaultThreshold, "least similarity reported, from 0 to 1")
+	lang := flags.String("lang", "", "only compare submissions in this language")
+	contestID := flags.String("contest", "", "only compare submissions made to this contest")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 || *threshold <= 0 || *threshold > 1 {
+		return errors.New("usage: similarity [-threshold T] [-lang L] [-contest ID] <id>")
+	}
+	if err := loadProblems(); err != nil {
+		return err
+	}
+	store, err := openSubmissionStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	report, err := similarityReport(store, flags.Arg(0), similarityFilter{Language: *lang, ContestID: *contestID, Threshold: *threshold})
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+)
+
+func TestRunCommand(t *testing.T) {
+	oldBank, oldStore := problemBank, problemStore
+	problemBank = problem.MustLoad(ProblemContent, "problems")
+	defer func() { problemBank, problemStore = oldBank, oldStore }()
+	t.Setenv("PROBLEMS_DIR", "")
+	t.Setenv("PROBLEM_STORE_DIR", t.TempDir())
+	t.Setenv("SUBMISSION_STORE", "file")
+	t.Setenv("SUBMISSION_DIR", t.TempDir())
+
+	if handled, _ := runCommand(nil); handled {
+		t.Error("Expected no arguments to start the server")
+	}
+	if handled, err := runCommand([]string{"serve"}); !handled || err == nil {
+		t.Error("Expected an unknown command to fail")
+	}
+
+	dir := filepath.Join(t.TempDir(), "int-into-string")
+	if _, err := runCommand([]string{"export", DefaultProblemID, dir}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "problem.yaml")); err != nil {
+		t.Errorf("Expected the package to be written, but got %v", err)
+	}
+	archive := filepath.Join(t.TempDir(), "p.zip")
+	if _, err := runCommand([]string{"export", "-version", "1", DefaultProblemID, archive}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if _, err := runCommand([]string{"export", "missing", dir}); err == nil {
+		t.Error("Expected error for an unknown problem, but got none")
+	}
+
+	if _, err := runCommand([]string{"import", "-no-verify", dir, archive}); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if p, _ := problemBank.Get(DefaultProblemID); p.Version != 3 || len(p.Tests) != 4 {
+		t.Errorf("Expected both imports to add a version, got %+v", p)
+	}
+	if _, err := runCommand([]string{"import", filepath.Join(dir, "missing")}); err == nil {
+		t.Error("Expected error for a missing package, but got none")
+	}
+
+	if _, err := runCommand([]string{"similarity", "-threshold", "0.8", DefaultProblemID}); err != nil {
+		t.Errorf("similarity failed: %v", err)
+	}
+	if _, err := runCommand([]string{"similarity", "-threshold", "2", DefaultProblemID}); err == nil {
+		t.Error("Expected error for a threshold above 1, but got none")
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/contest"
+	"github.com/afman42/go-web-code-interactive/internal/ratelimiter"
+)
+
+// ContestView is the public representation of a contest. The problems of
+// an upcoming contest stay hidden until it starts.
+type ContestView struct {
+	contest.Contest
+	State string `json:"state"`
+}
+
+func newContestView(c contest.Contest, now time.Time) ContestView {
+	v := ContestView{Contest: c, State: c.State(now)}
+	if v.State == "upcoming" {
+		v.Problems = []contest.Problem{}
+	}
+	return v
+}
+
+// ScoreRowView is a line of a contest scoreboard
+type ScoreRowView struct {
+	contest.Row
+	Player string `json:"player"`
+	// You marks the requesting client's own row
+	You bool `json:"you,omitempty"`
+}
+
+// ScoreboardView is a contest scoreboard with public player names
+type ScoreboardView struct {
+	contest.Scoreboard
+	Rows []ScoreRowView `json:"rows"`
+}
+
+// checkContestSubmission decides whether an execution request may be
+// submitted to its contest, returning http.StatusOK or the status and
+// message to reject it with
+func checkContestSubmission(r *http.Request, data Data) (int, string) {
+	if data.Tipe != "stq" {
+		return http.StatusBadRequest, "Only STQ solutions can be submitted to a contest"
+	}
+	c, err := contestStore.Get(data.ContestID)
+	if err != nil {
+		return http.StatusNotFound, "Check Contest is exist"
+	}
+	if !c.Running(time.Now()) {
+		return http.StatusForbidden, "Contest is not running"
+	}
+	if _, ok := c.Problem(data.ProblemID); !ok {
+		return http.StatusBadRequest, "Problem is not part of the contest"
+	}
+	if c.Quota != nil && !contestQuotas.Allow(c.ID, c.Quota.Window(), c.Quota.Submissions, ratelimiter.GetVisitorIP(r)) {
+		return StatusTooManyRequests, "Contest submission quota exceeded"
+	}
+	return http.StatusOK, ""
+}
+
+// checkContestProblems reports a problem of the contest missing from the bank
+func checkContestProblems(c contest.Contest) error {
+	for _, p := range c.Problems {
+		if _, ok := problemBank.Get(p.ID); !ok {
+			return fmt.Errorf("problem %s does not exist", p.ID)
+		}
+	}
+	return nil
+}
+
+// contests handles GET /api/contests
+func contests(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, "GET")
+		return
+	}
+	list, err := contestStore.List()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	now := time.Now()
+	views := []ContestView{}
+	for _, c := range list {
+		views = append(views, newContestView(c, now))
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+// contestByID handles GET /api/contests/{id}
+func contestByID(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, "GET")
+		return
+	}
+	c, err := contestStore.Get(r.PathValue("id"))
+	if err != nil {
+		writeContestError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newContestView(c, time.Now()))
+}
+
+// contestScoreboard handles GET /api/contests/{id}/scoreboard. During the
+// freeze admins can ask for the live standings with ?live=1.
+func contestScoreboard(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, "GET")
+		return
+	}
+	c, err := contestStore.Get(r.PathValue("id"))
+	if err != nil {
+		writeContestError(w, err)
+		return
+	}
+	live := r.URL.Query().Get("live") == "1"
+	if live && !requireAdmin(w, r) {
+		return
+	}
+	board := contest.Compute(c, contestAttempts.List(c.ID), contestReveals(c.ID), time.Now(), live)
+	me := ratelimiter.GetVisitorIP(r)
+	view := ScoreboardView{Scoreboard: board, Rows: []ScoreRowView{}}
+	for _, row := range board.Rows {
+		view.Rows = append(view.Rows, ScoreRowView{Row: row, Player: player(row.Owner), You: row.Owner == me})
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+// adminContests handles GET and POST /api/admin/contests
+func adminContests(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Allow", "GET, POST, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		list, err := contestStore.List()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, list)
+	case http.MethodPost:
+		var c contest.Contest
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if _, err := contestStore.Get(c.ID); err == nil {
+			writeError(w, http.StatusConflict, "contest already exists")
+			return
+		}
+		saveContest(w, c, http.StatusCreated)
+	default:
+		methodNotAllowed(w, "GET, POST, OPTIONS")
+	}
+}
+
+// adminContestByID handles GET, PUT and DELETE /api/admin/contests/{id}
+func adminContestByID(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Allow", "GET, PUT, DELETE, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	id := r.PathValue("id")
+	switch r.Method {
+	case http.MethodGet:
+		c, err := contestStore.Get(id)
+		if err != nil {
+			writeContestError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, c)
+	case http.MethodPut:
+		if _, err := contestStore.Get(id); err != nil {
+			writeContestError(w, err)
+			return
+		}
+		var c contest.Contest
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		c.ID = id
+		saveContest(w, c, http.StatusOK)
+	case http.MethodDelete:
+		if err := contestStore.Delete(id); err != nil {
+			writeContestError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w, "GET, PUT, DELETE, OPTIONS")
+	}
+}
+
+// saveContest checks a contest against the problem bank and stores it
+func saveContest(w http.ResponseWriter, c contest.Contest, status int) {
+	if err := checkContestProblems(c); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	c, err := contestStore.Save(c)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, status, c)
+}
+
+func writeContestError(w http.ResponseWriter, err error) {
+	if err == contest.ErrNotFound {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err.Error())
+}
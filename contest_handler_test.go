@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/contest"
+	"github.com/afman42/go-web-code-interactive/internal/judge"
+	"github.com/afman42/go-web-code-interactive/internal/leaderboard"
+	"github.com/afman42/go-web-code-interactive/internal/ratelimiter"
+	"github.com/afman42/go-web-code-interactive/internal/resultcache"
+	"github.com/afman42/go-web-code-interactive/internal/submission"
+)
+
+func TestContestHandlers(t *testing.T) {
+	store, err := contest.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	history, err := submission.OpenFile(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+	oldToken, oldStore, oldAttempts, oldQuotas, oldHistory := adminToken, contestStore, contestAttempts, contestQuotas, submissionStore
+	adminToken, contestStore, contestAttempts, contestQuotas, submissionStore = "secret", store, contest.NewAttempts(), ratelimiter.NewQuotas(), history
+	defer func() {
+		adminToken, contestStore, contestAttempts, contestQuotas, submissionStore = oldToken, oldStore, oldAttempts, oldQuotas, oldHistory
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/contests", contests)
+	mux.HandleFunc("/api/contests/{id}", contestByID)
+	mux.HandleFunc("/api/contests/{id}/scoreboard", contestScoreboard)
+	mux.HandleFunc("/api/admin/contests", adminContests)
+	mux.HandleFunc("/api/admin/contests/{id}", adminContestByID)
+	send := func(method, url, token string, body any) *httptest.ResponseRecorder {
+		var payload bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&payload).Encode(body)
+		}
+		req := httptest.NewRequest(method, url, &payload)
+		req.Header.Set("X-Real-IP", "10.0.0.1")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	check := func(ip string, data Data) int {
+		req := httptest.NewRequest("POST", "/", nil)
+		req.Header.Set("X-Real-IP", ip)
+		status, _ := checkContestSubmission(req, data)
+		return status
+	}
+
+	now := time.Now()
+	running := contest.Contest{
+		ID:            "weekly",
+		Title:         "Weekly",
+		Start:         now.Add(-time.Hour),
+		End:           now.Add(10 * time.Minute),
+		Problems:      []contest.Problem{{ID: DefaultProblemID}},
+		FreezeMinutes: 30,
+		Quota:         &contest.Quota{Submissions: 2, WindowSeconds: 60},
+	}
+
+	t.Run("Admin", func(t *testing.T) {
+		if rr := send("POST", "/api/admin/contests", "", running); rr.Code != http.StatusUnauthorized {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusUnauthorized)
+		}
+		unknown := running
+		unknown.Problems = []contest.Problem{{ID: "missing"}}
+		if rr := send("POST", "/api/admin/contests", "secret", unknown); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected a contest with an unknown problem to be rejected, but got %v", rr.Code)
+		}
+		rr := send("POST", "/api/admin/contests", "secret", running)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body)
+		}
+		var saved contest.Contest
+		json.NewDecoder(rr.Body).Decode(&saved)
+		if saved.Scoring != contest.ICPC || saved.Problems[0].Points != 1 {
+			t.Errorf("Expected the normalized contest, but got %+v", saved)
+		}
+		if rr := send("POST", "/api/admin/contests", "secret", running); rr.Code != http.StatusConflict {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusConflict)
+		}
+
+		upcoming := running
+		upcoming.Title, upcoming.Start, upcoming.End = "Next", now.Add(time.Hour), now.Add(2*time.Hour)
+		if rr := send("PUT", "/api/admin/contests/next", "secret", upcoming); rr.Code != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+		upcoming.ID = "next"
+		send("POST", "/api/admin/contests", "secret", upcoming)
+		upcoming.Title = "Next Week"
+		if rr := send("PUT", "/api/admin/contests/next", "secret", upcoming); rr.Code != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("Public", func(t *testing.T) {
+		var list []ContestView
+		json.NewDecoder(send("GET", "/api/contests", "", nil).Body).Decode(&list)
+		if len(list) != 2 || list[0].ID != "next" || list[0].State != "upcoming" || len(list[0].Problems) != 0 || list[0].Title != "Next Week" {
+			t.Fatalf("Expected the upcoming contest first with its problems hidden, but got %+v", list)
+		}
+		if list[1].State != "running" || len(list[1].Problems) != 1 {
+			t.Errorf("Expected the running contest with its problems, but got %+v", list[1])
+		}
+		if rr := send("GET", "/api/contests/missing", "", nil); rr.Code != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("Submissions", func(t *testing.T) {
+		stq := Data{Tipe: "stq", ContestID: "weekly", ProblemID: DefaultProblemID}
+		testCases := []struct {
+			name   string
+			data   Data
+			status int
+		}{
+			{"repl", Data{Tipe: "repl", ContestID: "weekly"}, http.StatusBadRequest},
+			{"unknown contest", Data{Tipe: "stq", ContestID: "missing", ProblemID: DefaultProblemID}, http.StatusNotFound},
+			{"not started", Data{Tipe: "stq", ContestID: "next", ProblemID: DefaultProblemID}, http.StatusForbidden},
+			{"other problem", Data{Tipe: "stq", ContestID: "weekly", ProblemID: "other"}, http.StatusBadRequest},
+			{"first", stq, http.StatusOK},
+			{"second", stq, http.StatusOK},
+			{"over quota", stq, StatusTooManyRequests},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				if got := check("10.0.0.9", tc.data); got != tc.status {
+					t.Errorf("Expected %v, but got %v", tc.status, got)
+				}
+			})
+		}
+		if got := check("10.0.0.8", stq); got != http.StatusOK {
+			t.Errorf("Expected the quota to be per client, but got %v", got)
+		}
+	})
+
+	t.Run("Scoreboard", func(t *testing.T) {
+		submit := func(ip string, passed int, at time.Time) {
+			s, err := history.Save(submission.Submission{Owner: ip, Type: "stq", ContestID: "weekly", ProblemID: DefaultProblemID, Mode: judge.ModeSubmit,
+				Result: &judge.Result{Passed: passed, Total: 2}, Created: at})
+			if err != nil {
+				t.Fatal(err)
+			}
+			contestAttempts.Add(s)
+		}
+		submit("10.0.0.2", 2, now.Add(-50*time.Minute))
+		// Made during the freeze, the last 30 minutes
+		submit("10.0.0.1", 1, now.Add(-15*time.Minute))
+		submit("10.0.0.1", 2, now.Add(-10*time.Minute))
+
+		var board ScoreboardView
+		json.NewDecoder(send("GET", "/api/contests/weekly/scoreboard", "", nil).Body).Decode(&board)
+		if !board.Frozen || len(board.Rows) != 2 || board.Rows[0].Player != player("10.0.0.2") || board.Rows[1].Points != 0 || !board.Rows[1].You {
+			t.Fatalf("Expected the frozen board, but got %+v", board)
+		}
+		if cell := board.Rows[1].Problems[DefaultProblemID]; cell.Pending != 2 || cell.Attempts != 0 {
+			t.Errorf("Expected 2 pending attempts, but got %+v", cell)
+		}
+		if rr := send("GET", "/api/contests/weekly/scoreboard?live=1", "", nil); rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected the live board to need the admin token, but got %v", rr.Code)
+		}
+		board = ScoreboardView{}
+		json.NewDecoder(send("GET", "/api/contests/weekly/scoreboard?live=1", "secret", nil).Body).Decode(&board)
+		if board.Frozen || len(board.Rows) != 2 || board.Rows[1].Points != 1 || board.Rows[1].Penalty != 50+contest.DefaultPenaltyMinutes {
+			t.Errorf("Expected the live board, but got %+v", board)
+		}
+
+		// The history is replayed into the scoreboards at startup
+		contestAttempts = contest.NewAttempts()
+		if err := replayHistory(); err != nil {
+			t.Fatalf("replayHistory failed: %v", err)
+		}
+		if list := contestAttempts.List("weekly"); len(list) != 3 {
+			t.Errorf("Expected 3 replayed attempts, but got %d", len(list))
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if rr := send("DELETE", "/api/admin/contests/next", "secret", nil); rr.Code != http.StatusNoContent {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+		}
+		if rr := send("GET", "/api/admin/contests/next", "secret", nil); rr.Code != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestContestResultCache(t *testing.T) {
+	store, err := contest.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	history, err := submission.OpenFile(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+	oldStore, oldAttempts, oldHistory, oldCache, oldBoard := contestStore, contestAttempts, submissionStore, resultCache, leaderboards
+	contestStore, contestAttempts, submissionStore, resultCache, leaderboards = store, contest.NewAttempts(), history, resultcache.New(ResultCacheSize, ResultCacheTTL), leaderboard.New()
+	defer func() {
+		contestStore, contestAttempts, submissionStore, resultCache, leaderboards = oldStore, oldAttempts, oldHistory, oldCache, oldBoard
+	}()
+	now := time.Now()
+	if _, err := store.Save(contest.Contest{ID: "cached", Title: "Cached", Start: now.Add(-time.Hour), End: now.Add(time.Hour),
+		Problems: []contest.Problem{{ID: DefaultProblemID}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	submit := func(ip, code, contestID string) submission.Submission {
+		body, _ := json.Marshal(Data{Txt: code, Language: "node", Tipe: "stq", ProblemID: DefaultProblemID, Mode: judge.ModeSubmit, ContestID: contestID})
+		req := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+		req.Header.Set("X-Real-IP", ip)
+		rr := httptest.NewRecorder()
+		index(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		var res Data
+		json.NewDecoder(rr.Body).Decode(&res)
+		s, err := history.Get(res.SubmissionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	t.Run("Contest Result Outside The Contest", func(t *testing.T) {
+		code := "function intIntoString(n) { return String(n) }"
+		if s := submit("10.0.0.1", code, "cached"); s.ContestID != "cached" {
+			t.Fatalf("Expected the contest submission, but got %+v", s)
+		}
+		if s := submit("10.0.0.2", code, ""); s.ContestID != "" {
+			t.Errorf("Expected a cached result not to join the first requester's contest, but got %q", s.ContestID)
+		}
+	})
+
+	t.Run("Plain Result In The Contest", func(t *testing.T) {
+		code := "function intIntoString(n) { return `${n}` }"
+		submit("10.0.0.3", code, "")
+		if s := submit("10.0.0.4", code, "cached"); s.ContestID != "cached" {
+			t.Errorf("Expected a cached result to count toward the requester's contest, but got %q", s.ContestID)
+		}
+	})
+
+	owners := map[string]bool{}
+	for _, a := range contestAttempts.List("cached") {
+		owners[a.Owner] = true
+	}
+	if len(owners) != 2 || !owners["10.0.0.1"] || !owners["10.0.0.4"] {
+		t.Errorf("Expected the two contestants on the scoreboard, but got %v", owners)
+	}
+
+	// Until the contest ends its submissions stay off the problem's
+	// leaderboard
+	owners = map[string]bool{}
+	for _, s := range leaderboards.Problem(DefaultProblemID, leaderboard.Filter{}) {
+		owners[s.Owner] = true
+	}
+	if len(owners) != 2 || !owners["10.0.0.2"] || !owners["10.0.0.3"] {
+		t.Errorf("Expected only the submissions outside the contest on the leaderboard, but got %v", owners)
+	}
+}
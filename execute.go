@@ -0,0 +1,346 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/coverage"
+	"github.com/afman42/go-web-code-interactive/internal/interp"
+	"github.com/afman42/go-web-code-interactive/internal/judge"
+	"github.com/afman42/go-web-code-interactive/internal/protocol"
+	"github.com/afman42/go-web-code-interactive/internal/unittest"
+	"github.com/afman42/go-web-code-interactive/utils"
+)
+
+var errWriteFile = errors.New("unable to write file")
+
+// execResult is the output of a single program run
+type execResult struct {
+	Stdout   string
+	Stderr   string
+	Results  []byte
+	Warm     bool
+	TimedOut bool
+	Duration time.Duration
+}
+
+// execute runs a single-file program once. The file is written to the temp
+// folder and, for interpreted languages, run on a pre-started worker when a
+// pool exists; workers do not support standard input.
+func execute(language, code, stdin string, timeout time.Duration) (execResult, error) {
+	name := map[string]string{"node": "main.js", "php": "main.php", "go": "main.go"}[language]
+	prog := judge.Program{Files: map[string]string{name: code}, Entry: name}
+	p, ok := workerPools[language]
+	if !ok || stdin != "" {
+		return executeProgram(language, prog, stdin, timeout)
+	}
+
+	start := time.Now()
+	w, err := newWorkspace(language, prog)
+	if err != nil {
+		return execResult{}, err
+	}
+	defer w.close()
+	res, warm, err := p.Run(filepath.Join(w.dir, name), timeout)
+	if err != nil && err != interp.ErrTimeout {
+		log.Printf("error worker: %v\n", err)
+	}
+	result := execResult{
+		Stdout:   w.trim.Replace(res.Stdout),
+		Stderr:   w.trim.Replace(res.Stderr),
+		Warm:     warm,
+		Duration: time.Since(start),
+	}
+	if err == interp.ErrTimeout {
+		result.Stderr = "Execution timed out"
+		result.TimedOut = true
+	}
+	return result, nil
+}
+
+// executeProgram prepares a program and runs it once. For Go the build
+// counts toward the timeout.
+func executeProgram(language string, prog judge.Program, stdin string, timeout time.Duration) (execResult, error) {
+	start := time.Now()
+	w, err := prepareProgram(language, prog, timeout, false)
+	var compileErr *judge.CompileError
+	if errors.As(err, &compileErr) {
+		return execResult{Stderr: compileErr.Output, Warm: w.warm, TimedOut: w.timedOut, Duration: time.Since(start)}, nil
+	} else if err != nil {
+		return execResult{}, err
+	}
+	defer w.close()
+	remaining := timeout - time.Since(start)
+	if remaining <= 0 {
+		return execResult{Stderr: "Execution timed out", Warm: w.warm, TimedOut: true, Duration: time.Since(start)}, nil
+	}
+	result := w.run(nil, stdin, remaining)
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// judgePreparer prepares the judge's programs in workspaces, builds are
+// limited by the execution timeout. warm is set once a build used the
+// prewarmed Go cache.
+func judgePreparer(warm *bool) judge.Preparer {
+	return coveragePreparer(warm, nil)
+}
+
+// coveragePreparer is judgePreparer collecting the coverage of the user's
+// code into cov, unless it is nil. Only programs holding the user's code are
+// instrumented, their coverage is read when the judge releases them.
+func coveragePreparer(warm *bool, cov *coverage.Profile) judge.Preparer {
+	return func(language string, prog judge.Program) (judge.Runner, func(), error) {
+		file := judge.SolutionFile(language)
+		_, user := prog.Files[file]
+		cover := cov != nil && user
+		ws, err := prepareProgram(language, prog, ExecTimeout, cover)
+		if ws != nil && ws.warm {
+			*warm = true
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		release := ws.close
+		if cover {
+			release = func() {
+				ws.coverage(cov, file)
+				ws.close()
+			}
+		}
+		return func(tests []byte, stdin string, timeout time.Duration) (judge.Output, error) {
+			res := ws.run(tests, stdin, timeout)
+			return judge.Output{Stdout: res.Stdout, Stderr: res.Stderr, Results: res.Results, TimedOut: res.TimedOut}, nil
+		}, release, nil
+	}
+}
+
+// workspace is a program written to its own directory in the temp folder,
+// ready to run as often as needed. Go programs are built once into a binary
+// next to their sources. Paths of the directory are removed from the output
+// so errors name the files as the user knows them. Harnesses read their
+// tests from and report through files in the same directory.
+type workspace struct {
+	language string
+	dir      string
+	command  []string
+	env      []string
+	warm     bool
+	timedOut bool
+	trim     *strings.Replacer
+	// coverDir receives the raw coverage data of every run when set
+	coverDir string
+}
+
+// prepareProgram writes the program's files and builds Go programs within
+// the timeout, instrumented for coverage with cover. A failed build is
+// returned as a *judge.CompileError along with the removed workspace, which
+// still tells whether the build was warm or timed out.
+func prepareProgram(language string, prog judge.Program, timeout time.Duration, cover bool) (*workspace, error) {
+	w, err := newWorkspace(language, prog)
+	if err != nil {
+		return nil, err
+	}
+	if cover {
+		w.coverDir = filepath.Join(w.dir, coverage.Dir)
+	}
+	if language != "go" {
+		w.command = []string{language, filepath.Join(w.dir, prog.Entry)}
+		return w, nil
+	}
+
+	w.useGoCache()
+	binary := filepath.Join(w.dir, "stq_program")
+	args := []string{"build", "-o", binary}
+	if cover {
+		args = append(args, "-cover", "-covermode=count")
+	}
+	// Package variables are initialized in the order of the files, the
+	// harness's come before the user's code
+	for _, name := range prog.Names() {
+		if name != prog.Entry {
+			args = append(args, filepath.Join(w.dir, name))
+		}
+	}
+	args = append(args, filepath.Join(w.dir, prog.Entry))
+	_, errout, err := utils.ShelloutWithOptions(utils.ShellOptions{Env: w.env, Timeout: timeout}, "go", args...)
+	if err != nil {
+		w.close()
+		if err == utils.ErrTimeout {
+			w.timedOut = true
+			return w, &judge.CompileError{Output: "Compilation timed out"}
+		}
+		return w, &judge.CompileError{Output: w.trim.Replace(errout)}
+	}
+	w.command = []string{binary}
+	return w, nil
+}
+
+// newWorkspace writes the program's files to a new directory in the temp
+// folder
+func newWorkspace(language string, prog judge.Program) (*workspace, error) {
+	dir, err := os.MkdirTemp(utils.PathFileTemp(""), "run-")
+	if err != nil {
+		log.Printf("unable to create run directory: %v", err)
+		return nil, errWriteFile
+	}
+	w := &workspace{language: language, dir: dir}
+	for _, name := range prog.Names() {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(prog.Files[name]), 0o644); err != nil {
+			log.Printf("unable to write file: %v", err.Error())
+			w.close()
+			return nil, errWriteFile
+		}
+	}
+
+	// go reports paths relative to the working directory
+	prefixes := []string{dir + string(filepath.Separator), ""}
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, dir); err == nil {
+			prefixes = append(prefixes, rel+string(filepath.Separator), "")
+		}
+	}
+	w.trim = strings.NewReplacer(prefixes...)
+	return w, nil
+}
+
+// useGoCache makes Go builds in the workspace use the prewarmed build cache,
+// once it is ready
+func (w *workspace) useGoCache() {
+	if goBuildCache == nil || !goBuildCache.Ready() {
+		return
+	}
+	env, err := goBuildCache.Env()
+	if err != nil {
+		log.Printf("error go cache: %v\n", err)
+		return
+	}
+	w.env = env
+	w.warm = true
+}
+
+// checkCoverage tells why the coverage of a request cannot be collected, if
+// it cannot. Submissions leave it out so it gives nothing away about the
+// hidden tests.
+func checkCoverage(data Data) string {
+	switch {
+	case data.Language != "go" && data.Language != "node":
+		return "Coverage is only available for Go and Node"
+	case data.Tipe == "repl":
+		return "Coverage is only available for STQ and test runs"
+	case data.Tipe == "stq" && data.Mode == judge.ModeSubmit:
+		return "Coverage is not available when submitting"
+	}
+	return ""
+}
+
+// runTests runs a user's test suite against their code once and parses the
+// per-test report. Building Go tests counts toward the timeout. The coverage
+// of the user's code is added to cov, unless it is nil.
+func runTests(language, code, tests string, timeout time.Duration, cov *coverage.Profile) (unittest.Report, execResult, error) {
+	w, err := newWorkspace(language, unittest.Build(language, code, tests))
+	if err != nil {
+		return unittest.Report{}, execResult{}, err
+	}
+	defer w.close()
+	if language == "go" {
+		w.useGoCache()
+	} else if cov != nil {
+		// go test writes its profile itself
+		w.coverDir = filepath.Join(w.dir, coverage.Dir)
+	}
+	w.command = unittest.Command(language, w.dir, cov != nil)
+	res := w.run(nil, "", timeout)
+	report, err := unittest.Parse(language, res.Stdout, res.Results)
+	if err != nil {
+		log.Printf("error test results: %v\n", err)
+	}
+	if cov != nil {
+		w.coverage(cov, unittest.CodeFile(language))
+	}
+	return report, res, nil
+}
+
+// run executes the prepared program once with the given tests file content
+// and standard input
+func (w *workspace) run(tests []byte, stdin string, timeout time.Duration) execResult {
+	results := filepath.Join(w.dir, protocol.ResultsFile)
+	testsFile := filepath.Join(w.dir, protocol.TestsFile)
+	os.Remove(results)
+	if err := os.WriteFile(testsFile, tests, 0o644); err != nil {
+		log.Printf("unable to write tests file: %v", err)
+	}
+	opts := utils.ShellOptions{
+		Env:     append([]string{protocol.ResultsEnv + "=" + results, protocol.TestsEnv + "=" + testsFile}, w.env...),
+		Stdin:   stdin,
+		Timeout: timeout,
+	}
+	if w.coverDir != "" {
+		os.MkdirAll(w.coverDir, os.ModePerm)
+		opts.Env = append(opts.Env, coverage.Env(w.language, w.coverDir))
+	}
+	start := time.Now()
+	out, errout, err := utils.ShelloutWithOptions(opts, w.command[0], w.command[1:]...)
+	result := execResult{
+		Stdout:   w.trim.Replace(out),
+		Stderr:   w.trim.Replace(errout),
+		Warm:     w.warm,
+		Duration: time.Since(start),
+	}
+	if err == utils.ErrTimeout {
+		result.Stderr = "Execution timed out"
+		result.TimedOut = true
+	} else if err != nil {
+		log.Printf("error shell: %v\n", err)
+	}
+	data, _ := os.ReadFile(results)
+	// Error records carry stack traces with the directory's paths as well
+	result.Results = []byte(w.trim.Replace(string(data)))
+	return result
+}
+
+// coverage adds the coverage of file over the workspace's runs to cov. Go
+// profiles come from go test or, for binaries built with -cover, from
+// converting the raw data with go tool covdata; Node leaves a V8 coverage
+// file per process.
+func (w *workspace) coverage(cov *coverage.Profile, file string) {
+	profile := filepath.Join(w.dir, coverage.ProfileFile)
+	if w.language == "go" && w.coverDir != "" {
+		if _, errout, err := utils.ShelloutWithOptions(utils.ShellOptions{Timeout: ExecTimeout}, "go", "tool", "covdata", "textfmt", "-i="+w.coverDir, "-o="+profile); err != nil {
+			log.Printf("error coverage: %v %s\n", err, errout)
+			return
+		}
+	}
+	if w.language == "go" {
+		data, err := os.ReadFile(profile)
+		// Tests that did not build leave no profile
+		if os.IsNotExist(err) {
+			return
+		} else if err == nil {
+			err = cov.AddGo(data, file)
+		}
+		if err != nil {
+			log.Printf("error coverage: %v\n", err)
+		}
+		return
+	}
+	names, _ := filepath.Glob(filepath.Join(w.coverDir, "*.json"))
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err == nil {
+			err = cov.AddV8(data, file)
+		}
+		if err != nil {
+			log.Printf("error coverage: %v\n", err)
+		}
+	}
+}
+
+// close removes the workspace
+func (w *workspace) close() {
+	os.RemoveAll(w.dir)
+}
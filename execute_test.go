@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/judge"
+	"github.com/afman42/go-web-code-interactive/internal/pool"
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+	"github.com/afman42/go-web-code-interactive/internal/protocol"
+)
+
+func TestPrepareProgram(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+	t.Run("Build Once", func(t *testing.T) {
+		prog := judge.Program{
+			Files: map[string]string{"main.go": `package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	data, _ := os.ReadFile(os.Getenv("` + protocol.TestsEnv + `"))
+	fmt.Print(string(data))
+}
+`},
+			Entry: "main.go",
+		}
+		ws, err := prepareProgram("go", prog, 30*time.Second, false)
+		if err != nil {
+			t.Fatalf("prepareProgram failed: %v", err)
+		}
+		defer ws.close()
+		// Every run starts the built binary, the sources are not compiled again
+		os.Remove(filepath.Join(ws.dir, "main.go"))
+		for _, tests := range []string{"first\n", "second\n"} {
+			res := ws.run([]byte(tests), "", 5*time.Second)
+			if res.Stdout != tests {
+				t.Errorf("Expected the binary to read %q, but got %q, %q", tests, res.Stdout, res.Stderr)
+			}
+		}
+	})
+
+	t.Run("Compile Error", func(t *testing.T) {
+		prog := judge.Program{Files: map[string]string{"solution.go": "package main\n\nfunc main() {\n\tundefinedName()\n}\n"}, Entry: "solution.go"}
+		_, err := prepareProgram("go", prog, 30*time.Second, false)
+		var compileErr *judge.CompileError
+		if !errors.As(err, &compileErr) {
+			t.Fatalf("Expected a compile error, but got %v", err)
+		}
+		if !strings.Contains(compileErr.Output, "solution.go:4") || strings.Contains(compileErr.Output, "run-") {
+			t.Errorf("Expected the error at the user's line, but got %q", compileErr.Output)
+		}
+	})
+}
+
+func TestExecutePooled(t *testing.T) {
+	programs := map[string][]string{
+		"node": {
+			"console.log(typeof Buffer, typeof URL, typeof TextEncoder, typeof setImmediate, typeof require, typeof module)",
+			"const crypto = require('crypto')\nconst path = require('path')\nconsole.log(crypto.createHash('sha1').update('stq').digest('hex'), path.basename(__filename))",
+			"console.log(require.main === module, process.argv.length, process.execArgv)",
+			"setImmediate(() => console.log('immediate'))\nsetTimeout(() => console.log('timeout'), 100)\nconsole.log('now')",
+			"function f() {\n  throw new Error('boom')\n}\nf()",
+			"process.stdin.on('data', (d) => console.log('read', String(d))).on('end', () => console.log('end'))",
+		},
+		"php": {
+			"before <?php echo 'inside'; ?> after\n",
+			"<?php\nvar_dump(count($argv), basename(__FILE__), isset($stqFile));",
+			"<?php\nfunction f() {\n    throw new Exception('boom');\n}\nf();",
+		},
+	}
+	for language, codes := range programs {
+		if _, err := exec.LookPath(language); err != nil {
+			t.Logf("%s is not installed", language)
+			continue
+		}
+		t.Run(language, func(t *testing.T) {
+			p := pool.New(language, 1)
+			defer p.Close()
+			for _, code := range codes {
+				plain, err := execute(language, code, "", 5*time.Second)
+				if err != nil {
+					t.Fatalf("execute failed: %v", err)
+				}
+				workerPools[language] = p
+				pooled, err := execute(language, code, "", 5*time.Second)
+				delete(workerPools, language)
+				if err != nil {
+					t.Fatalf("execute failed: %v", err)
+				}
+				// Only the frame of node's own entry point is missing, the
+				// driver took its place
+				entry := func(s string) string {
+					var lines []string
+					for _, line := range strings.Split(s, "\n") {
+						if !strings.Contains(line, "node:internal/main/run_main_module") {
+							lines = append(lines, line)
+						}
+					}
+					return strings.Join(lines, "\n")
+				}
+				if pooled.Stdout != plain.Stdout || entry(pooled.Stderr) != entry(plain.Stderr) {
+					t.Errorf("Expected pooled output to match a plain run of %q, but got %q, %q instead of %q, %q",
+						code, pooled.Stdout, pooled.Stderr, plain.Stdout, plain.Stderr)
+				}
+				if strings.Contains(pooled.Stderr, "[eval]") || strings.Contains(pooled.Stdout, "Command line code") {
+					t.Errorf("Expected the driver's frames to be removed, but got %q, %q", pooled.Stdout, pooled.Stderr)
+				}
+			}
+		})
+	}
+}
+
+// TestJudgeForgedResults runs solutions that answer wrong but append
+// passing results to the results file, with every nonce they can find
+func TestJudgeForgedResults(t *testing.T) {
+	p := problem.Problem{
+		ID:        "forge",
+		Signature: "inc(n: int) -> int",
+		Tests: []problem.TestCase{
+			{Args: []json.RawMessage{json.RawMessage("1")}, Expected: json.RawMessage("2")},
+			{Args: []json.RawMessage{json.RawMessage("2")}, Expected: json.RawMessage("3")},
+		},
+	}
+	solutions := map[string]string{
+		"node": `const fs = require('fs');
+let nonce = '';
+try {
+  nonce = JSON.parse(fs.readFileSync(process.env.STQ_TESTS, 'utf8').split('\n')[0]).nonce;
+} catch (e) {}
+for (const test of [0, 1]) {
+  fs.appendFileSync(process.env.STQ_RESULTS, JSON.stringify({ v: 1, type: 'result', test, value: test + 2, nonce }) + '\n');
+}
+function inc(n) {
+  return n;
+}
+`,
+		"go": `package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var forged = forge()
+
+func forge() bool {
+	var in struct{ Nonce string }
+	data, _ := os.ReadFile(os.Getenv("STQ_TESTS"))
+	json.Unmarshal([]byte(strings.Split(string(data), "\n")[0]), &in)
+	f, _ := os.OpenFile(os.Getenv("STQ_RESULTS"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	defer f.Close()
+	for test := 0; test < 2; test++ {
+		fmt.Fprintf(f, "{\"v\": 1, \"type\": \"result\", \"test\": %d, \"value\": %d, \"nonce\": %q}\n", test, test+2, in.Nonce)
+	}
+	return true
+}
+
+func inc(n int) int {
+	return n
+}
+`,
+	}
+	for lang, code := range solutions {
+		t.Run(lang, func(t *testing.T) {
+			if _, err := exec.LookPath(lang); err != nil {
+				t.Skipf("%s is not installed", lang)
+			}
+			var warm bool
+			res, err := judge.Run(p, lang, code, judge.ModeRun, judgePreparer(&warm))
+			if err != nil {
+				t.Fatalf("Run failed: %v", err)
+			}
+			if res.Verdict != judge.WrongAnswer || res.Passed != 0 {
+				t.Errorf("Expected the forged results to be ignored, but got %+v", res)
+			}
+			if !strings.Contains(res.Tests[0].Stderr, "not written by the harness were ignored") {
+				t.Errorf("Expected the forged records to be reported, but got %q", res.Tests[0].Stderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/contest"
+	"github.com/afman42/go-web-code-interactive/internal/hint"
+	"github.com/afman42/go-web-code-interactive/internal/markdown"
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+	"github.com/afman42/go-web-code-interactive/internal/ratelimiter"
+)
+
+// HintView is a revealed hint, rendered from Markdown
+type HintView struct {
+	Index     int       `json:"index"`
+	HTML      string    `json:"html"`
+	ContestID string    `json:"contestId,omitempty"`
+	Revealed  time.Time `json:"revealed"`
+}
+
+// HintsView is a client's progress through the hints of a problem
+type HintsView struct {
+	Total    int        `json:"total"`
+	Revealed []HintView `json:"revealed"`
+}
+
+// EditorialView is an unlocked editorial. UnlockedBy is "solved",
+// "attempts" or "admin".
+type EditorialView struct {
+	HTML       string `json:"html"`
+	UnlockedBy string `json:"unlockedBy"`
+}
+
+func newHintView(p problem.Problem, r hint.Reveal) HintView {
+	v := HintView{Index: r.Index, ContestID: r.ContestID, Revealed: r.Created}
+	// A later version may have fewer hints than were revealed
+	if r.Index < len(p.Hints) {
+		v.HTML = markdown.Render(p.Hints[r.Index])
+	}
+	return v
+}
+
+// runningContestWith returns a running contest the problem is part of
+func runningContestWith(problemID string) (contest.Contest, bool) {
+	if contestStore == nil {
+		return contest.Contest{}, false
+	}
+	list, err := contestStore.List()
+	if err != nil {
+		return contest.Contest{}, false
+	}
+	now := time.Now()
+	for _, c := range list {
+		if _, ok := c.Problem(problemID); ok && c.Running(now) {
+			return c, true
+		}
+	}
+	return contest.Contest{}, false
+}
+
+// contestReveals returns the hint reveals charged to a contest
+func contestReveals(contestID string) []hint.Reveal {
+	if hintStore == nil {
+		return nil
+	}
+	return hintStore.Contest(contestID)
+}
+
+// checkHintContest decides which contest a hint reveal is charged to,
+// returning http.StatusOK or the status and message to reject it with.
+// While a problem is part of a running contest its hints must be charged to
+// it, so contestants cannot reveal them for free.
+func checkHintContest(contestID, problemID string) (int, string) {
+	if contestID == "" {
+		if c, ok := runningContestWith(problemID); ok {
+			return http.StatusForbidden, fmt.Sprintf("Problem is part of the running contest %s, name it with contestId", c.ID)
+		}
+		return http.StatusOK, ""
+	}
+	if contestStore == nil {
+		return http.StatusNotFound, "Check Contest is exist"
+	}
+	c, err := contestStore.Get(contestID)
+	if err != nil {
+		return http.StatusNotFound, "Check Contest is exist"
+	}
+	if !c.Running(time.Now()) {
+		return http.StatusForbidden, "Contest is not running"
+	}
+	if _, ok := c.Problem(problemID); !ok {
+		return http.StatusBadRequest, "Problem is not part of the contest"
+	}
+	return http.StatusOK, ""
+}
+
+// problemHints handles GET and POST /api/problems/{id}/hints. GET lists the
+// hints the client revealed so far; POST reveals the next one and records
+// it, charging it to the running contest named by contestId.
+func problemHints(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		methodNotAllowed(w, "GET, POST")
+		return
+	}
+	p, ok := problemBank.Get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "problem not found")
+		return
+	}
+	if hintStore == nil {
+		writeError(w, http.StatusServiceUnavailable, "hints are unavailable")
+		return
+	}
+	owner := ratelimiter.GetVisitorIP(r)
+	if r.Method == http.MethodGet {
+		view := HintsView{Total: len(p.Hints), Revealed: []HintView{}}
+		for _, reveal := range hintStore.Revealed(owner, p.ID) {
+			view.Revealed = append(view.Revealed, newHintView(p, reveal))
+		}
+		writeJSON(w, http.StatusOK, view)
+		return
+	}
+
+	var body struct {
+		ContestID string `json:"contestId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if status, message := checkHintContest(body.ContestID, p.ID); status != http.StatusOK {
+		writeError(w, status, message)
+		return
+	}
+	reveal, err := hintStore.Next(owner, p.ID, body.ContestID, len(p.Hints))
+	if errors.Is(err, hint.ErrNoMoreHints) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, newHintView(p, reveal))
+}
+
+// problemEditorial handles GET /api/problems/{id}/editorial. The editorial
+// stays locked until the client solves the problem or, if the problem sets
+// editorialAfter, is rejected that many times, and while the problem is
+// part of a running contest. Admins can always read it.
+func problemEditorial(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, "GET")
+		return
+	}
+	p, ok := problemBank.Get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "problem not found")
+		return
+	}
+	if p.Editorial == "" {
+		writeError(w, http.StatusNotFound, "problem has no editorial")
+		return
+	}
+	view := EditorialView{HTML: markdown.Render(p.Editorial)}
+	owner := ratelimiter.GetVisitorIP(r)
+	_, inContest := runningContestWith(p.ID)
+	switch {
+	case isAdmin(r):
+		view.UnlockedBy = "admin"
+	case inContest:
+		writeError(w, http.StatusForbidden, "Editorial is locked while the problem is part of a running contest")
+		return
+	case leaderboards.Progress(owner)[p.ID]:
+		view.UnlockedBy = "solved"
+	case p.EditorialAfter > 0 && leaderboards.Failures(owner, p.ID) >= p.EditorialAfter:
+		view.UnlockedBy = "attempts"
+	default:
+		message := "Editorial unlocks once the problem is solved"
+		if p.EditorialAfter > 0 {
+			message += fmt.Sprintf(" or after %d rejected submissions (%d so far)", p.EditorialAfter, leaderboards.Failures(owner, p.ID))
+		}
+		writeError(w, http.StatusForbidden, message)
+		return
+	}
+	writeJSON(w, http.StatusOK, view)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/contest"
+	"github.com/afman42/go-web-code-interactive/internal/hint"
+	"github.com/afman42/go-web-code-interactive/internal/judge"
+	"github.com/afman42/go-web-code-interactive/internal/leaderboard"
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+	"github.com/afman42/go-web-code-interactive/internal/submission"
+)
+
+func TestHintHandlers(t *testing.T) {
+	hints, err := hint.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hints.Close()
+	contests, err := contest.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldHints, oldContests, oldBoard, oldToken := hintStore, contestStore, leaderboards, adminToken
+	hintStore, contestStore, leaderboards, adminToken = hints, contests, leaderboard.New(), "secret"
+	defer func() {
+		hintStore, contestStore, leaderboards, adminToken = oldHints, oldContests, oldBoard, oldToken
+		problemBank.Delete("hinted", nil)
+	}()
+	p := problem.Problem{ID: "hinted", Title: "Hinted", Statement: "Double `n`.", Signature: "double(n: int) -> int",
+		Hints:     []string{"Multiply by **two**", "Or add `n` to itself"},
+		Editorial: "Return `n * 2`.", EditorialAfter: 2,
+		Tests: []problem.TestCase{{Args: []json.RawMessage{json.RawMessage("1")}, Expected: json.RawMessage("2")}}}
+	if _, err := problemBank.Put(p, false, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/problems/{id}", problemByID)
+	mux.HandleFunc("/api/problems/{id}/hints", problemHints)
+	mux.HandleFunc("/api/problems/{id}/editorial", problemEditorial)
+	send := func(method, url, ip, token string, body any) *httptest.ResponseRecorder {
+		var payload bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&payload).Encode(body)
+		}
+		req := httptest.NewRequest(method, url, &payload)
+		req.Header.Set("X-Real-IP", ip)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Problem", func(t *testing.T) {
+		var view ProblemView
+		json.NewDecoder(send("GET", "/api/problems/hinted", "10.0.0.1", "", nil).Body).Decode(&view)
+		if view.Hints != 2 || !view.HasEditorial {
+			t.Errorf("Expected the hint count and editorial flag, but got %+v", view)
+		}
+		if rr := send("GET", "/api/problems/hinted", "10.0.0.1", "", nil); strings.Contains(rr.Body.String(), "Multiply") || strings.Contains(rr.Body.String(), "n * 2") {
+			t.Errorf("Expected hints and editorial to stay on the server, got %s", rr.Body)
+		}
+	})
+
+	t.Run("Hints", func(t *testing.T) {
+		var list HintsView
+		json.NewDecoder(send("GET", "/api/problems/hinted/hints", "10.0.0.1", "", nil).Body).Decode(&list)
+		if list.Total != 2 || len(list.Revealed) != 0 {
+			t.Fatalf("Expected no hint revealed yet, but got %+v", list)
+		}
+		for i, want := range []string{"<strong>two</strong>", "<code>n</code>"} {
+			rr := send("POST", "/api/problems/hinted/hints", "10.0.0.1", "", nil)
+			if rr.Code != http.StatusCreated {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+			}
+			var h HintView
+			json.NewDecoder(rr.Body).Decode(&h)
+			if h.Index != i || !strings.Contains(h.HTML, want) {
+				t.Errorf("Expected hint %d, but got %+v", i, h)
+			}
+		}
+		if rr := send("POST", "/api/problems/hinted/hints", "10.0.0.1", "", nil); rr.Code != http.StatusConflict {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusConflict)
+		}
+		list = HintsView{}
+		json.NewDecoder(send("GET", "/api/problems/hinted/hints", "10.0.0.1", "", nil).Body).Decode(&list)
+		if len(list.Revealed) != 2 {
+			t.Errorf("Expected both reveals to be recorded, but got %+v", list)
+		}
+		list = HintsView{}
+		json.NewDecoder(send("GET", "/api/problems/hinted/hints", "10.0.0.2", "", nil).Body).Decode(&list)
+		if len(list.Revealed) != 0 {
+			t.Errorf("Expected reveals to be per client, but got %+v", list)
+		}
+		if rr := send("GET", "/api/problems/missing/hints", "10.0.0.1", "", nil); rr.Code != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("Editorial", func(t *testing.T) {
+		reject := func(ip string) {
+			leaderboards.Add(submission.Submission{Owner: ip, ProblemID: "hinted", Mode: judge.ModeSubmit, Passed: 0, Total: 1})
+		}
+		rr := send("GET", "/api/problems/hinted/editorial", "10.0.0.3", "", nil)
+		if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), "after 2 rejected submissions (0 so far)") {
+			t.Errorf("Expected a locked editorial, but got %v %s", rr.Code, rr.Body)
+		}
+		reject("10.0.0.3")
+		reject("10.0.0.3")
+		var view EditorialView
+		rr = send("GET", "/api/problems/hinted/editorial", "10.0.0.3", "", nil)
+		json.NewDecoder(rr.Body).Decode(&view)
+		if rr.Code != http.StatusOK || view.UnlockedBy != "attempts" || !strings.Contains(view.HTML, "<code>n * 2</code>") {
+			t.Errorf("Expected the editorial after 2 rejections, but got %v %+v", rr.Code, view)
+		}
+		leaderboards.Add(submission.Submission{Owner: "10.0.0.4", ProblemID: "hinted", Mode: judge.ModeSubmit, Passed: 1, Total: 1})
+		view = EditorialView{}
+		json.NewDecoder(send("GET", "/api/problems/hinted/editorial", "10.0.0.4", "", nil).Body).Decode(&view)
+		if view.UnlockedBy != "solved" {
+			t.Errorf("Expected the editorial after a solve, but got %+v", view)
+		}
+	})
+
+	t.Run("Contest", func(t *testing.T) {
+		now := time.Now()
+		c := contest.Contest{ID: "cup", Title: "Cup", Start: now.Add(-time.Hour), End: now.Add(time.Hour),
+			Problems: []contest.Problem{{ID: "hinted", HintCost: 5}}}
+		if _, err := contestStore.Save(c); err != nil {
+			t.Fatal(err)
+		}
+		if rr := send("POST", "/api/problems/hinted/hints", "10.0.0.5", "", nil); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected a reveal outside the running contest to be rejected, but got %v", rr.Code)
+		}
+		if rr := send("POST", "/api/problems/hinted/hints", "10.0.0.5", "", map[string]string{"contestId": "missing"}); rr.Code != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+		if rr := send("POST", "/api/problems/hinted/hints", "10.0.0.5", "", map[string]string{"contestId": "cup"}); rr.Code != http.StatusCreated {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+		}
+		if got := contestReveals("cup"); len(got) != 1 || got[0].Owner != "10.0.0.5" {
+			t.Errorf("Expected the reveal to be charged to the contest, but got %+v", got)
+		}
+		if rr := send("GET", "/api/problems/hinted/editorial", "10.0.0.4", "", nil); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected the editorial to be locked during the contest, but got %v", rr.Code)
+		}
+		var view EditorialView
+		json.NewDecoder(send("GET", "/api/problems/hinted/editorial", "10.0.0.4", "secret", nil).Body).Decode(&view)
+		if view.UnlockedBy != "admin" {
+			t.Errorf("Expected admins to read the editorial, but got %+v", view)
+		}
+	})
+}
@@ -0,0 +1,233 @@
+// Package catalog searches the problem bank. It keeps an in-memory inverted
+// index over titles, tags, function names and statements, ranked with BM25,
+// so search needs no external service.
+package catalog
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+)
+
+// Weights of a term by the field it appears in
+const (
+	TitleWeight     = 3
+	TagWeight       = 2
+	FunctionWeight  = 2
+	StatementWeight = 1
+)
+
+// BM25 parameters
+const (
+	k1 = 1.2
+	b  = 0.75
+	// prefixDiscount scales terms that only start with a query term
+	prefixDiscount = 0.5
+)
+
+// Hit is a problem matching a search, with its relevance
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+// doc is an indexed version of a problem
+type doc struct {
+	version int
+	terms   map[string]int
+	length  int
+}
+
+// Index is an inverted index of problems
+type Index struct {
+	mu   sync.Mutex
+	docs map[string]doc
+	// postings maps each term to the weighted frequency per problem
+	postings map[string]map[string]int
+	// terms holds the keys of postings sorted for prefix lookups, nil when
+	// it needs rebuilding
+	terms       []string
+	totalLength int
+}
+
+// New creates an empty index
+func New() *Index {
+	return &Index{docs: make(map[string]doc), postings: make(map[string]map[string]int)}
+}
+
+// Tokenize splits text into lowercase words of letters and digits. A
+// camelCase or snake_case identifier also yields its parts, so intIntoString
+// is found by "string".
+func Tokenize(text string) []string {
+	var tokens []string
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}) {
+		parts := splitIdentifier(word)
+		if len(parts) > 1 {
+			tokens = append(tokens, strings.ToLower(strings.ReplaceAll(word, "_", "")))
+		}
+		for _, part := range parts {
+			tokens = append(tokens, strings.ToLower(part))
+		}
+	}
+	return tokens
+}
+
+// splitIdentifier splits a word at underscores and lower to upper case
+// changes
+func splitIdentifier(word string) []string {
+	var parts []string
+	for _, chunk := range strings.Split(word, "_") {
+		start := 0
+		runes := []rune(chunk)
+		for i := 1; i < len(runes); i++ {
+			if unicode.IsUpper(runes[i]) && !unicode.IsUpper(runes[i-1]) {
+				parts = append(parts, string(runes[start:i]))
+				start = i
+			}
+		}
+		if start < len(runes) {
+			parts = append(parts, string(runes[start:]))
+		}
+	}
+	return parts
+}
+
+// Sync brings the index in line with the bank's problems: new problems and
+// new versions are indexed, removed problems dropped. Unchanged problems
+// cost a map lookup, so calling it before each search is cheap.
+func (x *Index) Sync(problems []problem.Problem) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	seen := make(map[string]bool, len(problems))
+	for _, p := range problems {
+		seen[p.ID] = true
+		if d, ok := x.docs[p.ID]; ok && d.version == p.Version {
+			continue
+		}
+		x.remove(p.ID)
+		x.add(p)
+	}
+	for id := range x.docs {
+		if !seen[id] {
+			x.remove(id)
+		}
+	}
+}
+
+func (x *Index) add(p problem.Problem) {
+	d := doc{version: p.Version, terms: map[string]int{}}
+	for _, field := range []struct {
+		text   string
+		weight int
+	}{
+		{p.Title, TitleWeight},
+		{strings.Join(p.Tags, " "), TagWeight},
+		{p.Function, FunctionWeight},
+		{p.Statement, StatementWeight},
+	} {
+		for _, term := range Tokenize(field.text) {
+			d.terms[term] += field.weight
+			d.length += field.weight
+		}
+	}
+	for term, tf := range d.terms {
+		if x.postings[term] == nil {
+			x.postings[term] = map[string]int{}
+			x.terms = nil
+		}
+		x.postings[term][p.ID] = tf
+	}
+	x.docs[p.ID] = d
+	x.totalLength += d.length
+}
+
+func (x *Index) remove(id string) {
+	d, ok := x.docs[id]
+	if !ok {
+		return
+	}
+	for term := range d.terms {
+		delete(x.postings[term], id)
+		if len(x.postings[term]) == 0 {
+			delete(x.postings, term)
+			x.terms = nil
+		}
+	}
+	delete(x.docs, id)
+	x.totalLength -= d.length
+}
+
+// expand returns the indexed terms equal to or starting with term
+func (x *Index) expand(term string) []string {
+	i := sort.SearchStrings(x.terms, term)
+	var matches []string
+	for ; i < len(x.terms) && strings.HasPrefix(x.terms[i], term); i++ {
+		matches = append(matches, x.terms[i])
+	}
+	return matches
+}
+
+// Search returns the problems matching every word of the query, most
+// relevant first. A query word also matches the words it starts with.
+func (x *Index) Search(query string) []Hit {
+	words := Tokenize(query)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.terms == nil {
+		x.terms = make([]string, 0, len(x.postings))
+		for term := range x.postings {
+			x.terms = append(x.terms, term)
+		}
+		sort.Strings(x.terms)
+	}
+	hits := []Hit{}
+	if len(words) == 0 || len(x.docs) == 0 {
+		return hits
+	}
+	n := float64(len(x.docs))
+	avgLength := float64(x.totalLength) / n
+	scores := map[string]float64{}
+	for i, word := range words {
+		// A problem stays a candidate only if it matches every word
+		matched := map[string]float64{}
+		for _, term := range x.expand(word) {
+			postings := x.postings[term]
+			idf := math.Log(1 + (n-float64(len(postings))+0.5)/(float64(len(postings))+0.5))
+			for id, tf := range postings {
+				length := float64(x.docs[id].length)
+				score := idf * float64(tf) * (k1 + 1) / (float64(tf) + k1*(1-b+b*length/avgLength))
+				if term != word {
+					score *= prefixDiscount
+				}
+				matched[id] = max(matched[id], score)
+			}
+		}
+		if i == 0 {
+			scores = matched
+			continue
+		}
+		for id := range scores {
+			if score, ok := matched[id]; ok {
+				scores[id] += score
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	return hits
+}
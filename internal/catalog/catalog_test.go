@@ -0,0 +1,70 @@
+package catalog
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+)
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("Write `intIntoString`: max_sum of HTTPServer, 2 arrays!")
+	want := []string{"write", "intintostring", "int", "into", "string", "maxsum", "max", "sum", "of", "httpserver", "2", "arrays"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, but got %v", want, got)
+	}
+}
+
+func ids(hits []Hit) []string {
+	list := []string{}
+	for _, h := range hits {
+		list = append(list, h.ID)
+	}
+	return list
+}
+
+func TestSearch(t *testing.T) {
+	problems := []problem.Problem{
+		{ID: "two-sum", Version: 1, Title: "Two Sum", Function: "twoSum", Tags: []string{"arrays", "hashing"},
+			Statement: "Given an array of numbers, return the indices of the two numbers that add up to the target."},
+		{ID: "reverse", Version: 1, Title: "Reverse a String", Function: "reverse", Tags: []string{"strings"},
+			Statement: "Return the string backwards. Sum nothing."},
+		{ID: "max-subarray", Version: 1, Title: "Maximum Subarray", Function: "maxSubarray", Tags: []string{"arrays", "dp"},
+			Statement: "Find the contiguous subarray with the largest sum and return the sum."},
+	}
+	x := New()
+	x.Sync(problems)
+
+	testCases := []struct {
+		query string
+		ids   []string
+	}{
+		{"sum", []string{"two-sum", "max-subarray", "reverse"}},
+		{"array sum", []string{"two-sum", "max-subarray"}},
+		{"revers", []string{"reverse"}},
+		{"Subarray", []string{"max-subarray"}},
+		{"dp", []string{"max-subarray"}},
+		{"graph", []string{}},
+		{"  ", []string{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			if got := ids(x.Search(tc.query)); !reflect.DeepEqual(got, tc.ids) {
+				t.Errorf("Expected %v, but got %v", tc.ids, got)
+			}
+		})
+	}
+
+	// A new version is reindexed, a removed problem is dropped
+	problems[1].Version, problems[1].Title = 2, "Palindrome Check"
+	x.Sync(problems[1:])
+	if got := ids(x.Search("sum")); !reflect.DeepEqual(got, []string{"max-subarray", "reverse"}) {
+		t.Errorf("Expected two-sum to be gone, but got %v", got)
+	}
+	if got := ids(x.Search("palindrome")); !reflect.DeepEqual(got, []string{"reverse"}) {
+		t.Errorf("Expected the new title to be indexed, but got %v", got)
+	}
+	if got := ids(x.Search("two")); len(got) != 0 {
+		t.Errorf("Expected no stale terms, but got %v", got)
+	}
+}
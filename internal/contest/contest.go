@@ -0,0 +1,158 @@
+// Package contest runs timed contests over problems of the bank. A contest
+// accepts submissions between its start and end, scores them ICPC style
+// (problems solved, then penalty time) or IOI style (partial points), and
+// can freeze its public scoreboard for the last minutes.
+package contest
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// ErrNotFound is returned for an unknown contest ID
+var ErrNotFound = errors.New("contest not found")
+
+// Scoring rules
+const (
+	// ICPC ranks by the points of solved problems, then by penalty time:
+	// the minutes to each solve plus PenaltyMinutes per rejected attempt
+	// before it
+	ICPC = "icpc"
+	// IOI awards each problem's points in proportion to the best score
+	IOI = "ioi"
+)
+
+// DefaultPenaltyMinutes is added per rejected attempt on an ICPC solve
+const DefaultPenaltyMinutes = 20
+
+// validID matches contest IDs, which also name files and URL segments
+var validID = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Problem is a problem of the bank as part of a contest
+type Problem struct {
+	ID string `json:"id"`
+	// Points defaults to 1 under ICPC scoring and 100 under IOI scoring
+	Points int `json:"points"`
+	// HintCost is what each hint revealed before an attempt costs it: points
+	// off an IOI score, penalty minutes on an ICPC solve
+	HintCost int `json:"hintCost,omitempty"`
+}
+
+// Quota limits how many submissions a client makes to a contest per window,
+// on top of the server's own rate limit
+type Quota struct {
+	Submissions   int `json:"submissions"`
+	WindowSeconds int `json:"windowSeconds"`
+}
+
+// Window returns the quota's window as a duration
+func (q Quota) Window() time.Duration {
+	return time.Duration(q.WindowSeconds) * time.Second
+}
+
+// Contest is a timed set of problems
+type Contest struct {
+	ID       string    `json:"id"`
+	Title    string    `json:"title"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Problems []Problem `json:"problems"`
+	Scoring  string    `json:"scoring"`
+	// PenaltyMinutes is the ICPC penalty per rejected attempt
+	PenaltyMinutes int `json:"penaltyMinutes,omitempty"`
+	// FreezeMinutes hides the results of the last minutes from the public
+	// scoreboard until the contest ends
+	FreezeMinutes int    `json:"freezeMinutes,omitempty"`
+	Quota         *Quota `json:"quota,omitempty"`
+}
+
+// Normalize fills in defaults and checks that the contest can run
+func (c *Contest) Normalize() error {
+	if !validID.MatchString(c.ID) {
+		return fmt.Errorf("contest id %q may only contain lowercase letters, digits, - and _", c.ID)
+	}
+	if c.Title == "" {
+		return fmt.Errorf("contest %s: title is required", c.ID)
+	}
+	if c.Start.IsZero() || !c.End.After(c.Start) {
+		return fmt.Errorf("contest %s: end must be after start", c.ID)
+	}
+	if c.Scoring == "" {
+		c.Scoring = ICPC
+	}
+	if c.Scoring != ICPC && c.Scoring != IOI {
+		return fmt.Errorf("contest %s: unknown scoring %q, expected %s or %s", c.ID, c.Scoring, ICPC, IOI)
+	}
+	if len(c.Problems) == 0 {
+		return fmt.Errorf("contest %s: at least one problem is required", c.ID)
+	}
+	seen := map[string]bool{}
+	for i := range c.Problems {
+		p := &c.Problems[i]
+		if seen[p.ID] {
+			return fmt.Errorf("contest %s: problem %q is listed twice", c.ID, p.ID)
+		}
+		seen[p.ID] = true
+		if p.Points < 0 || p.HintCost < 0 {
+			return fmt.Errorf("contest %s: problem %s has negative points or hint cost", c.ID, p.ID)
+		}
+		if p.Points == 0 {
+			p.Points = 1
+			if c.Scoring == IOI {
+				p.Points = 100
+			}
+		}
+	}
+	if c.PenaltyMinutes < 0 || c.FreezeMinutes < 0 {
+		return fmt.Errorf("contest %s: penalty and freeze minutes cannot be negative", c.ID)
+	}
+	if c.PenaltyMinutes == 0 && c.Scoring == ICPC {
+		c.PenaltyMinutes = DefaultPenaltyMinutes
+	}
+	if time.Duration(c.FreezeMinutes)*time.Minute > c.End.Sub(c.Start) {
+		return fmt.Errorf("contest %s: the freeze is longer than the contest", c.ID)
+	}
+	if c.Quota != nil && (c.Quota.Submissions <= 0 || c.Quota.WindowSeconds <= 0) {
+		return fmt.Errorf("contest %s: quota needs positive submissions and windowSeconds", c.ID)
+	}
+	return nil
+}
+
+// Problem returns a problem of the contest
+func (c Contest) Problem(id string) (Problem, bool) {
+	for _, p := range c.Problems {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return Problem{}, false
+}
+
+// State is "upcoming", "running" or "ended" at t
+func (c Contest) State(t time.Time) string {
+	switch {
+	case t.Before(c.Start):
+		return "upcoming"
+	case t.Before(c.End):
+		return "running"
+	default:
+		return "ended"
+	}
+}
+
+// Running reports whether the contest accepts submissions at t
+func (c Contest) Running(t time.Time) bool {
+	return c.State(t) == "running"
+}
+
+// FreezeAt is when the public scoreboard stops changing
+func (c Contest) FreezeAt() time.Time {
+	return c.End.Add(-time.Duration(c.FreezeMinutes) * time.Minute)
+}
+
+// Frozen reports whether the public scoreboard is frozen at t
+func (c Contest) Frozen(t time.Time) bool {
+	return c.FreezeMinutes > 0 && !t.Before(c.FreezeAt()) && t.Before(c.End)
+}
@@ -0,0 +1,123 @@
+package contest
+
+import (
+	"testing"
+	"time"
+)
+
+var start = time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+// sample returns a two hour contest over two problems
+func sample(scoring string) Contest {
+	return Contest{
+		ID:            "spring",
+		Title:         "Spring Contest",
+		Start:         start,
+		End:           start.Add(2 * time.Hour),
+		Problems:      []Problem{{ID: "sum"}, {ID: "max", Points: 3}},
+		Scoring:       scoring,
+		FreezeMinutes: 30,
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	c := sample("")
+	if err := c.Normalize(); err != nil {
+		t.Fatalf("Normalize failed: %v", err)
+	}
+	if c.Scoring != ICPC || c.PenaltyMinutes != DefaultPenaltyMinutes || c.Problems[0].Points != 1 || c.Problems[1].Points != 3 {
+		t.Errorf("Expected ICPC defaults, but got %+v", c)
+	}
+	c = sample(IOI)
+	c.Normalize()
+	if c.PenaltyMinutes != 0 || c.Problems[0].Points != 100 {
+		t.Errorf("Expected IOI defaults, but got %+v", c)
+	}
+
+	testCases := []struct {
+		name   string
+		modify func(c *Contest)
+	}{
+		{"bad id", func(c *Contest) { c.ID = "Spring Contest" }},
+		{"no title", func(c *Contest) { c.Title = "" }},
+		{"end before start", func(c *Contest) { c.End = c.Start }},
+		{"unknown scoring", func(c *Contest) { c.Scoring = "golf" }},
+		{"no problems", func(c *Contest) { c.Problems = nil }},
+		{"duplicate problem", func(c *Contest) { c.Problems = append(c.Problems, Problem{ID: "sum"}) }},
+		{"freeze too long", func(c *Contest) { c.FreezeMinutes = 121 }},
+		{"empty quota", func(c *Contest) { c.Quota = &Quota{Submissions: 5} }},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := sample(ICPC)
+			tc.modify(&c)
+			if err := c.Normalize(); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestWindow(t *testing.T) {
+	c := sample(ICPC)
+	testCases := []struct {
+		at      time.Time
+		state   string
+		running bool
+		frozen  bool
+	}{
+		{start.Add(-time.Second), "upcoming", false, false},
+		{start, "running", true, false},
+		{start.Add(89 * time.Minute), "running", true, false},
+		{start.Add(90 * time.Minute), "running", true, true},
+		{start.Add(2 * time.Hour), "ended", false, false},
+	}
+	for _, tc := range testCases {
+		if got := c.State(tc.at); got != tc.state {
+			t.Errorf("Expected %s at %v, but got %s", tc.state, tc.at, got)
+		}
+		if got := c.Running(tc.at); got != tc.running {
+			t.Errorf("Expected running %v at %v, but got %v", tc.running, tc.at, got)
+		}
+		if got := c.Frozen(tc.at); got != tc.frozen {
+			t.Errorf("Expected frozen %v at %v, but got %v", tc.frozen, tc.at, got)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Save(Contest{ID: "bad"}); err == nil {
+		t.Error("Expected an invalid contest to be rejected")
+	}
+	saved, err := store.Save(sample(""))
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if saved.Scoring != ICPC {
+		t.Errorf("Expected the saved contest to be normalized, but got %+v", saved)
+	}
+	later := sample(IOI)
+	later.ID, later.Start, later.End = "summer", start.AddDate(0, 3, 0), start.AddDate(0, 3, 1)
+	store.Save(later)
+
+	got, err := store.Get("spring")
+	if err != nil || got.Title != "Spring Contest" || !got.Start.Equal(start) {
+		t.Errorf("Expected the spring contest, but got %+v, %v", got, err)
+	}
+	if list, _ := store.List(); len(list) != 2 || list[0].ID != "summer" {
+		t.Errorf("Expected the latest contest first, but got %+v", list)
+	}
+	if err := store.Delete("spring"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Get("spring"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, but got %v", err)
+	}
+	if err := store.Delete("spring"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, but got %v", err)
+	}
+}
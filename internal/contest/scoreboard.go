@@ -0,0 +1,224 @@
+package contest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/hint"
+	"github.com/afman42/go-web-code-interactive/internal/submission"
+)
+
+// Attempt is a graded submission to a contest problem
+type Attempt struct {
+	SubmissionID string
+	Owner        string
+	ProblemID    string
+	Passed       int
+	Total        int
+	Score        int
+	Created      time.Time
+}
+
+// accepted reports whether the attempt passed every test
+func (a Attempt) accepted() bool {
+	return a.Passed == a.Total
+}
+
+// Attempts holds the graded submissions of every contest in memory. It is
+// filled from the submission history at startup and grows as submissions
+// are recorded.
+type Attempts struct {
+	mu        sync.RWMutex
+	byContest map[string][]Attempt
+}
+
+// NewAttempts creates an empty collection
+func NewAttempts() *Attempts {
+	return &Attempts{byContest: make(map[string][]Attempt)}
+}
+
+// Add records a submission made to a contest. Only submissions graded on
+// every test (mode "submit") count; Add reports whether it did.
+func (a *Attempts) Add(s submission.Submission) bool {
+	if s.ContestID == "" || s.ProblemID == "" || s.Mode != "submit" || s.Total == 0 {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.byContest[s.ContestID] = append(a.byContest[s.ContestID], Attempt{
+		SubmissionID: s.ID,
+		Owner:        s.Owner,
+		ProblemID:    s.ProblemID,
+		Passed:       s.Passed,
+		Total:        s.Total,
+		Score:        s.Score,
+		Created:      s.Created,
+	})
+	return true
+}
+
+// List returns the attempts made to a contest
+func (a *Attempts) List(contestID string) []Attempt {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return append([]Attempt(nil), a.byContest[contestID]...)
+}
+
+// Cell is a client's result on one problem
+type Cell struct {
+	// Attempts counts the attempts the scoreboard shows, Pending those made
+	// while it is frozen
+	Attempts int  `json:"attempts"`
+	Pending  int  `json:"pending,omitempty"`
+	Solved   bool `json:"solved"`
+	// Hints counts the hints revealed during the contest
+	Hints int `json:"hints,omitempty"`
+	// Minutes is when the problem was solved, from the start of the contest
+	Minutes int `json:"minutes,omitempty"`
+	Points  int `json:"points"`
+}
+
+// Row is a client's line of the scoreboard
+type Row struct {
+	Rank  int    `json:"rank"`
+	Owner string `json:"-"`
+	// Points sums the points of solved problems (ICPC) or of every problem
+	// in proportion to its best score (IOI)
+	Points  int `json:"points"`
+	Solved  int `json:"solved"`
+	Penalty int `json:"penalty"`
+	// LastSolved is when the row last gained points, which orders rows that
+	// share a rank
+	LastSolved time.Time        `json:"-"`
+	Problems   map[string]*Cell `json:"problems"`
+}
+
+// above reports whether a ranks above b
+func (a Row) above(b Row) bool {
+	if a.Points != b.Points {
+		return a.Points > b.Points
+	}
+	return a.Penalty < b.Penalty
+}
+
+// Scoreboard is the standing of a contest at some time
+type Scoreboard struct {
+	ContestID string `json:"contestId"`
+	Scoring   string `json:"scoring"`
+	State     string `json:"state"`
+	// Frozen is set when attempts made since FreezeAt are held back
+	Frozen   bool       `json:"frozen"`
+	FreezeAt *time.Time `json:"freezeAt,omitempty"`
+	Rows     []Row      `json:"rows"`
+}
+
+// Compute scores the attempts of a contest as of now, charging the hints
+// revealed during it. While the contest is frozen, attempts made since the
+// freeze only count as pending unless live is set. Attempts and reveals
+// outside the contest window are ignored.
+func Compute(c Contest, attempts []Attempt, reveals []hint.Reveal, now time.Time, live bool) Scoreboard {
+	board := Scoreboard{ContestID: c.ID, Scoring: c.Scoring, State: c.State(now), Rows: []Row{}}
+	if c.Frozen(now) && !live {
+		freezeAt := c.FreezeAt()
+		board.Frozen, board.FreezeAt = true, &freezeAt
+	}
+	attempts = append([]Attempt(nil), attempts...)
+	sort.SliceStable(attempts, func(i, j int) bool { return attempts[i].Created.Before(attempts[j].Created) })
+
+	// revealed holds the reveal times per client and problem, in order
+	revealed := map[[2]string][]time.Time{}
+	for _, r := range reveals {
+		if r.Created.Before(c.Start) || !r.Created.Before(c.End) {
+			continue
+		}
+		key := [2]string{r.Owner, r.ProblemID}
+		revealed[key] = append(revealed[key], r.Created)
+	}
+	for _, times := range revealed {
+		sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	}
+	// hintsBefore counts the hints a client revealed on a problem before t
+	hintsBefore := func(owner, problemID string, t time.Time) int {
+		times := revealed[[2]string{owner, problemID}]
+		return sort.Search(len(times), func(i int) bool { return !times[i].Before(t) })
+	}
+
+	rows := map[string]*Row{}
+	for _, a := range attempts {
+		p, ok := c.Problem(a.ProblemID)
+		if !ok || a.Created.Before(c.Start) || !a.Created.Before(c.End) {
+			continue
+		}
+		row := rows[a.Owner]
+		if row == nil {
+			row = &Row{Owner: a.Owner, Problems: map[string]*Cell{}}
+			rows[a.Owner] = row
+		}
+		cell := row.Problems[p.ID]
+		if cell == nil {
+			cell = &Cell{}
+			row.Problems[p.ID] = cell
+		}
+		cost := p.HintCost * hintsBefore(a.Owner, p.ID, a.Created)
+		if board.Frozen && !a.Created.Before(*board.FreezeAt) {
+			cell.Pending++
+			continue
+		}
+		minutes := int(a.Created.Sub(c.Start) / time.Minute)
+		switch c.Scoring {
+		case ICPC:
+			if cell.Solved {
+				continue
+			}
+			cell.Attempts++
+			if a.accepted() {
+				cell.Solved, cell.Minutes, cell.Points = true, minutes, p.Points
+				row.Points += p.Points
+				row.Solved++
+				row.Penalty += minutes + (cell.Attempts-1)*c.PenaltyMinutes + cost
+				row.LastSolved = a.Created
+			}
+		case IOI:
+			cell.Attempts++
+			if points := max(0, a.Score*p.Points/100-cost); points > cell.Points {
+				row.Points += points - cell.Points
+				cell.Points = points
+				row.LastSolved = a.Created
+			}
+			if a.accepted() && !cell.Solved {
+				cell.Solved, cell.Minutes = true, minutes
+				row.Solved++
+			}
+		}
+	}
+
+	cutoff := now
+	if board.Frozen {
+		cutoff = *board.FreezeAt
+	}
+	for _, row := range rows {
+		for id, cell := range row.Problems {
+			cell.Hints = hintsBefore(row.Owner, id, cutoff)
+		}
+		board.Rows = append(board.Rows, *row)
+	}
+	sort.Slice(board.Rows, func(i, j int) bool {
+		a, b := board.Rows[i], board.Rows[j]
+		if a.above(b) || b.above(a) {
+			return a.above(b)
+		}
+		if !a.LastSolved.Equal(b.LastSolved) {
+			return a.LastSolved.Before(b.LastSolved)
+		}
+		return a.Owner < b.Owner
+	})
+	// Rows that tie on points and penalty share a rank
+	for i := range board.Rows {
+		board.Rows[i].Rank = i + 1
+		if i > 0 && !board.Rows[i-1].above(board.Rows[i]) {
+			board.Rows[i].Rank = board.Rows[i-1].Rank
+		}
+	}
+	return board
+}
@@ -0,0 +1,161 @@
+package contest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/hint"
+	"github.com/afman42/go-web-code-interactive/internal/submission"
+)
+
+// attempt is made minutes after the start of the sample contest
+func attempt(owner, problem string, passed, minutes int) Attempt {
+	return Attempt{Owner: owner, ProblemID: problem, Passed: passed, Total: 4, Score: passed * 25, Created: start.Add(time.Duration(minutes) * time.Minute)}
+}
+
+func ranks(board Scoreboard) map[string]int {
+	ranks := map[string]int{}
+	for _, row := range board.Rows {
+		ranks[row.Owner] = row.Rank
+	}
+	return ranks
+}
+
+func TestComputeICPC(t *testing.T) {
+	c := sample(ICPC)
+	c.Normalize()
+	attempts := []Attempt{
+		attempt("a", "sum", 2, 10),
+		attempt("a", "sum", 4, 25),
+		attempt("b", "sum", 4, 40),
+		attempt("b", "max", 4, 50),
+		attempt("c", "max", 4, 60),
+		// After a solve, further attempts do not count
+		attempt("c", "max", 0, 70),
+		// Made during the freeze
+		attempt("a", "max", 4, 100),
+		// Outside the contest or not one of its problems
+		attempt("d", "sum", 4, -5),
+		attempt("d", "sum", 4, 120),
+		attempt("d", "other", 4, 30),
+	}
+
+	board := Compute(c, attempts, nil, start.Add(110*time.Minute), false)
+	if !board.Frozen || board.FreezeAt == nil || !board.FreezeAt.Equal(start.Add(90*time.Minute)) {
+		t.Fatalf("Expected a frozen board, but got %+v", board)
+	}
+	if len(board.Rows) != 3 {
+		t.Fatalf("Expected 3 rows, but got %+v", board.Rows)
+	}
+	// b solved both (1 + 3 points), c solved max (3), a solved sum (1)
+	if want := map[string]int{"b": 1, "c": 2, "a": 3}; !equalRanks(ranks(board), want) {
+		t.Errorf("Expected %v, but got %v", want, ranks(board))
+	}
+	a := board.Rows[2]
+	if a.Penalty != 25+DefaultPenaltyMinutes || a.Problems["sum"].Attempts != 2 || a.Problems["max"].Pending != 1 || a.Problems["max"].Solved {
+		t.Errorf("Expected a's penalty and pending attempt, but got %+v %+v %+v", a, a.Problems["sum"], a.Problems["max"])
+	}
+	if c := board.Rows[1]; c.Problems["max"].Attempts != 1 || c.Penalty != 60 {
+		t.Errorf("Expected c's single attempt, but got %+v", c.Problems["max"])
+	}
+
+	// Admins see through the freeze, and it lifts when the contest ends
+	for _, board := range []Scoreboard{
+		Compute(c, attempts, nil, start.Add(110*time.Minute), true),
+		Compute(c, attempts, nil, start.Add(3*time.Hour), false),
+	} {
+		if board.Frozen || board.Rows[0].Owner != "b" || board.Rows[1].Owner != "a" || board.Rows[1].Points != 4 {
+			t.Errorf("Expected a second after the frozen solve, but got %+v", board.Rows)
+		}
+	}
+}
+
+func TestComputeIOI(t *testing.T) {
+	c := sample(IOI)
+	c.FreezeMinutes, c.Problems[1].Points = 0, 300
+	c.Normalize()
+	board := Compute(c, []Attempt{
+		attempt("a", "sum", 2, 10),
+		attempt("a", "sum", 1, 20),
+		attempt("a", "max", 4, 30),
+		attempt("b", "sum", 4, 10),
+		attempt("b", "max", 3, 15),
+		attempt("c", "sum", 4, 50),
+		attempt("c", "max", 3, 55),
+	}, nil, start.Add(time.Hour), false)
+
+	// a: 50 + 300, b and c: 100 + 225 and share a rank
+	if want := map[string]int{"a": 1, "b": 2, "c": 2}; !equalRanks(ranks(board), want) {
+		t.Errorf("Expected %v, but got %v", want, ranks(board))
+	}
+	if board.Rows[0].Points != 350 || board.Rows[0].Problems["sum"].Points != 50 || board.Rows[0].Solved != 1 {
+		t.Errorf("Expected a's best partial score to count, but got %+v", board.Rows[0])
+	}
+	if board.Rows[1].Owner != "b" {
+		t.Errorf("Expected b, who got there first, before c, but got %+v", board.Rows)
+	}
+}
+
+func TestComputeHintCost(t *testing.T) {
+	reveal := func(owner, problem string, minutes int) hint.Reveal {
+		return hint.Reveal{Owner: owner, ProblemID: problem, Created: start.Add(time.Duration(minutes) * time.Minute)}
+	}
+	reveals := []hint.Reveal{
+		reveal("a", "sum", 5),
+		reveal("a", "sum", 8),
+		// After the attempt it would have helped, or before the contest
+		reveal("a", "sum", 30),
+		reveal("b", "sum", -10),
+	}
+	attempts := []Attempt{attempt("a", "sum", 4, 20), attempt("b", "sum", 3, 20)}
+
+	icpc := sample(ICPC)
+	icpc.Problems[0].HintCost = 10
+	icpc.Normalize()
+	board := Compute(icpc, attempts, reveals, start.Add(time.Hour), false)
+	if a := board.Rows[0]; a.Owner != "a" || a.Penalty != 20+2*10 || a.Problems["sum"].Hints != 3 {
+		t.Errorf("Expected 2 hints on a's penalty, but got %+v %+v", a, a.Problems["sum"])
+	}
+
+	ioi := sample(IOI)
+	ioi.FreezeMinutes, ioi.Problems[0].HintCost = 0, 30
+	ioi.Normalize()
+	board = Compute(ioi, attempts, reveals, start.Add(time.Hour), false)
+	// a: 100 - 2*30, b: 75 with the hint from before the contest free
+	if want := map[string]int{"b": 1, "a": 2}; !equalRanks(ranks(board), want) {
+		t.Errorf("Expected %v, but got %v", want, ranks(board))
+	}
+	if board.Rows[1].Points != 40 || board.Rows[0].Points != 75 || board.Rows[0].Problems["sum"].Hints != 0 {
+		t.Errorf("Expected hint costs off the IOI scores, but got %+v", board.Rows)
+	}
+}
+
+func TestAttempts(t *testing.T) {
+	a := NewAttempts()
+	for _, s := range []submission.Submission{
+		{ContestID: "spring", ProblemID: "sum", Mode: "run", Passed: 1, Total: 1},
+		{ProblemID: "sum", Mode: "submit", Passed: 1, Total: 1},
+	} {
+		if a.Add(s) {
+			t.Errorf("Expected %+v not to count", s)
+		}
+	}
+	if !a.Add(submission.Submission{ID: "x", Owner: "o", ContestID: "spring", ProblemID: "sum", Mode: "submit", Passed: 1, Total: 2, Score: 50}) {
+		t.Error("Expected a graded contest submission to count")
+	}
+	if list := a.List("spring"); len(list) != 1 || list[0].SubmissionID != "x" || list[0].Score != 50 {
+		t.Errorf("Expected the attempt, but got %+v", list)
+	}
+}
+
+func equalRanks(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
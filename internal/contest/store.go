@@ -0,0 +1,95 @@
+package contest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Store persists contests as one JSON file per contest
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewStore creates a store rooted at dir, creating the directory if needed
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Save normalizes and writes a contest, creating or replacing it
+func (s *Store) Save(c Contest) (Contest, error) {
+	if err := c.Normalize(); err != nil {
+		return Contest{}, err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return Contest{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return c, os.WriteFile(s.path(c.ID), data, 0o644)
+}
+
+// Get loads a contest by ID
+func (s *Store) Get(id string) (Contest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read(id)
+}
+
+// List returns every contest, the latest start first
+func (s *Store) List() ([]Contest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	contests := []Contest{}
+	for _, f := range files {
+		c, err := s.read(strings.TrimSuffix(filepath.Base(f), ".json"))
+		if err != nil {
+			continue
+		}
+		contests = append(contests, c)
+	}
+	sort.Slice(contests, func(i, j int) bool {
+		return contests[i].Start.After(contests[j].Start)
+	})
+	return contests, nil
+}
+
+// Delete removes a contest
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.read(id); err != nil {
+		return err
+	}
+	return os.Remove(s.path(id))
+}
+
+func (s *Store) read(id string) (Contest, error) {
+	var c Contest
+	data, err := os.ReadFile(s.path(id))
+	if os.IsNotExist(err) {
+		return c, ErrNotFound
+	}
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
+
+// path maps an ID to its file, rejecting anything that is not a plain name
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, filepath.Base(filepath.Clean("/"+id))+".json")
+}
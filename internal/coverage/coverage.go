@@ -0,0 +1,202 @@
+// Package coverage turns the raw coverage data of Go and Node runs into hit
+// counts per line of the user's code. Only the user's own file is read from
+// the data, so harness and test files running next to it never shift or
+// pad the lines.
+package coverage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+const (
+	// Dir is the directory in a run's scratch directory that Go binaries
+	// built with -cover and Node write their raw coverage data to
+	Dir = "stq_cover"
+	// ProfileFile is the name of the Go text profile in the scratch directory
+	ProfileFile = "stq_cover.out"
+)
+
+// Env returns the environment variable making a language's programs write
+// their coverage data to dir, see Dir
+func Env(language, dir string) string {
+	if language == "go" {
+		return "GOCOVERDIR=" + dir
+	}
+	return "NODE_V8_COVERAGE=" + dir
+}
+
+// Line is how often a line of code ran
+type Line struct {
+	Line int `json:"line"`
+	Hits int `json:"hits"`
+}
+
+// Report lists the lines of the user's code holding executable code, in
+// order; lines missing from it have nothing to run, such as blank lines and
+// comments
+type Report struct {
+	Lines   []Line `json:"lines"`
+	Covered int    `json:"covered"`
+	Total   int    `json:"total"`
+	// Percent is the share of the lines that ran, rounded to a tenth
+	Percent float64 `json:"percent"`
+}
+
+// Profile adds up the coverage of one source file over any number of runs
+type Profile struct {
+	lines []string
+	hits  map[int]int
+}
+
+// New returns an empty profile of the source
+func New(source string) *Profile {
+	return &Profile{lines: strings.Split(source, "\n"), hits: map[int]int{}}
+}
+
+// code reports whether a 1-based line holds more than blanks and comments
+func (p *Profile) code(line int) bool {
+	if line < 1 || line > len(p.lines) {
+		return false
+	}
+	text := strings.TrimSpace(p.lines[line-1])
+	return text != "" && !strings.HasPrefix(text, "//") && !strings.HasPrefix(text, "/*") && !strings.HasPrefix(text, "*")
+}
+
+// merge adds the hit counts of one run
+func (p *Profile) merge(run map[int]int) {
+	for line, n := range run {
+		p.hits[line] += n
+	}
+}
+
+// AddGo adds a Go text profile, as written by `go test -coverprofile` or
+// `go tool covdata textfmt`. Each block of file counts toward every line it
+// spans; a line in several blocks takes the highest count.
+func (p *Profile) AddGo(profile []byte, file string) error {
+	run := map[int]int{}
+	scanner := bufio.NewScanner(bytes.NewReader(profile))
+	for scanner.Scan() {
+		text := scanner.Text()
+		if text == "" || strings.HasPrefix(text, "mode:") {
+			continue
+		}
+		// name:startLine.startCol,endLine.endCol statements count
+		colon := strings.LastIndexByte(text, ':')
+		fields := strings.Fields(text[colon+1:])
+		if colon < 0 || len(fields) != 3 {
+			return fmt.Errorf("invalid profile line %q", text)
+		}
+		if filepath.Base(filepath.ToSlash(text[:colon])) != file {
+			continue
+		}
+		start, end, ok := strings.Cut(fields[0], ",")
+		from, err1 := strconv.Atoi(strings.Split(start, ".")[0])
+		to, err2 := strconv.Atoi(strings.Split(end, ".")[0])
+		count, err3 := strconv.Atoi(fields[2])
+		if !ok || err1 != nil || err2 != nil || err3 != nil {
+			return fmt.Errorf("invalid profile line %q", text)
+		}
+		for line := from; line <= to; line++ {
+			if p.code(line) {
+				run[line] = max(run[line], count)
+			}
+		}
+	}
+	p.merge(run)
+	return scanner.Err()
+}
+
+// v8Coverage is a file Node writes to NODE_V8_COVERAGE
+type v8Coverage struct {
+	Result []struct {
+		URL       string `json:"url"`
+		Functions []struct {
+			Ranges []v8Range `json:"ranges"`
+		} `json:"functions"`
+	} `json:"result"`
+}
+
+// v8Range counts the runs of code between two UTF-16 offsets of a script
+type v8Range struct {
+	StartOffset int `json:"startOffset"`
+	EndOffset   int `json:"endOffset"`
+	Count       int `json:"count"`
+}
+
+// AddV8 adds a V8 coverage file of one Node process. The script of file is
+// found by its URL, a file:// URL or the bare name for scripts evaluated
+// with vm. Ranges nest, so the narrowest range holding a character counts
+// its runs; a line takes the highest count of its characters.
+func (p *Profile) AddV8(data []byte, file string) error {
+	var cov v8Coverage
+	if err := json.Unmarshal(data, &cov); err != nil {
+		return err
+	}
+	// line of each UTF-16 unit of the source, 0 for blanks
+	var units []int
+	for i, text := range p.lines {
+		for _, r := range text + "\n" {
+			line := i + 1
+			if unicode.IsSpace(r) {
+				line = 0
+			}
+			for n := utf16.RuneLen(r); n > 0; n-- {
+				units = append(units, line)
+			}
+		}
+	}
+	for _, script := range cov.Result {
+		if script.URL != file && !strings.HasSuffix(script.URL, "/"+file) {
+			continue
+		}
+		var ranges []v8Range
+		for _, fn := range script.Functions {
+			ranges = append(ranges, fn.Ranges...)
+		}
+		sort.SliceStable(ranges, func(i, j int) bool {
+			return ranges[i].EndOffset-ranges[i].StartOffset > ranges[j].EndOffset-ranges[j].StartOffset
+		})
+		counts := make([]int, len(units))
+		covered := make([]bool, len(units))
+		for _, r := range ranges {
+			for i := max(r.StartOffset, 0); i < min(r.EndOffset, len(units)); i++ {
+				counts[i], covered[i] = r.Count, true
+			}
+		}
+		run := map[int]int{}
+		for i, line := range units {
+			if line > 0 && covered[i] && p.code(line) {
+				run[line] = max(run[line], counts[i])
+			}
+		}
+		p.merge(run)
+	}
+	return nil
+}
+
+// Report returns the hit counts added so far
+func (p *Profile) Report() Report {
+	r := Report{Lines: []Line{}}
+	for line, n := range p.hits {
+		r.Lines = append(r.Lines, Line{Line: line, Hits: n})
+		if n > 0 {
+			r.Covered++
+		}
+	}
+	sort.Slice(r.Lines, func(i, j int) bool { return r.Lines[i].Line < r.Lines[j].Line })
+	r.Total = len(r.Lines)
+	if r.Total > 0 {
+		r.Percent = math.Round(float64(r.Covered)*1000/float64(r.Total)) / 10
+	}
+	return r
+}
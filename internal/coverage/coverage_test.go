@@ -0,0 +1,81 @@
+package coverage
+
+import (
+	"reflect"
+	"testing"
+)
+
+const goSource = `package main
+
+func F(n int) string {
+	// positive numbers
+	if n > 0 {
+		return "pos"
+	}
+	return "neg"
+}
+`
+
+func TestAddGo(t *testing.T) {
+	p := New(goSource)
+	profile := `mode: count
+/tmp/run-1/solution.go:3.22,5.11 1 2
+/tmp/run-1/solution.go:5.11,7.3 1 2
+/tmp/run-1/solution.go:8.2,8.14 1 0
+/tmp/run-1/stq_driver.go:5.15,5.33 2 2
+`
+	if err := p.AddGo([]byte(profile), "solution.go"); err != nil {
+		t.Fatal(err)
+	}
+	// A second run adds up
+	if err := p.AddGo([]byte("mode: count\ncommand-line-arguments/solution.go:8.2,8.14 1 1\n"), "solution.go"); err != nil {
+		t.Fatal(err)
+	}
+	want := Report{Lines: []Line{{3, 2}, {5, 2}, {6, 2}, {7, 2}, {8, 1}}, Covered: 5, Total: 5, Percent: 100}
+	if got := p.Report(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, but got %+v", want, got)
+	}
+	if err := p.AddGo([]byte("solution.go:x 1"), "solution.go"); err == nil {
+		t.Error("Expected error for an invalid profile line, but got none")
+	}
+}
+
+const nodeSource = `function f(n) {
+  // comment
+  if (n > 0) {
+    return 'pos'
+  }
+  return 'neg' + 'é'
+}
+function never() {
+  return 1
+}
+`
+
+func TestAddV8(t *testing.T) {
+	p := New(nodeSource)
+	data := `{"result": [
+{"url": "solution.js", "functions": [
+  {"functionName": "", "ranges": [{"startOffset": 0, "endOffset": 120, "count": 1}]},
+  {"functionName": "f", "ranges": [{"startOffset": 0, "endOffset": 87, "count": 2}, {"startOffset": 64, "endOffset": 86, "count": 0}]},
+  {"functionName": "never", "ranges": [{"startOffset": 88, "endOffset": 119, "count": 0}]}]},
+{"url": "stq_driver.js", "functions": [{"functionName": "", "ranges": [{"startOffset": 0, "endOffset": 12, "count": 1}]}]}]}`
+	if err := p.AddV8([]byte(data), "solution.js"); err != nil {
+		t.Fatal(err)
+	}
+	want := Report{Lines: []Line{{1, 2}, {3, 2}, {4, 2}, {5, 2}, {6, 0}, {7, 2}, {8, 0}, {9, 0}, {10, 0}}, Covered: 5, Total: 9, Percent: 55.6}
+	if got := p.Report(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, but got %+v", want, got)
+	}
+
+	// Scripts loaded from disk are named by URL
+	file := New("module.exports = 1\n")
+	file.AddV8([]byte(`{"result": [{"url": "file:///tmp/run-1/main.js", "functions": [{"ranges": [{"startOffset": 0, "endOffset": 19, "count": 1}]}]},
+{"url": "file:///tmp/run-1/main.test.js", "functions": [{"ranges": [{"startOffset": 0, "endOffset": 19, "count": 7}]}]}]}`), "main.js")
+	if got := file.Report(); len(got.Lines) != 1 || got.Lines[0] != (Line{1, 1}) {
+		t.Errorf("Expected main.js alone, but got %+v", got)
+	}
+	if err := p.AddV8([]byte("{"), "solution.js"); err == nil {
+		t.Error("Expected error for invalid JSON, but got none")
+	}
+}
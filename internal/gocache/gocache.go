@@ -0,0 +1,264 @@
+package gocache
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StdPackages are the standard library packages user code may import, they
+// are compiled into the shared cache at startup
+var StdPackages = []string{
+	"bufio", "bytes", "container/heap", "container/list", "encoding/json",
+	"errors", "fmt", "math", "math/big", "regexp", "sort", "strconv",
+	"strings", "sync", "time", "unicode", "unicode/utf8",
+}
+
+const (
+	// LayerLimit is how many bytes runs may add to the writable layer before
+	// it is laid anew from the shared cache
+	LayerLimit = 1 << 30
+	// LayerMaxAge is how long a layer is used before it is laid anew
+	LayerMaxAge = 24 * time.Hour
+	// CheckInterval is how often the layer's size and age are checked
+	CheckInterval = 10 * time.Minute
+)
+
+const warmupProgram = "package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println(\"warmup\") }\n"
+
+// Cache is a prewarmed Go build cache shared by all Go executions. Once warm
+// the shared directory is made read-only and runs build against a writable
+// layer on top of it, a directory of hard links to the shared entries. The
+// go command locks and replaces cache entries atomically, so concurrent
+// builds share the layer safely. Every run adds its own packages to it, so
+// once the layer outgrows LayerLimit or LayerMaxAge a fresh one replaces
+// it; the one before stays until the next reset for the builds still using
+// it.
+type Cache struct {
+	dir      string
+	overlays string
+	// layer is the directory runs build against, laid at born; retired is
+	// the one it replaced. base is the size of the shared cache, which the
+	// layer starts out linking.
+	layer   atomic.Pointer[string]
+	born    time.Time
+	retired string
+	base    int64
+	ready   atomic.Bool
+	mu      sync.Mutex
+}
+
+// New creates a cache rooted at dir, the writable layers are created next
+// to it. It starts the check that resets an outgrown layer.
+func New(dir string) *Cache {
+	dir = filepath.Clean(dir)
+	c := &Cache{dir: dir, overlays: dir + "-overlays"}
+	go c.maintain()
+	return c
+}
+
+// Ready reports whether the shared cache has been warmed
+func (c *Cache) Ready() bool {
+	return c.ready.Load()
+}
+
+// Warm compiles the given packages and a trivial program into the shared
+// cache, makes it read-only and lays the writable layer over it
+func (c *Cache) Warm(packages []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ready.Store(false)
+
+	// A previous run may have left the cache read-only
+	if err := setWritable(c.dir, true); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	setWritable(c.overlays, true)
+	if err := os.RemoveAll(c.overlays); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.dir, os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.overlays, os.ModePerm); err != nil {
+		return err
+	}
+
+	env := []string{"GOCACHE=" + c.dir}
+	args := append([]string{"build", "-o", os.DevNull}, packages...)
+	if out, err := run(env, args...); err != nil {
+		return fmt.Errorf("prebuild standard library: %v: %s", err, out)
+	}
+
+	tmp, err := os.MkdirTemp(c.overlays, "warmup-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	file := filepath.Join(tmp, "main.go")
+	if err := os.WriteFile(file, []byte(warmupProgram), 0o644); err != nil {
+		return err
+	}
+	if out, err := run(env, "build", "-o", os.DevNull, file); err != nil {
+		return fmt.Errorf("warmup build: %v: %s", err, out)
+	}
+
+	if err := setWritable(c.dir, false); err != nil {
+		return err
+	}
+	if c.base, err = dirSize(c.dir); err != nil {
+		return err
+	}
+	c.retired = ""
+	if err := c.lay(); err != nil {
+		return err
+	}
+	c.ready.Store(true)
+	return nil
+}
+
+// lay replaces the writable layer with a fresh one, c.mu must be held
+func (c *Cache) lay() error {
+	layer, err := os.MkdirTemp(c.overlays, "layer-")
+	if err != nil {
+		return err
+	}
+	if err := c.overlay(layer); err != nil {
+		os.RemoveAll(layer)
+		return err
+	}
+	if c.retired != "" {
+		os.RemoveAll(c.retired)
+	}
+	if old := c.layer.Swap(&layer); old != nil {
+		c.retired = *old
+	}
+	c.born = time.Now()
+	return nil
+}
+
+// maintain checks the layer every CheckInterval
+func (c *Cache) maintain() {
+	for {
+		time.Sleep(CheckInterval)
+		if err := c.check(LayerLimit, LayerMaxAge); err != nil {
+			log.Printf("error resetting go build cache layer: %v\n", err)
+		}
+	}
+}
+
+// check lays a fresh layer once runs have added more than limit bytes to the
+// current one or it is older than maxAge
+func (c *Cache) check(limit int64, maxAge time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.Ready() {
+		return nil
+	}
+	if time.Since(c.born) < maxAge {
+		size, err := dirSize(*c.layer.Load())
+		if err != nil || size-c.base <= limit {
+			return err
+		}
+	}
+	return c.lay()
+}
+
+// Env returns the environment pointing the go command at the writable layer
+// of the warm cache
+func (c *Cache) Env() ([]string, error) {
+	if !c.Ready() {
+		return nil, fmt.Errorf("go build cache is not warm yet")
+	}
+	return []string{"GOCACHE=" + *c.layer.Load()}, nil
+}
+
+// overlay seeds dir with hard links to the entries of the shared cache
+func (c *Cache) overlay(dir string) error {
+	return filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(c.dir, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+		// Top-level bookkeeping files such as trim.txt are rewritten by the
+		// go command, so they are copied instead of linked
+		if !strings.ContainsRune(rel, filepath.Separator) {
+			return copyFile(path, target)
+		}
+		if err := os.Link(path, target); err != nil {
+			return copyFile(path, target)
+		}
+		return nil
+	})
+}
+
+// dirSize sums the sizes of the files under root
+func dirSize(root string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
+
+func run(env []string, args ...string) (string, error) {
+	cmd := exec.Command("go", args...)
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// setWritable toggles write permission on every entry under root
+func setWritable(root string, writable bool) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		mode := fs.FileMode(0o444)
+		if d.IsDir() {
+			mode = 0o555
+		}
+		if writable {
+			mode |= 0o200
+		}
+		return os.Chmod(path, mode)
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, in)
+	return err
+}
@@ -0,0 +1,88 @@
+package gocache
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCache_WarmAndLayer(t *testing.T) {
+	root := t.TempDir()
+	c := New(filepath.Join(root, "gocache"))
+	t.Cleanup(func() { setWritable(root, true) })
+
+	if _, err := c.Env(); err == nil {
+		t.Error("Expected error for the cache before warmup, but got none")
+	}
+
+	if err := c.Warm([]string{"strconv"}); err != nil {
+		t.Fatalf("Warm failed: %v", err)
+	}
+	if !c.Ready() {
+		t.Fatal("Expected cache to be ready after warmup")
+	}
+
+	info, err := os.Stat(c.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm()&0o200 != 0 {
+		t.Errorf("Expected shared cache to be read-only, got %v", info.Mode().Perm())
+	}
+
+	env, err := c.Env()
+	if err != nil {
+		t.Fatalf("Env failed: %v", err)
+	}
+	layer := strings.TrimPrefix(env[0], "GOCACHE=")
+
+	// Runs against the layer find the prebuilt packages and can write
+	file := filepath.Join(root, "main.go")
+	os.WriteFile(file, []byte("package main\n\nimport \"strconv\"\n\nfunc main() { println(strconv.Itoa(42)) }\n"), 0o644)
+	cmd := exec.Command("go", "run", file)
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil || strings.TrimSpace(string(out)) != "42" {
+		t.Errorf("Expected go run against the layer to print 42, got %q (%v)", out, err)
+	}
+
+	// The layer is shared by every run
+	if again, _ := c.Env(); again[0] != env[0] {
+		t.Errorf("Expected runs to share the layer, got %q and %q", env[0], again[0])
+	}
+	if _, err := os.Stat(layer); err != nil {
+		t.Errorf("Expected the layer to remain between runs, got %v", err)
+	}
+	// The shared cache is untouched by runs
+	if _, err := os.Stat(c.dir); err != nil {
+		t.Errorf("Expected shared cache to remain, got %v", err)
+	}
+
+	// A layer within its limits is kept, an outgrown one is laid anew while
+	// the old one stays for the builds still using it
+	if err := c.check(LayerLimit, time.Hour); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if again, _ := c.Env(); again[0] != env[0] {
+		t.Errorf("Expected the layer to be kept within its limits, got %q", again[0])
+	}
+	if err := c.check(0, time.Hour); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	fresh, _ := c.Env()
+	if fresh[0] == env[0] {
+		t.Error("Expected the outgrown layer to be replaced")
+	}
+	if _, err := os.Stat(layer); err != nil {
+		t.Errorf("Expected the replaced layer to remain until the next reset, got %v", err)
+	}
+	if err := c.check(LayerLimit, 0); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if _, err := os.Stat(layer); !os.IsNotExist(err) {
+		t.Errorf("Expected the retired layer to be removed at the next reset, got %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+// Package hint records which hints of a problem each client has revealed.
+// Hints are revealed strictly in order, so a client's progress on a problem
+// is the number of reveals; each reveal is kept with its time and the
+// contest it was charged to in an append-only log of JSON lines.
+package hint
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LogFile is the name of the store's log in its directory
+const LogFile = "hints.jsonl"
+
+// ErrNoMoreHints is returned when every hint of a problem is revealed
+var ErrNoMoreHints = errors.New("every hint is already revealed")
+
+// Reveal is a hint shown to a client
+type Reveal struct {
+	Owner     string `json:"owner"`
+	ProblemID string `json:"problemId"`
+	// Index is the position of the hint, starting at 0
+	Index int `json:"index"`
+	// ContestID is set when the reveal counts against a contest score
+	ContestID string    `json:"contestId,omitempty"`
+	Created   time.Time `json:"created"`
+}
+
+// Store keeps the reveals of every client in memory, backed by the log
+type Store struct {
+	mu   sync.RWMutex
+	file *os.File
+	// byOwner indexes the reveals by client, then problem, in order
+	byOwner   map[string]map[string][]Reveal
+	byContest map[string][]Reveal
+}
+
+// Open opens the store in dir, creating the directory and log if needed.
+// A log cut short by a crash loses only its last line.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, LogFile), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{file: f, byOwner: make(map[string]map[string][]Reveal), byContest: make(map[string][]Reveal)}
+	reader := bufio.NewReader(f)
+	var offset int64
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			f.Close()
+			return nil, err
+		}
+		if len(bytes.TrimSpace(data)) > 0 {
+			var r Reveal
+			if jsonErr := json.Unmarshal(data, &r); jsonErr != nil {
+				if err != io.EOF {
+					f.Close()
+					return nil, fmt.Errorf("%s line %d: %v", LogFile, line, jsonErr)
+				}
+				// A write cut short, the next one would continue its line
+				if err := f.Truncate(offset); err != nil {
+					f.Close()
+					return nil, err
+				}
+				break
+			}
+			s.add(r)
+		}
+		offset += int64(len(data))
+		if err == io.EOF {
+			if len(data) > 0 && data[len(data)-1] != '\n' {
+				if _, err := f.Write([]byte("\n")); err != nil {
+					f.Close()
+					return nil, err
+				}
+			}
+			break
+		}
+	}
+	return s, nil
+}
+
+func (s *Store) add(r Reveal) {
+	problems := s.byOwner[r.Owner]
+	if problems == nil {
+		problems = make(map[string][]Reveal)
+		s.byOwner[r.Owner] = problems
+	}
+	problems[r.ProblemID] = append(problems[r.ProblemID], r)
+	if r.ContestID != "" {
+		s.byContest[r.ContestID] = append(s.byContest[r.ContestID], r)
+	}
+}
+
+// Next reveals a client's next hint of a problem with total hints,
+// charging it to contestID when set
+func (s *Store) Next(owner, problemID, contestID string, total int) (Reveal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	index := len(s.byOwner[owner][problemID])
+	if index >= total {
+		return Reveal{}, ErrNoMoreHints
+	}
+	r := Reveal{Owner: owner, ProblemID: problemID, Index: index, ContestID: contestID, Created: time.Now().UTC()}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return Reveal{}, err
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return Reveal{}, err
+	}
+	s.add(r)
+	return r, nil
+}
+
+// Revealed returns a client's reveals of a problem, in order
+func (s *Store) Revealed(owner, problemID string) []Reveal {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Reveal{}, s.byOwner[owner][problemID]...)
+}
+
+// Contest returns the reveals charged to a contest
+func (s *Store) Contest(contestID string) []Reveal {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Reveal(nil), s.byContest[contestID]...)
+}
+
+// Close closes the log
+func (s *Store) Close() error {
+	return s.file.Close()
+}
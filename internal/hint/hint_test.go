@@ -0,0 +1,50 @@
+package hint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		r, err := s.Next("10.0.0.1", "sum", "", 2)
+		if err != nil || r.Index != i {
+			t.Fatalf("Expected hint %d, but got %+v, %v", i, r, err)
+		}
+	}
+	if _, err := s.Next("10.0.0.1", "sum", "", 2); err != ErrNoMoreHints {
+		t.Errorf("Expected %v, but got %v", ErrNoMoreHints, err)
+	}
+	if _, err := s.Next("10.0.0.2", "sum", "weekly", 2); err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	s.Close()
+
+	// A write cut short by a crash is dropped on open
+	f, _ := os.OpenFile(filepath.Join(dir, LogFile), os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString(`{"owner":"10.0.0.3","prob`)
+	f.Close()
+	s, err = Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer s.Close()
+	if got := s.Revealed("10.0.0.1", "sum"); len(got) != 2 || got[1].Index != 1 {
+		t.Errorf("Expected 2 reveals to be reloaded, but got %+v", got)
+	}
+	if got := s.Contest("weekly"); len(got) != 1 || got[0].Owner != "10.0.0.2" {
+		t.Errorf("Expected the contest reveal, but got %+v", got)
+	}
+	if got := s.Revealed("10.0.0.3", "sum"); len(got) != 0 {
+		t.Errorf("Expected the partial line to be dropped, but got %+v", got)
+	}
+	if _, err := s.Next("10.0.0.3", "sum", "", 2); err != nil {
+		t.Errorf("Expected the log to accept new reveals, but got %v", err)
+	}
+}
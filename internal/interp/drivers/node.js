@@ -0,0 +1,41 @@
+// Session driver for node: reads one JSON request per line from stdin,
+// evaluates it in a persistent vm context and answers with one JSON line.
+const vm = require("vm");
+const util = require("util");
+const readline = require("readline");
+
+let out = [];
+let errout = [];
+
+const sandboxConsole = {
+  log: (...args) => out.push(util.format(...args) + "\n"),
+  info: (...args) => out.push(util.format(...args) + "\n"),
+  debug: (...args) => out.push(util.format(...args) + "\n"),
+  error: (...args) => errout.push(util.format(...args) + "\n"),
+  warn: (...args) => errout.push(util.format(...args) + "\n"),
+};
+
+const context = vm.createContext({ console: sandboxConsole });
+
+const rl = readline.createInterface({ input: process.stdin });
+rl.on("line", (line) => {
+  const req = JSON.parse(line);
+  out = [];
+  errout = [];
+  let value = "";
+  let error = "";
+  try {
+    const result = vm.runInContext(req.code, context, {
+      filename: "cell",
+      timeout: req.timeoutMs > 0 ? req.timeoutMs : undefined,
+    });
+    if (result !== undefined) {
+      value = util.inspect(result);
+    }
+  } catch (e) {
+    error = e && e.stack ? String(e.stack) : String(e);
+  }
+  process.stdout.write(
+    JSON.stringify({ out: out.join(""), errout: errout.join(""), value, error }) + "\n",
+  );
+});
@@ -0,0 +1,36 @@
+// Pool worker driver for node, passed to `node -e`: waits for the path of a
+// program file on stdin, then runs it as `node file` would, as the main
+// module with the process' own globals and require. The block keeps the
+// driver's names out of the global scope, and what `-e` adds to it is
+// removed first.
+{
+  const fs = require("fs");
+  const Module = require("module");
+  for (const name of ["require", "module", "exports", "__filename", "__dirname"]) {
+    delete globalThis[name];
+  }
+  process.execArgv.splice(0);
+
+  const bytes = [];
+  const one = Buffer.alloc(1);
+  for (;;) {
+    let n;
+    try {
+      n = fs.readSync(0, one, 0, 1, null);
+    } catch (e) {
+      if (e.code === "EAGAIN") {
+        continue;
+      }
+      throw e;
+    }
+    if (n === 0 || one[0] === 10) {
+      break;
+    }
+    bytes.push(one[0]);
+  }
+  const file = Buffer.from(bytes).toString();
+  if (file !== "") {
+    process.argv[1] = file;
+    Module.runMain();
+  }
+}
@@ -0,0 +1,24 @@
+// Session driver for php, passed to `php -r`: reads one JSON request per line
+// from stdin, evaluates it in the global scope so variables, functions and
+// classes survive between cells, and answers with one JSON line.
+while (($__line = fgets(STDIN)) !== false) {
+    $__req = json_decode($__line, true);
+    $__error = '';
+    if ($__req['timeoutMs'] > 0) {
+        set_time_limit((int) ceil($__req['timeoutMs'] / 1000));
+    }
+    ob_start();
+    try {
+        eval(preg_replace('/^\s*<\?php/', '', $__req['code']));
+    } catch (Throwable $__e) {
+        $__error = get_class($__e) . ': ' . $__e->getMessage() . ' on line ' . $__e->getLine();
+    }
+    $__out = ob_get_clean();
+    fwrite(STDOUT, json_encode([
+        'out' => $__out,
+        'errout' => '',
+        'value' => '',
+        'error' => $__error,
+    ], JSON_INVALID_UTF8_SUBSTITUTE) . "\n");
+    fflush(STDOUT);
+}
@@ -0,0 +1,11 @@
+// Pool worker driver for php, passed to `php -r`: waits for the path of a
+// program file on stdin, then runs it as `php file` would. The path is kept
+// in $argv only, so no variable of the driver is left in the global scope.
+$argv = [rtrim((string) fgets(STDIN), "\n")];
+$argc = 1;
+$_SERVER['argv'] = $argv;
+$_SERVER['argc'] = $argc;
+$_SERVER['SCRIPT_NAME'] = $_SERVER['SCRIPT_FILENAME'] = $_SERVER['PHP_SELF'] = $argv[0];
+if ($argv[0] !== '') {
+    require $argv[0];
+}
@@ -0,0 +1,200 @@
+package interp
+
+import (
+	"bytes"
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/gocache"
+)
+
+// Go has no interactive interpreter in the standard toolchain, so a Go session
+// replays every accepted cell as one program and reports only the output that
+// the newest cell added. Cells that fail to compile or exit with an error are
+// discarded, leaving the session state as it was.
+//
+// Replaying means every Eval runs the statements of all earlier cells again:
+// their side effects, such as writing files or reading the clock, repeat,
+// and output that differs between runs shows up again as new output.
+
+var (
+	unusedVarPattern    = regexp.MustCompile(`declared and not used: (\w+)`)
+	unusedImportPattern = regexp.MustCompile(`"([^"]+)" imported and not used`)
+)
+
+// maxFixups bounds how many unused variable/import errors are patched per cell
+const maxFixups = 16
+
+// GoBuildCache, when set and warm, provides the build cache for Go sessions
+var GoBuildCache *gocache.Cache
+
+type goReplay struct {
+	mu      sync.Mutex
+	dir     string
+	imports []string
+	decls   []string
+	stmts   []string
+	stdout  string
+	stderr  string
+	closed  bool
+}
+
+func newGoReplay() (*goReplay, error) {
+	dir, err := os.MkdirTemp("", "goreplay-")
+	if err != nil {
+		return nil, err
+	}
+	return &goReplay{dir: dir}, nil
+}
+
+// Eval adds the cell to the program and runs it again. The timeout covers
+// the whole evaluation, the rebuilds that patch unused names included.
+func (g *goReplay) Eval(code string, timeout time.Duration) (Result, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return Result{}, ErrClosed
+	}
+
+	imports, decls, stmts := g.imports, g.decls, g.stmts
+	if f, err := parser.ParseFile(token.NewFileSet(), "", "package main\n"+code, 0); err == nil {
+		imports, decls = splitDecls(f, "package main\n"+code, imports, decls)
+	} else if _, err := parser.ParseFile(token.NewFileSet(), "", "package main\nfunc main() {\n"+code+"\n}", 0); err == nil {
+		stmts = append(append([]string{}, stmts...), code)
+	} else {
+		return Result{Error: err.Error()}, nil
+	}
+
+	stdout, stderr, err := g.run(imports, decls, stmts, timeout)
+	if err == context.DeadlineExceeded {
+		return Result{}, ErrTimeout
+	}
+	if err != nil {
+		return Result{Stdout: newSuffix(g.stdout, stdout), Stderr: stderr, Error: err.Error()}, nil
+	}
+
+	res := Result{Stdout: newSuffix(g.stdout, stdout), Stderr: newSuffix(g.stderr, stderr)}
+	g.imports, g.decls, g.stmts = imports, decls, stmts
+	g.stdout, g.stderr = stdout, stderr
+	return res, nil
+}
+
+// Close removes the session's scratch directory
+func (g *goReplay) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.closed = true
+	return os.RemoveAll(g.dir)
+}
+
+// run builds and runs the program, silencing unused variables and imports that
+// are a normal part of working incrementally. Every attempt shares one
+// deadline.
+func (g *goReplay) run(imports, decls, stmts []string, timeout time.Duration) (string, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var blanks []string
+	skipped := map[string]bool{}
+	for i := 0; ; i++ {
+		src := buildProgram(imports, skipped, decls, stmts, blanks)
+		file := filepath.Join(g.dir, "main.go")
+		if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+			return "", "", err
+		}
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "go", "run", file)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if GoBuildCache != nil && GoBuildCache.Ready() {
+			if env, err := GoBuildCache.Env(); err == nil {
+				cmd.Env = append(os.Environ(), env...)
+			}
+		}
+		err := cmd.Run()
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", "", context.DeadlineExceeded
+		}
+		if err == nil {
+			return stdout.String(), stderr.String(), nil
+		}
+
+		fixed := false
+		if i < maxFixups {
+			for _, m := range unusedVarPattern.FindAllStringSubmatch(stderr.String(), -1) {
+				blanks = append(blanks, "_ = "+m[1])
+				fixed = true
+			}
+			for _, m := range unusedImportPattern.FindAllStringSubmatch(stderr.String(), -1) {
+				skipped[m[1]] = true
+				fixed = true
+			}
+		}
+		if !fixed {
+			return stdout.String(), stderr.String(), err
+		}
+	}
+}
+
+func buildProgram(imports []string, skipped map[string]bool, decls, stmts, blanks []string) string {
+	var b strings.Builder
+	b.WriteString("package main\n\n")
+	for _, imp := range imports {
+		if !skipped[imp] {
+			b.WriteString("import " + strconv.Quote(imp) + "\n")
+		}
+	}
+	for _, d := range decls {
+		b.WriteString("\n" + d + "\n")
+	}
+	b.WriteString("\nfunc main() {\n")
+	for _, s := range stmts {
+		b.WriteString(s + "\n")
+	}
+	for _, s := range blanks {
+		b.WriteString(s + "\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// splitDecls separates import paths from the remaining top-level declarations
+func splitDecls(f *ast.File, src string, imports, decls []string) ([]string, []string) {
+	imports = append([]string{}, imports...)
+	decls = append([]string{}, decls...)
+	seen := map[string]bool{}
+	for _, imp := range imports {
+		seen[imp] = true
+	}
+	for _, spec := range f.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err == nil && !seen[path] {
+			imports = append(imports, path)
+			seen[path] = true
+		}
+	}
+	for _, d := range f.Decls {
+		if gd, ok := d.(*ast.GenDecl); ok && gd.Tok == token.IMPORT {
+			continue
+		}
+		decls = append(decls, src[d.Pos()-1:d.End()-1])
+	}
+	return imports, decls
+}
+
+func newSuffix(previous, current string) string {
+	if strings.HasPrefix(current, previous) {
+		return current[len(previous):]
+	}
+	return current
+}
@@ -0,0 +1,49 @@
+package interp
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"time"
+)
+
+//go:embed drivers/node.js
+var nodeDriver string
+
+//go:embed drivers/php.php
+var phpDriver string
+
+var (
+	// ErrTimeout is returned when a snippet runs longer than its time limit.
+	// The interpreter is killed and must not be used afterwards.
+	ErrTimeout = errors.New("evaluation timed out")
+	// ErrClosed is returned when evaluating on an interpreter that has exited
+	ErrClosed = errors.New("interpreter is closed")
+)
+
+// Result holds the output of a single evaluated snippet
+type Result struct {
+	Stdout string `json:"out"`
+	Stderr string `json:"errout"`
+	Value  string `json:"value"`
+	Error  string `json:"error"`
+}
+
+// Interpreter keeps program state between evaluated snippets
+type Interpreter interface {
+	Eval(code string, timeout time.Duration) (Result, error)
+	Close() error
+}
+
+// Start launches a stateful interpreter for the given language
+func Start(language string) (Interpreter, error) {
+	switch language {
+	case "node":
+		return startProcess("node", "-e", nodeDriver)
+	case "php":
+		return startProcess("php", "-r", phpDriver)
+	case "go":
+		return newGoReplay()
+	}
+	return nil, fmt.Errorf("unsupported language: %s", language)
+}
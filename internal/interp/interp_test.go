@@ -0,0 +1,180 @@
+package interp
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartUnsupportedLanguage(t *testing.T) {
+	if _, err := Start("python"); err == nil {
+		t.Error("Expected error for unsupported language, but got none")
+	}
+}
+
+func TestNodeInterpreter(t *testing.T) {
+	in, err := Start("node")
+	if err != nil {
+		t.Fatalf("Failed to start node interpreter: %v", err)
+	}
+	defer in.Close()
+
+	// State should survive between snippets
+	if _, err := in.Eval("let counter = 41; function inc() { return ++counter; }", 5*time.Second); err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+	res, err := in.Eval("console.log(inc())", 5*time.Second)
+	if err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+	if res.Stdout != "42\n" {
+		t.Errorf("Expected stdout '42\\n', but got: %q", res.Stdout)
+	}
+
+	// Errors are reported per cell and do not end the session
+	res, err = in.Eval("throw new Error('boom')", 5*time.Second)
+	if err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+	if !strings.Contains(res.Error, "boom") {
+		t.Errorf("Expected error to contain 'boom', but got: %q", res.Error)
+	}
+	res, err = in.Eval("counter", 5*time.Second)
+	if err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+	if res.Value != "42" {
+		t.Errorf("Expected value '42', but got: %q", res.Value)
+	}
+}
+
+func TestNodeInterpreterTimeout(t *testing.T) {
+	in, err := Start("node")
+	if err != nil {
+		t.Fatalf("Failed to start node interpreter: %v", err)
+	}
+	defer in.Close()
+
+	res, err := in.Eval("while (true) {}", 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+	if !strings.Contains(res.Error, "timed out") {
+		t.Errorf("Expected timeout error, but got: %q", res.Error)
+	}
+}
+
+func TestPHPInterpreter(t *testing.T) {
+	if _, err := exec.LookPath("php"); err != nil {
+		t.Skip("php is not installed")
+	}
+	in, err := Start("php")
+	if err != nil {
+		t.Fatalf("Failed to start php interpreter: %v", err)
+	}
+	defer in.Close()
+
+	if _, err := in.Eval("<?php $name = 'php';", 5*time.Second); err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+	res, err := in.Eval("echo 'hello ' . $name;", 5*time.Second)
+	if err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+	if res.Stdout != "hello php" {
+		t.Errorf("Expected stdout 'hello php', but got: %q", res.Stdout)
+	}
+}
+
+func TestGoInterpreter(t *testing.T) {
+	in, err := Start("go")
+	if err != nil {
+		t.Fatalf("Failed to start go interpreter: %v", err)
+	}
+	defer in.Close()
+
+	steps := []struct {
+		code     string
+		expected string
+	}{
+		{`import "fmt"`, ""},
+		{`func double(n int) int { return n * 2 }`, ""},
+		{`x := double(21)`, ""},
+		{`fmt.Println(x)`, "42\n"},
+		{`fmt.Println("again")`, "again\n"},
+	}
+	for _, step := range steps {
+		res, err := in.Eval(step.code, 60*time.Second)
+		if err != nil {
+			t.Fatalf("Eval(%q) failed: %v", step.code, err)
+		}
+		if res.Error != "" {
+			t.Fatalf("Eval(%q) returned error: %s %s", step.code, res.Error, res.Stderr)
+		}
+		if res.Stdout != step.expected {
+			t.Errorf("Eval(%q): expected stdout %q, but got %q", step.code, step.expected, res.Stdout)
+		}
+	}
+
+	// A cell that does not compile is discarded
+	res, err := in.Eval(`fmt.Println(undefinedName)`, 60*time.Second)
+	if err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+	if res.Error == "" || !strings.Contains(res.Stderr, "undefined") {
+		t.Errorf("Expected compile error, but got: %+v", res)
+	}
+	res, err = in.Eval(`fmt.Println(x + 1)`, 60*time.Second)
+	if err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+	if res.Stdout != "43\n" {
+		t.Errorf("Expected stdout '43\\n' after discarded cell, but got: %q", res.Stdout)
+	}
+}
+
+func TestNodeWorker(t *testing.T) {
+	in, err := StartWorker("node")
+	if err != nil {
+		t.Fatalf("Failed to start node worker: %v", err)
+	}
+	defer in.Close()
+
+	file := filepath.Join(t.TempDir(), "main.js")
+	code := "setTimeout(() => console.log('later'), 20)\nconsole.log('now', require('path').basename(__filename))\nnull.x"
+	if err := os.WriteFile(file, []byte(code), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res, err := in.Run(file, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if res.Stdout != "now main.js\n" {
+		t.Errorf("Expected the file to run as the main module, but got: %q", res.Stdout)
+	}
+	if !strings.Contains(res.Stderr, "TypeError") || strings.Contains(res.Stderr, "[eval]") {
+		t.Errorf("Expected the error without the driver's frames, but got: %q", res.Stderr)
+	}
+
+	// A worker runs a single program
+	if _, err := in.Run(file, 5*time.Second); err != ErrClosed {
+		t.Errorf("Expected a second run to fail with ErrClosed, but got: %v", err)
+	}
+}
+
+func TestStripDriver(t *testing.T) {
+	node := "Error: boom\n    at f (main.js:2:9)\n    at Function.executeUserEntryPoint [as runMain] (node:internal/modules/run_main:164:12)\n    at [eval]:33:12\n    at runScriptInThisContext (node:internal/vm:209:10)\n\nNode.js v20\n"
+	want := "Error: boom\n    at f (main.js:2:9)\n    at Function.executeUserEntryPoint [as runMain] (node:internal/modules/run_main:164:12)\n\nNode.js v20\n"
+	if got := stripNodeDriver(node); got != want {
+		t.Errorf("Expected %q, but got %q", want, got)
+	}
+
+	php := "Stack trace:\n#0 /tmp/main.php(5): f()\n#1 Command line code(11): require('/tmp/main.php')\n#2 {main}\n  thrown in /tmp/main.php on line 3\n"
+	want = "Stack trace:\n#0 /tmp/main.php(5): f()\n#1 {main}\n  thrown in /tmp/main.php on line 3\n"
+	if got := stripPHPDriver(php); got != want {
+		t.Errorf("Expected %q, but got %q", want, got)
+	}
+}
@@ -0,0 +1,117 @@
+package interp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// request is one line sent to a driver on stdin
+type request struct {
+	Code      string `json:"code"`
+	TimeoutMs int64  `json:"timeoutMs"`
+}
+
+// process is a long-lived node or php driver speaking JSON lines over pipes
+type process struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	stderr bytes.Buffer
+	mu     sync.Mutex
+	closed bool
+}
+
+func startProcess(name string, args ...string) (*process, error) {
+	p := &process{cmd: exec.Command(name, args...)}
+	stdin, err := p.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := p.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	p.cmd.Stderr = &p.stderr
+	if err := p.cmd.Start(); err != nil {
+		return nil, err
+	}
+	p.stdin = stdin
+	p.stdout = bufio.NewReader(stdout)
+	return p, nil
+}
+
+// Eval sends code to the driver and waits for its answer. The driver enforces
+// the timeout itself where it can; the process is killed if it does not answer
+// shortly after.
+func (p *process) Eval(code string, timeout time.Duration) (Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return Result{}, ErrClosed
+	}
+
+	line, err := json.Marshal(request{Code: code, TimeoutMs: timeout.Milliseconds()})
+	if err != nil {
+		return Result{}, err
+	}
+	if _, err := p.stdin.Write(append(line, '\n')); err != nil {
+		p.kill()
+		return Result{}, ErrClosed
+	}
+
+	type answer struct {
+		line []byte
+		err  error
+	}
+	done := make(chan answer, 1)
+	go func() {
+		line, err := p.stdout.ReadBytes('\n')
+		done <- answer{line, err}
+	}()
+
+	select {
+	case a := <-done:
+		if a.err != nil {
+			// The driver exited, e.g. on a PHP fatal error, keep what it printed
+			p.kill()
+			return Result{Stdout: string(a.line), Stderr: p.stderr.String()}, ErrClosed
+		}
+		var res Result
+		if err := json.Unmarshal(a.line, &res); err != nil {
+			// Something other than the driver wrote to stdout, the protocol
+			// is out of sync and the process cannot be trusted any more
+			p.kill()
+			return Result{Stdout: string(a.line), Stderr: p.stderr.String()}, ErrClosed
+		}
+		return res, nil
+	case <-time.After(timeout + time.Second):
+		p.kill()
+		return Result{}, ErrTimeout
+	}
+}
+
+// Close stops the driver process
+func (p *process) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	p.stdin.Close()
+	return p.cmd.Wait()
+}
+
+func (p *process) kill() {
+	if p.closed {
+		return
+	}
+	p.closed = true
+	p.cmd.Process.Kill()
+	p.cmd.Wait()
+}
@@ -0,0 +1,149 @@
+package interp
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed drivers/node_worker.js
+var nodeWorkerDriver string
+
+//go:embed drivers/php_worker.php
+var phpWorkerDriver string
+
+// Worker is an interpreter started ahead of time that runs exactly one
+// program file, the same way `node file` or `php file` would, as soon as it
+// is given the file's path
+type Worker interface {
+	Run(file string, timeout time.Duration) (Result, error)
+	Close() error
+}
+
+// StartWorker launches a worker for the given language. The driver waiting
+// for the path is the only difference to running the file directly, its
+// frames are removed from the stack traces the program prints, on stdout as
+// well since PHP shows its errors there and programs may print caught ones.
+func StartWorker(language string) (Worker, error) {
+	switch language {
+	case "node":
+		return startFileWorker(stripNodeDriver, "node", "-e", nodeWorkerDriver)
+	case "php":
+		return startFileWorker(stripPHPDriver, "php", "-r", phpWorkerDriver)
+	}
+	return nil, fmt.Errorf("unsupported worker language: %s", language)
+}
+
+// fileWorker is a node or php process blocked on reading a path from stdin
+type fileWorker struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout bytes.Buffer
+	stderr bytes.Buffer
+	strip  func(string) string
+	exited chan struct{}
+	mu     sync.Mutex
+	used   bool
+}
+
+func startFileWorker(strip func(string) string, name string, args ...string) (*fileWorker, error) {
+	w := &fileWorker{cmd: exec.Command(name, args...), strip: strip, exited: make(chan struct{})}
+	stdin, err := w.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	w.cmd.Stdout = &w.stdout
+	w.cmd.Stderr = &w.stderr
+	// Background processes of the program must not hold the run open
+	w.cmd.WaitDelay = time.Second
+	if err := w.cmd.Start(); err != nil {
+		return nil, err
+	}
+	w.stdin = stdin
+	go func() {
+		w.cmd.Wait()
+		close(w.exited)
+	}()
+	return w, nil
+}
+
+// Run runs the program file and waits for the process to exit. Standard
+// input is closed once the path is sent, as it is for a program run without
+// input. A worker runs a single program, later calls return ErrClosed.
+func (w *fileWorker) Run(file string, timeout time.Duration) (Result, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.used {
+		return Result{}, ErrClosed
+	}
+	w.used = true
+
+	_, err := io.WriteString(w.stdin, file+"\n")
+	w.stdin.Close()
+	if err != nil {
+		w.kill()
+		return Result{}, ErrClosed
+	}
+	select {
+	case <-w.exited:
+		return Result{Stdout: w.strip(w.stdout.String()), Stderr: w.strip(w.stderr.String())}, nil
+	case <-time.After(timeout):
+		w.kill()
+		return Result{}, ErrTimeout
+	}
+}
+
+// Close stops the worker if it has not run a program yet
+func (w *fileWorker) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.used = true
+	w.kill()
+	return nil
+}
+
+func (w *fileWorker) kill() {
+	select {
+	case <-w.exited:
+	default:
+		w.cmd.Process.Kill()
+		<-w.exited
+	}
+}
+
+// stripNodeDriver removes the frames of the `node -e` driver, which come
+// last in a stack trace: from the first [eval] frame to the end of the trace
+func stripNodeDriver(output string) string {
+	lines := strings.SplitAfter(output, "\n")
+	kept := lines[:0]
+	driver := false
+	for _, line := range lines {
+		frame := strings.HasPrefix(strings.TrimLeft(line, " "), "at ")
+		if frame && strings.Contains(line, "[eval]") {
+			driver = true
+		}
+		if !frame {
+			driver = false
+		}
+		if !driver {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "")
+}
+
+// phpDriverFrame matches the `php -r` driver's require, the last frame of a
+// stack trace before {main}
+var phpDriverFrame = regexp.MustCompile(`#(\d+) Command line code\(\d+\): require\([^\n]*\)\n#\d+ \{main\}`)
+
+// stripPHPDriver removes the frame of the `php -r` driver, {main} takes its
+// number
+func stripPHPDriver(output string) string {
+	return phpDriverFrame.ReplaceAllString(output, "#${1} {main}")
+}
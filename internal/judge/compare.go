@@ -0,0 +1,220 @@
+package judge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+	"github.com/afman42/go-web-code-interactive/internal/protocol"
+)
+
+// checkerFiles names the checker program file per language
+var checkerFiles = map[string]string{
+	"node": "checker.js",
+	"php":  "checker.php",
+	"go":   "checker.go",
+}
+
+// verdict is the value of a checker's verdict record
+type verdict struct {
+	OK      bool   `json:"ok"`
+	Message string `json:"message"`
+}
+
+// compare decides whether the raw actual value matches the test. A checker
+// comparator runs the author's program, which may explain its decision in
+// the returned message.
+func compare(c problem.Comparator, tc problem.TestCase, actual json.RawMessage, checkers *checkers) (bool, string, error) {
+	switch c.Type {
+	case problem.CompareExact:
+		var e, a bytes.Buffer
+		if json.Compact(&e, tc.Expected) != nil || json.Compact(&a, actual) != nil {
+			return false, "", nil
+		}
+		return bytes.Equal(e.Bytes(), a.Bytes()), "", nil
+	case problem.CompareChecker:
+		return checkers.check(*c.Checker, tc, actual)
+	}
+
+	var expected, got any
+	json.Unmarshal(tc.Expected, &expected)
+	json.Unmarshal(actual, &got)
+	switch c.Type {
+	case problem.CompareUnordered, problem.CompareSet:
+		e, eok := expected.([]any)
+		a, aok := got.([]any)
+		if !eok || !aok {
+			return false, "", nil
+		}
+		if c.Type == problem.CompareSet {
+			e, a = dedupe(c, e), dedupe(c, a)
+		}
+		return sameElements(c, e, a), "", nil
+	}
+	return equal(c, expected, got), "", nil
+}
+
+// equal compares decoded JSON values, strings and numbers according to the
+// comparator and lists in order
+func equal(c problem.Comparator, expected, actual any) bool {
+	switch e := expected.(type) {
+	case string:
+		a, ok := actual.(string)
+		if !ok {
+			return false
+		}
+		if c.Type == problem.CompareWhitespace {
+			return slices.Equal(strings.Fields(e), strings.Fields(a))
+		}
+		return e == a
+	case float64:
+		a, ok := actual.(float64)
+		if !ok {
+			return false
+		}
+		if c.Type == problem.CompareFloat {
+			return within(e, a, c.AbsEps, c.RelEps)
+		}
+		return e == a
+	case []any:
+		a, ok := actual.([]any)
+		if !ok || len(a) != len(e) {
+			return false
+		}
+		for i := range e {
+			if !equal(c, e[i], a[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		a, ok := actual.(map[string]any)
+		if !ok || len(a) != len(e) {
+			return false
+		}
+		for k, v := range e {
+			av, ok := a[k]
+			if !ok || !equal(c, v, av) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(expected, actual)
+}
+
+// within reports whether two numbers differ by at most the absolute or the
+// relative epsilon
+func within(expected, actual, absEps, relEps float64) bool {
+	if absEps == 0 && relEps == 0 {
+		absEps = problem.DefaultFloatEpsilon
+	}
+	diff := math.Abs(expected - actual)
+	return diff <= absEps || diff <= relEps*math.Max(math.Abs(expected), math.Abs(actual))
+}
+
+// sameElements compares two lists as multisets
+func sameElements(c problem.Comparator, expected, actual []any) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	used := make([]bool, len(actual))
+	for _, e := range expected {
+		found := false
+		for i, a := range actual {
+			if !used[i] && equal(c, e, a) {
+				used[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func dedupe(c problem.Comparator, list []any) []any {
+	unique := []any{}
+	for _, v := range list {
+		if !slices.ContainsFunc(unique, func(u any) bool { return equal(c, u, v) }) {
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
+
+// checkers prepares each checker program of a problem once per submission
+type checkers struct {
+	prepare  Preparer
+	timeout  time.Duration
+	runs     map[problem.Checker]Runner
+	releases []func()
+}
+
+func newCheckers(prepare Preparer, timeout time.Duration) *checkers {
+	return &checkers{prepare: prepare, timeout: timeout, runs: make(map[problem.Checker]Runner)}
+}
+
+// get returns the runner of a checker, preparing it on first use
+func (c *checkers) get(checker problem.Checker) (Runner, error) {
+	if run, ok := c.runs[checker]; ok {
+		return run, nil
+	}
+	name := checkerFiles[checker.Language]
+	run, release, err := c.prepare(checker.Language, Program{Files: map[string]string{name: checker.Source}, Entry: name})
+	if err != nil {
+		return nil, fmt.Errorf("checker: %v", err)
+	}
+	c.runs[checker] = run
+	c.releases = append(c.releases, release)
+	return run, nil
+}
+
+// close removes every prepared checker
+func (c *checkers) close() {
+	for _, release := range c.releases {
+		release()
+	}
+}
+
+// check runs the author's checker program on the test. A checker that
+// fails to report a verdict is an error of the problem, not of the user.
+func (c *checkers) check(checker problem.Checker, tc problem.TestCase, actual json.RawMessage) (bool, string, error) {
+	input, err := json.Marshal(struct {
+		Args     []json.RawMessage `json:"args"`
+		Expected json.RawMessage   `json:"expected"`
+		Actual   json.RawMessage   `json:"actual"`
+	}{tc.Args, tc.Expected, actual})
+	if err != nil {
+		return false, "", err
+	}
+	run, err := c.get(checker)
+	if err != nil {
+		return false, "", err
+	}
+	out, err := run(nil, string(input), c.timeout)
+	if err != nil {
+		return false, "", err
+	}
+	records, err := protocol.Parse(out.Results)
+	if err != nil {
+		return false, "", fmt.Errorf("checker: %v", err)
+	}
+	record, ok := protocol.Find(records, protocol.TypeVerdict, 0)
+	if !ok {
+		return false, "", fmt.Errorf("checker reported no verdict: %s", strings.TrimSpace(out.Stderr))
+	}
+	var v verdict
+	if err := json.Unmarshal(record.Value, &v); err != nil {
+		return false, "", fmt.Errorf("checker verdict: %v", err)
+	}
+	return v.OK, v.Message, nil
+}
@@ -0,0 +1,86 @@
+package judge
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+)
+
+func TestCompare(t *testing.T) {
+	testCases := []struct {
+		name     string
+		c        problem.Comparator
+		expected string
+		actual   string
+		ok       bool
+	}{
+		{"json equal", problem.Comparator{Type: problem.CompareJSON}, `{"a": [1, 2]}`, `{"a":[1,2.0]}`, true},
+		{"json different", problem.Comparator{Type: problem.CompareJSON}, `[1, 2]`, `[2, 1]`, false},
+		{"exact equal", problem.Comparator{Type: problem.CompareExact}, `[1, 2]`, `[1,2]`, true},
+		{"exact number text", problem.Comparator{Type: problem.CompareExact}, `1`, `1.0`, false},
+		{"whitespace crlf", problem.Comparator{Type: problem.CompareWhitespace}, `"a b\nc"`, `"a  b\r\nc \n\n"`, true},
+		{"whitespace tokens", problem.Comparator{Type: problem.CompareWhitespace}, `"a b"`, `"ab"`, false},
+		{"float default", problem.Comparator{Type: problem.CompareFloat}, `0.3`, `0.30000000000000004`, true},
+		{"float abs", problem.Comparator{Type: problem.CompareFloat, AbsEps: 0.01}, `[1.5]`, `[1.509]`, true},
+		{"float abs outside", problem.Comparator{Type: problem.CompareFloat, AbsEps: 0.01}, `1.5`, `1.52`, false},
+		{"float rel", problem.Comparator{Type: problem.CompareFloat, RelEps: 1e-3}, `1000000`, `1000500`, true},
+		{"unordered", problem.Comparator{Type: problem.CompareUnordered}, `[1, 2, 2]`, `[2, 1, 2]`, true},
+		{"unordered counts", problem.Comparator{Type: problem.CompareUnordered}, `[1, 2, 2]`, `[1, 1, 2]`, false},
+		{"set", problem.Comparator{Type: problem.CompareSet}, `[1, 2]`, `[2, 1, 2, 1]`, true},
+		{"set missing", problem.Comparator{Type: problem.CompareSet}, `[1, 2]`, `[1]`, false},
+		{"set not a list", problem.Comparator{Type: problem.CompareSet}, `[1]`, `1`, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			test := problem.TestCase{Expected: json.RawMessage(tc.expected)}
+			ok, _, err := compare(tc.c, test, json.RawMessage(tc.actual), nil)
+			if err != nil {
+				t.Fatalf("compare failed: %v", err)
+			}
+			if ok != tc.ok {
+				t.Errorf("Expected %v comparing %s with %s, but got %v", tc.ok, tc.expected, tc.actual, ok)
+			}
+		})
+	}
+}
+
+func TestCompareChecker(t *testing.T) {
+	c := problem.Comparator{Type: problem.CompareChecker, Checker: &problem.Checker{Language: "node", Source: "check()"}}
+	test := problem.TestCase{Args: []json.RawMessage{json.RawMessage("4")}, Expected: json.RawMessage("2")}
+
+	var stdin string
+	prepared := 0
+	prepare := func(language string, prog Program) (Runner, func(), error) {
+		prepared++
+		if prog.Files[prog.Entry] != "check()" || language != "node" {
+			t.Errorf("Expected the checker program to run, but got %+v", prog)
+		}
+		return func(tests []byte, input string, timeout time.Duration) (Output, error) {
+			stdin = input
+			return Output{Results: []byte(`{"v": 1, "type": "verdict", "test": 0, "value": {"ok": true, "message": "either root"}}`)}, nil
+		}, func() {}, nil
+	}
+	checkers := newCheckers(prepare, time.Second)
+	for range 2 {
+		ok, message, err := compare(c, test, json.RawMessage("-2"), checkers)
+		if err != nil || !ok || message != "either root" {
+			t.Errorf("Expected the checker to accept, but got %v, %q, %v", ok, message, err)
+		}
+	}
+	if stdin != `{"args":[4],"expected":2,"actual":-2}` {
+		t.Errorf("Unexpected checker input: %s", stdin)
+	}
+	if prepared != 1 {
+		t.Errorf("Expected the checker to be prepared once, but got %d", prepared)
+	}
+
+	silent := fake(func(Program, []byte, time.Duration) (Output, error) {
+		return Output{Stderr: "SyntaxError"}, nil
+	})
+	if _, _, err := compare(c, test, json.RawMessage("-2"), newCheckers(silent, time.Second)); err == nil || !strings.Contains(err.Error(), "SyntaxError") {
+		t.Errorf("Expected an error for a checker without verdict, but got %v", err)
+	}
+}
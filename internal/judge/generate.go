@@ -0,0 +1,185 @@
+package judge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+	"github.com/afman42/go-web-code-interactive/internal/protocol"
+	"github.com/afman42/go-web-code-interactive/internal/signature"
+)
+
+// StressBatch is how many generated cases a stress run generates and judges
+// at a time
+const StressBatch = 200
+
+// generatorFiles names the generator program file per language
+var generatorFiles = map[string]string{
+	"node": "generator.js",
+	"php":  "generator.php",
+	"go":   "generator.go",
+}
+
+// StressReport is the outcome of running a solution against generated cases
+type StressReport struct {
+	Cases  int `json:"cases"`
+	Failed int `json:"failed"`
+	// Verdict is Accepted when every case passed and otherwise the verdict
+	// of the smallest failure
+	Verdict       string `json:"verdict"`
+	CompileOutput string `json:"compileOutput,omitempty"`
+	// Smallest is the failing case with the shortest input and Seed the seed
+	// it was generated from, so it can be reproduced
+	Smallest *TestResult `json:"smallest,omitempty"`
+	Seed     *int64      `json:"seed,omitempty"`
+}
+
+// Inputs runs the problem's generator once for every seed and returns the
+// arguments it generated for each, checked against the signature
+func Inputs(p problem.Problem, seeds []int64, prepare Preparer) ([][]json.RawMessage, error) {
+	g := p.Generator
+	if g == nil {
+		return nil, fmt.Errorf("problem %s has no generator", p.ID)
+	}
+	var sig *signature.Signature
+	if p.Signature != "" {
+		parsed, err := signature.Parse(p.Signature)
+		if err != nil {
+			return nil, err
+		}
+		sig = &parsed
+	}
+	name := generatorFiles[g.Language]
+	run, release, err := prepare(g.Language, Program{Files: map[string]string{name: g.Source}, Entry: name})
+	if err != nil {
+		return nil, fmt.Errorf("generator: %v", err)
+	}
+	defer release()
+	stdin, err := json.Marshal(struct {
+		Seeds []int64 `json:"seeds"`
+	}{seeds})
+	if err != nil {
+		return nil, err
+	}
+	out, err := run(nil, string(stdin), p.TimeLimit())
+	if err != nil {
+		return nil, err
+	}
+	if out.TimedOut {
+		return nil, errors.New("generator timed out")
+	}
+	records, err := protocol.Parse(out.Results)
+	if err != nil {
+		return nil, fmt.Errorf("generator: %v", err)
+	}
+
+	inputs := make([][]json.RawMessage, len(seeds))
+	for i, seed := range seeds {
+		record, ok := protocol.Find(records, protocol.TypeInput, i)
+		if !ok {
+			return nil, fmt.Errorf("generator wrote no input for seed %d: %s", seed, strings.TrimSpace(out.Stderr))
+		}
+		if err := json.Unmarshal(record.Value, &inputs[i]); err != nil {
+			return nil, fmt.Errorf("generator input for seed %d: %v", seed, err)
+		}
+		if sig != nil {
+			if err := sig.CheckArgs(inputs[i]); err != nil {
+				return nil, fmt.Errorf("generator input for seed %d: %v", seed, err)
+			}
+		}
+	}
+	return inputs, nil
+}
+
+// Generate creates a hidden test per seed from the generator's arguments,
+// with the result of the reference solution as the expected value
+func Generate(p problem.Problem, seeds []int64, prepare Preparer) ([]problem.TestCase, error) {
+	language, ok := p.ReferenceLanguage()
+	if !ok {
+		return nil, errors.New("generated tests need a reference solution")
+	}
+	inputs, err := Inputs(p, seeds, prepare)
+	if err != nil {
+		return nil, err
+	}
+	tests := make([]problem.TestCase, len(seeds))
+	for i := range seeds {
+		tests[i] = problem.TestCase{Args: inputs[i], Hidden: true, Seed: &seeds[i]}
+	}
+
+	s := &session{p: p, language: language, tests: tests, solve: true}
+	res, err := s.run(p.Reference[language], ModeRun, prepare)
+	if err != nil {
+		return nil, fmt.Errorf("%s reference solution: %v", language, err)
+	}
+	if res.CompileOutput != "" {
+		return nil, fmt.Errorf("%s reference solution: %s", language, CompilationError)
+	}
+	for i, test := range res.Tests {
+		if !test.Passed {
+			return nil, fmt.Errorf("%s reference solution on seed %d: %s %s", language, seeds[i], test.Verdict, strings.TrimSpace(test.Stderr))
+		}
+		tests[i].Expected = json.RawMessage(test.Actual)
+	}
+	return tests, nil
+}
+
+// Expand replaces the generated tests of a problem with tests generated
+// for its generator's seeds, keeping the tests written by the author
+func Expand(p problem.Problem, prepare Preparer) (problem.Problem, error) {
+	p.Tests = p.Written()
+	if p.Generator == nil || len(p.Generator.Seeds) == 0 {
+		return p, nil
+	}
+	generated, err := Generate(p, p.Generator.Seeds, prepare)
+	if err != nil {
+		return p, err
+	}
+	p.Tests = append(p.Tests, generated...)
+	return p, nil
+}
+
+// Stress runs code against the cases generated for every seed and reports
+// the failing case with the shortest input
+func Stress(p problem.Problem, language, code string, seeds []int64, prepare Preparer) (StressReport, error) {
+	if !slices.Contains(p.Languages(), language) {
+		return StressReport{}, fmt.Errorf("problem %s does not support %s", p.ID, language)
+	}
+	report := StressReport{Verdict: Accepted}
+	for start := 0; start < len(seeds); start += StressBatch {
+		batch := seeds[start:min(start+StressBatch, len(seeds))]
+		tests, err := Generate(p, batch, prepare)
+		if err != nil {
+			return report, err
+		}
+		for i := range tests {
+			tests[i].Hidden = false
+		}
+		res, err := run(p, language, code, ModeRun, tests, prepare)
+		if err != nil {
+			return report, err
+		}
+		if res.CompileOutput != "" {
+			report.Verdict = CompilationError
+			report.CompileOutput = res.CompileOutput
+			return report, nil
+		}
+		report.Cases += len(tests)
+		for i, test := range res.Tests {
+			if test.Passed {
+				continue
+			}
+			report.Failed++
+			if report.Smallest == nil || len(test.Input) < len(report.Smallest.Input) {
+				test.Index = start + i
+				report.Smallest = &test
+				report.Seed = &batch[i]
+				report.Verdict = test.Verdict
+			}
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,112 @@
+package judge
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+	"github.com/afman42/go-web-code-interactive/internal/protocol"
+)
+
+// generatorProblem generates n = 3 * seed for every seed
+func generatorProblem() problem.Problem {
+	p := signatureProblem()
+	p.Reference = map[string]string{"node": "right"}
+	p.Generator = &problem.Generator{Language: "node", Source: "triple", Seeds: []int64{1, 2}}
+	return p
+}
+
+// generatorPreparer runs the generator and solutions of generatorProblem.
+// The right solution answers n+1, the buggy one answers n for even n from
+// 20 and a broken generator writes a string.
+func generatorPreparer(runs *int) Preparer {
+	return func(language string, prog Program) (Runner, func(), error) {
+		return func(tests []byte, stdin string, timeout time.Duration) (Output, error) {
+			*runs++
+			var out Output
+			if source, ok := prog.Files["generator.js"]; ok {
+				var in struct{ Seeds []int64 }
+				json.Unmarshal([]byte(stdin), &in)
+				for i, seed := range in.Seeds {
+					value := fmt.Sprint(seed * 3)
+					if source == "broken" {
+						value = `"x"`
+					}
+					out.Results = append(out.Results, fmt.Sprintf(`{"v": 1, "type": "input", "test": %d, "value": [%s]}`+"\n", i, value)...)
+				}
+				return out, nil
+			}
+			code := prog.Files[solutionFiles[language]]
+			for _, line := range strings.Split(strings.TrimSpace(string(tests)), "\n") {
+				var in protocol.Input
+				json.Unmarshal([]byte(line), &in)
+				n, _ := strconv.Atoi(string(in.Args[0]))
+				result := n + 1
+				if code == "buggy" && n >= 20 && n%2 == 0 {
+					result = n
+				}
+				out.Results = append(out.Results, fmt.Sprintf(`{"v": 1, "type": "result", "test": %d, "value": %d, "nonce": %q}`+"\n", in.Test, result, in.Nonce)...)
+			}
+			return out, nil
+		}, func() {}, nil
+	}
+}
+
+func TestExpand(t *testing.T) {
+	runs := 0
+	p, err := Expand(generatorProblem(), generatorPreparer(&runs))
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if len(p.Tests) != 3 || runs != 2 {
+		t.Fatalf("Expected two generated tests from one generator and one reference run, but got %+v after %d runs", p.Tests, runs)
+	}
+	generated := p.Tests[2]
+	if !generated.Hidden || *generated.Seed != 2 || string(generated.Args[0]) != "6" || string(generated.Expected) != "7" {
+		t.Errorf("Unexpected generated test: %+v", generated)
+	}
+
+	p.Generator.Seeds = []int64{5}
+	if p, _ = Expand(p, generatorPreparer(&runs)); len(p.Tests) != 2 || *p.Tests[1].Seed != 5 {
+		t.Errorf("Expected generated tests to be replaced, but got %+v", p.Tests)
+	}
+
+	broken := generatorProblem()
+	broken.Generator.Source = "broken"
+	if _, err := Expand(broken, generatorPreparer(&runs)); err == nil || !strings.Contains(err.Error(), "seed 1") {
+		t.Errorf("Expected error for arguments not matching the signature, but got %v", err)
+	}
+	noReference := generatorProblem()
+	noReference.Reference = nil
+	if _, err := Expand(noReference, generatorPreparer(&runs)); err == nil {
+		t.Error("Expected error without a reference solution, but got none")
+	}
+}
+
+func TestStress(t *testing.T) {
+	seeds := make([]int64, 300)
+	for i := range seeds {
+		seeds[i] = int64(i + 1)
+	}
+	runs := 0
+	report, err := Stress(generatorProblem(), "go", "buggy", seeds, generatorPreparer(&runs))
+	if err != nil {
+		t.Fatalf("Stress failed: %v", err)
+	}
+	// n = 3 * seed fails when even and at least 20, the first is seed 8
+	if report.Cases != 300 || report.Failed != 147 || report.Verdict != WrongAnswer {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if report.Smallest == nil || report.Smallest.Input != "24" || *report.Seed != 8 || report.Smallest.Index != 7 {
+		t.Errorf("Expected the smallest failing input, but got %+v", report.Smallest)
+	}
+
+	report, err = Stress(generatorProblem(), "node", "right", seeds[:10], generatorPreparer(&runs))
+	if err != nil || report.Verdict != Accepted || report.Failed != 0 || report.Smallest != nil {
+		t.Errorf("Expected every case to pass, but got %+v, %v", report, err)
+	}
+}
@@ -0,0 +1,428 @@
+package judge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+	"github.com/afman42/go-web-code-interactive/internal/protocol"
+)
+
+// Verdicts of a test case and of a whole submission
+const (
+	Accepted          = "Accepted"
+	WrongAnswer       = "Wrong Answer"
+	RuntimeError      = "Runtime Error"
+	TimeLimitExceeded = "Time Limit Exceeded"
+	CompilationError  = "Compilation Error"
+)
+
+// Modes of a judged run
+const (
+	// ModeRun executes the sample tests and reports every detail
+	ModeRun = "run"
+	// ModeSubmit executes every test but only reports the pass count and the
+	// first failure, without the input or expected output of hidden tests
+	ModeSubmit = "submit"
+)
+
+// Output is what a single run of a program produced
+type Output struct {
+	Stdout string
+	Stderr string
+	// Results is the content of the results file written by the harness
+	Results  []byte
+	TimedOut bool
+}
+
+// Runner runs a prepared program once with the content of the tests file
+// and standard input
+type Runner func(tests []byte, stdin string, timeout time.Duration) (Output, error)
+
+// Preparer writes a program to its own directory and builds it when the
+// language is compiled, so it can run many times for the cost of one build.
+// Release removes the program once it is no longer needed. A program that
+// does not build is reported as a *CompileError.
+type Preparer func(language string, prog Program) (run Runner, release func(), err error)
+
+// CompileError is the compiler output of a program that does not build
+type CompileError struct {
+	Output string
+}
+
+func (e *CompileError) Error() string {
+	return e.Output
+}
+
+// loops names the languages whose generated drivers run every test of a
+// request in one process, catching exceptions per test. Compiled programs
+// start quickly once built and run once per test, keeping each test's
+// limit and output apart.
+var loops = map[string]bool{"node": true, "php": true}
+
+// TestResult is the verdict of one test case
+type TestResult struct {
+	Index    int      `json:"index"`
+	Verdict  string   `json:"verdict"`
+	Passed   bool     `json:"passed"`
+	Hidden   bool     `json:"hidden,omitempty"`
+	Input    string   `json:"input"`
+	Expected string   `json:"expected"`
+	Actual   string   `json:"actual"`
+	Stdout   string   `json:"stdout,omitempty"`
+	Stderr   string   `json:"stderr,omitempty"`
+	Diff     []string `json:"diff,omitempty"`
+	// Message explains a checker's decision
+	Message    string `json:"message,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// Result is the verdict of a submission over every test case
+type Result struct {
+	Mode    string       `json:"mode"`
+	Verdict string       `json:"verdict"`
+	Passed  int          `json:"passed"`
+	Total   int          `json:"total"`
+	Score   int          `json:"score"`
+	Tests   []TestResult `json:"tests"`
+	// DurationMs sums the measured durations of the judged tests, building
+	// the program is not part of it
+	DurationMs int64 `json:"durationMs"`
+	// CompileOutput is what the compiler reported for a program that does
+	// not build
+	CompileOutput string `json:"compileOutput,omitempty"`
+	// FirstFailure is the first failing test of a submission
+	FirstFailure *TestResult `json:"firstFailure,omitempty"`
+}
+
+// session judges one submission against the selected tests
+type session struct {
+	p        problem.Problem
+	language string
+	tests    []problem.TestCase
+	checkers *checkers
+	// solve accepts every result as it is, for a reference solution
+	// computing the expected values of generated tests
+	solve bool
+}
+
+// Run executes code against the test cases of the problem selected by mode,
+// each under the problem's own time limit. A generated driver reads the tests
+// from the tests file, so the program is built only once: interpreters loop
+// over the tests in one process and compiled binaries run once per test.
+// A hand-written harness is built for every test. The harness reports the
+// function's return value through the results file, standard output is
+// entirely the user's own.
+func Run(p problem.Problem, language, code, mode string, prepare Preparer) (Result, error) {
+	if !slices.Contains(p.Languages(), language) {
+		return Result{}, fmt.Errorf("problem %s does not support %s", p.ID, language)
+	}
+	var tests []problem.TestCase
+	switch mode {
+	case ModeRun:
+		tests = p.Samples()
+	case ModeSubmit:
+		tests = p.Tests
+	default:
+		return Result{}, fmt.Errorf("unknown mode: %s", mode)
+	}
+	return run(p, language, code, mode, tests, prepare)
+}
+
+// Verify runs the problem's reference solution in every language it
+// supports against all tests, with every detail reported. It fails when a
+// language has no reference solution or its solution is not accepted.
+func Verify(p problem.Problem, prepare Preparer) (map[string]Result, error) {
+	results := make(map[string]Result)
+	for _, language := range p.Languages() {
+		code := p.Reference[language]
+		if code == "" {
+			return results, fmt.Errorf("no %s reference solution", language)
+		}
+		res, err := run(p, language, code, ModeRun, p.Tests, prepare)
+		if err != nil {
+			return results, fmt.Errorf("%s reference solution: %v", language, err)
+		}
+		results[language] = res
+		if res.Verdict != Accepted {
+			return results, fmt.Errorf("%s reference solution: %s", language, res.Verdict)
+		}
+	}
+	return results, nil
+}
+
+// run judges code against the given tests, reporting every test in run mode
+func run(p problem.Problem, language, code, mode string, tests []problem.TestCase, prepare Preparer) (Result, error) {
+	s := &session{p: p, language: language, tests: tests}
+	return s.run(code, mode, prepare)
+}
+
+// run judges code against the session's tests
+func (s *session) run(code, mode string, prepare Preparer) (Result, error) {
+	p, tests := s.p, s.tests
+	s.checkers = newCheckers(prepare, p.TimeLimit())
+	defer s.checkers.close()
+	var tested []TestResult
+	var err error
+	if p.ReadsTests(s.language) {
+		tested, err = s.runShared(code, prepare)
+	} else {
+		tested, err = s.runEach(code, prepare)
+	}
+
+	result := Result{Mode: mode, Verdict: Accepted, Total: len(tests), Tests: []TestResult{}}
+	var compileErr *CompileError
+	if errors.As(err, &compileErr) {
+		result.CompileOutput = compileErr.Output
+		tested = make([]TestResult, len(tests))
+		for i := range tests {
+			tested[i] = s.newTest(i)
+			tested[i].Verdict = CompilationError
+		}
+	} else if err != nil {
+		return Result{}, err
+	}
+
+	for _, test := range tested {
+		result.DurationMs += test.DurationMs
+		if test.Passed {
+			result.Passed++
+		} else if result.Verdict == Accepted {
+			result.Verdict = test.Verdict
+			if mode == ModeSubmit {
+				failure := redact(test)
+				result.FirstFailure = &failure
+			}
+		}
+		if mode == ModeRun {
+			result.Tests = append(result.Tests, test)
+		}
+	}
+	if result.Total > 0 {
+		result.Score = result.Passed * 100 / result.Total
+	}
+	return result, nil
+}
+
+// runShared prepares the program once and feeds it the tests through the
+// tests file. A process looping over several tests shares one time limit,
+// each test's own duration is checked from its record. After such a run
+// stops early the remaining tests each get a fresh process, so a slow or
+// crashing test costs at most one extra time limit.
+func (s *session) runShared(code string, prepare Preparer) ([]TestResult, error) {
+	prog, err := Build(s.p, s.language, code, problem.TestCase{})
+	if err != nil {
+		return nil, err
+	}
+	run, release, err := prepare(s.language, prog)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	tested := make([]TestResult, len(s.tests))
+	batch := loops[s.language]
+	nonce, err := protocol.NewNonce()
+	if err != nil {
+		return nil, err
+	}
+	for next := 0; next < len(s.tests); {
+		end := next + 1
+		if batch {
+			end = len(s.tests)
+		}
+		inputs := make([]protocol.Input, 0, end-next)
+		for i := next; i < end; i++ {
+			inputs = append(inputs, protocol.Input{Test: i, Args: s.tests[i].Args, Nonce: nonce})
+		}
+		data, err := protocol.EncodeInputs(inputs)
+		if err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		out, err := run(data, "", s.p.TimeLimit())
+		if err != nil {
+			return nil, err
+		}
+		done, err := s.collect(out, nonce, time.Since(start), next, end, tested)
+		if err != nil {
+			return nil, err
+		}
+		if done < end {
+			batch = false
+		}
+		next = done
+	}
+	return tested, nil
+}
+
+// runEach builds and runs a hand-written harness once per test case
+func (s *session) runEach(code string, prepare Preparer) ([]TestResult, error) {
+	tested := make([]TestResult, len(s.tests))
+	for i, tc := range s.tests {
+		prog, err := Build(s.p, s.language, code, tc)
+		if err != nil {
+			return nil, err
+		}
+		run, release, err := prepare(s.language, prog)
+		if err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		out, err := run(nil, "", s.p.TimeLimit())
+		release()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.collect(out, "", time.Since(start), i, i+1, tested); err != nil {
+			return nil, err
+		}
+	}
+	return tested, nil
+}
+
+// collect judges tests[from:to] from one run of the program and returns the
+// index after the last test the run accounts for. When the program stopped
+// in the middle of a test, that test is charged with the runtime error, or
+// with the time limit when it ran alone, and the tests after it are left to
+// another run. Output that cannot be attributed to a single test goes to the
+// first one. With a nonce, records that do not carry it were not written by
+// the generated harness and are ignored.
+func (s *session) collect(out Output, nonce string, elapsed time.Duration, from, to int, tested []TestResult) (int, error) {
+	records, err := protocol.Parse(out.Results)
+	if err != nil {
+		out.Stderr = strings.TrimSpace(out.Stderr + "\n" + err.Error())
+		records = nil
+	}
+	if nonce != "" {
+		var forged int
+		if records, forged = protocol.Signed(records, nonce); forged > 0 {
+			out.Stderr = strings.TrimSpace(fmt.Sprintf("%s\n%d results records not written by the harness were ignored", out.Stderr, forged))
+		}
+	}
+	for i := from; i < to; i++ {
+		test, finished, err := s.fromRecords(i, records)
+		if err != nil {
+			return 0, err
+		}
+		if i == from {
+			test.Stdout = out.Stdout + test.Stdout
+		}
+		if !finished {
+			if out.TimedOut && to-from > 1 {
+				// The shared limit ran out, the test gets a run of its own
+				return i, nil
+			}
+			test.Stderr = out.Stderr
+			test.Verdict = RuntimeError
+			if out.TimedOut {
+				test.Verdict = TimeLimitExceeded
+			}
+			test.DurationMs = elapsed.Milliseconds()
+			tested[i] = test
+			return i + 1, nil
+		}
+		if test.DurationMs == 0 && to-from == 1 {
+			test.DurationMs = elapsed.Milliseconds()
+		}
+		tested[i] = test
+	}
+	tested[from].Stderr = strings.TrimSpace(out.Stderr + "\n" + tested[from].Stderr)
+	return to, nil
+}
+
+// newTest returns the result of test i before it is judged
+func (s *session) newTest(i int) TestResult {
+	tc := s.tests[i]
+	args := make([]string, len(tc.Args))
+	for j, arg := range tc.Args {
+		args[j] = string(arg)
+	}
+	return TestResult{
+		Index:    i,
+		Hidden:   tc.Hidden,
+		Input:    strings.Join(args, ", "),
+		Expected: string(tc.Expected),
+	}
+}
+
+// fromRecords judges test i from the records of a run. Generated drivers
+// report each test under its index, hand-written harnesses run a single
+// test reported as test 0. It returns false when the run wrote neither a
+// result nor an error for the test.
+func (s *session) fromRecords(i int, records []protocol.Record) (TestResult, bool, error) {
+	test := s.newTest(i)
+	id := i
+	if !s.p.ReadsTests(s.language) {
+		id = 0
+	}
+	if stdout, ok := protocol.Find(records, protocol.TypeStdout, id); ok {
+		json.Unmarshal(stdout.Value, &test.Stdout)
+	}
+	if failed, ok := protocol.Find(records, protocol.TypeError, id); ok {
+		test.Verdict = RuntimeError
+		json.Unmarshal(failed.Value, &test.Stderr)
+		test.DurationMs = int64(math.Ceil(failed.DurationMs))
+		return test, true, nil
+	}
+	record, ok := protocol.Find(records, protocol.TypeResult, id)
+	if !ok {
+		return test, false, nil
+	}
+	test.Actual = string(record.Value)
+	test.DurationMs = int64(math.Ceil(record.DurationMs))
+	if time.Duration(record.DurationMs*float64(time.Millisecond)) > s.p.TimeLimit() {
+		test.Verdict = TimeLimitExceeded
+		return test, true, nil
+	}
+	if s.solve {
+		test.Verdict = Accepted
+		test.Passed = true
+		return test, true, nil
+	}
+
+	tc := s.tests[i]
+	ok, message, err := compare(s.p.Comparator(tc), tc, record.Value, s.checkers)
+	if err != nil {
+		return TestResult{}, false, err
+	}
+	test.Message = message
+	if ok {
+		test.Verdict = Accepted
+		test.Passed = true
+		return test, true, nil
+	}
+	var expected, actual any
+	json.Unmarshal(tc.Expected, &expected)
+	json.Unmarshal(record.Value, &actual)
+	test.Verdict = WrongAnswer
+	test.Diff = Diff(pretty(expected), pretty(actual))
+	return test, true, nil
+}
+
+// redact removes everything from a hidden test's result that could reveal
+// its input or expected output, including what the program printed
+func redact(test TestResult) TestResult {
+	if !test.Hidden {
+		return test
+	}
+	return TestResult{
+		Index:      test.Index,
+		Verdict:    test.Verdict,
+		Hidden:     true,
+		DurationMs: test.DurationMs,
+	}
+}
+
+func pretty(v any) string {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,374 @@
+package judge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+	"github.com/afman42/go-web-code-interactive/internal/protocol"
+)
+
+// result is the results file of a harness reporting value for test 0
+func result(value string) []byte {
+	return []byte(`{"v": 1, "type": "result", "test": 0, "value": ` + value + "}\n")
+}
+
+// fake returns a Preparer whose programs answer every run with out
+func fake(out func(prog Program, tests []byte, timeout time.Duration) (Output, error)) Preparer {
+	return func(language string, prog Program) (Runner, func(), error) {
+		return func(tests []byte, stdin string, timeout time.Duration) (Output, error) {
+			return out(prog, tests, timeout)
+		}, func() {}, nil
+	}
+}
+
+func testProblem() problem.Problem {
+	return problem.Problem{
+		ID:          "double",
+		Function:    "double",
+		Harness:     map[string]string{"node": "\nconsole.log(JSON.stringify(double({{args}})))"},
+		TimeLimitMs: 500,
+		Tests: []problem.TestCase{
+			{Args: []json.RawMessage{json.RawMessage("1")}, Expected: json.RawMessage("2")},
+			{Args: []json.RawMessage{json.RawMessage("2")}, Expected: json.RawMessage("4")},
+			{Args: []json.RawMessage{json.RawMessage("3")}, Expected: json.RawMessage("6")},
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	// The fake runner answers per test case, keyed by the argument
+	outputs := map[string]Output{
+		"1": {Stdout: "debug\n", Results: result("2")},
+		"2": {Results: result("5")},
+		"3": {TimedOut: true, Stderr: "Execution timed out"},
+	}
+	var timeouts []time.Duration
+	run := fake(func(prog Program, tests []byte, timeout time.Duration) (Output, error) {
+		code := prog.Files["stq_driver.js"]
+		timeouts = append(timeouts, timeout)
+		arg := strings.TrimRight(code[strings.LastIndex(code, "double(")+7:], ")")
+		return outputs[arg], nil
+	})
+
+	res, err := Run(testProblem(), "node", "function double(n) {}", ModeRun, run)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if res.Verdict != WrongAnswer || res.Passed != 1 || res.Total != 3 || res.Score != 33 {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+	if len(timeouts) != 3 || timeouts[0] != 500*time.Millisecond {
+		t.Errorf("Expected each test to run with the problem time limit, but got %v", timeouts)
+	}
+
+	passed := res.Tests[0]
+	if !passed.Passed || passed.Actual != "2" || passed.Stdout != "debug\n" {
+		t.Errorf("Unexpected passed test: %+v", passed)
+	}
+	wrong := res.Tests[1]
+	if wrong.Verdict != WrongAnswer || wrong.Expected != "4" || wrong.Actual != "5" {
+		t.Errorf("Unexpected wrong test: %+v", wrong)
+	}
+	if !reflect.DeepEqual(wrong.Diff, []string{"- 4", "+ 5"}) {
+		t.Errorf("Unexpected diff: %q", wrong.Diff)
+	}
+	if res.Tests[2].Verdict != TimeLimitExceeded {
+		t.Errorf("Expected time limit exceeded, but got: %+v", res.Tests[2])
+	}
+}
+
+func TestRunModes(t *testing.T) {
+	p := testProblem()
+	p.Tests[1].Hidden = true
+	p.Tests[2].Hidden = true
+	// Every test prints the argument with a debug line, so hidden tests fail
+	// with output that would reveal their input
+	run := fake(func(prog Program, tests []byte, timeout time.Duration) (Output, error) {
+		code := prog.Files["stq_driver.js"]
+		arg := strings.TrimRight(code[strings.LastIndex(code, "double(")+7:], ")")
+		if arg == "1" {
+			return Output{Results: result("2")}, nil
+		}
+		return Output{Stdout: "input " + arg + "\n", Stderr: "input " + arg, Results: result("0")}, nil
+	})
+
+	t.Run("run", func(t *testing.T) {
+		res, err := Run(p, "node", "", ModeRun, run)
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if res.Total != 1 || len(res.Tests) != 1 || res.Verdict != Accepted {
+			t.Errorf("Expected only the sample test to run, but got: %+v", res)
+		}
+	})
+
+	t.Run("submit", func(t *testing.T) {
+		res, err := Run(p, "node", "", ModeSubmit, run)
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if res.Total != 3 || res.Passed != 1 || res.Verdict != WrongAnswer {
+			t.Errorf("Expected every test to run, but got: %+v", res)
+		}
+		if len(res.Tests) != 0 {
+			t.Errorf("Expected no per-test details on submit, but got: %+v", res.Tests)
+		}
+		failure := res.FirstFailure
+		if failure == nil || failure.Index != 1 || !failure.Hidden || failure.Verdict != WrongAnswer {
+			t.Fatalf("Expected the first hidden failure, but got: %+v", failure)
+		}
+		if failure.Input != "" || failure.Expected != "" || failure.Actual != "" || failure.Stdout != "" || failure.Stderr != "" || failure.Diff != nil {
+			t.Errorf("Expected hidden failure to be redacted, but got: %+v", failure)
+		}
+	})
+}
+
+func TestRunVerdicts(t *testing.T) {
+	testCases := []struct {
+		name     string
+		output   Output
+		expected string
+	}{
+		{"accepted", Output{Stdout: "2\n", Results: result("2")}, Accepted},
+		{"structurally equal", Output{Results: result("2.0")}, Accepted},
+		{"wrong answer", Output{Results: result(`"2"`)}, WrongAnswer},
+		{"debug print is not the result", Output{Stdout: "2\n", Results: result("3")}, WrongAnswer},
+		{"missing value", Output{Results: []byte(`{"v": 1, "type": "result", "test": 0}`)}, WrongAnswer},
+		{"unknown version", Output{Results: []byte(`{"v": 99, "type": "result", "test": 0, "value": 2}`)}, RuntimeError},
+		{"runtime error", Output{Stderr: "TypeError: boom"}, RuntimeError},
+		{"no result", Output{Stdout: "2\n"}, RuntimeError},
+		{"time limit", Output{TimedOut: true}, TimeLimitExceeded},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := testProblem()
+			p.Tests = p.Tests[:1]
+			res, err := Run(p, "node", "", ModeRun, fake(func(Program, []byte, time.Duration) (Output, error) {
+				return tc.output, nil
+			}))
+			if err != nil {
+				t.Fatalf("Run failed: %v", err)
+			}
+			if res.Verdict != tc.expected {
+				t.Errorf("Expected verdict %q, but got %q", tc.expected, res.Verdict)
+			}
+		})
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	if _, err := Run(testProblem(), "go", "", ModeRun, nil); err == nil {
+		t.Error("Expected error for unsupported language, but got none")
+	}
+	if _, err := Run(testProblem(), "node", "", "debug", nil); err == nil {
+		t.Error("Expected error for unknown mode, but got none")
+	}
+	fail := errors.New("disk full")
+	_, err := Run(testProblem(), "node", "", ModeRun, fake(func(Program, []byte, time.Duration) (Output, error) {
+		return Output{}, fail
+	}))
+	if err != fail {
+		t.Errorf("Expected runner error, but got: %v", err)
+	}
+}
+
+// sharedProblem has five tests of a generated driver, test 2 is slow and
+// test 3 crashes the program
+func sharedProblem() problem.Problem {
+	p := signatureProblem()
+	p.TimeLimitMs = 100
+	p.Tests = nil
+	for n := 1; n <= 5; n++ {
+		arg, _ := json.Marshal(n)
+		p.Tests = append(p.Tests, problem.TestCase{Args: []json.RawMessage{arg}, Expected: json.RawMessage(fmt.Sprint(n + 1))})
+	}
+	return p
+}
+
+// sharedOutput answers a run of the tests file like a generated driver would
+func sharedOutput(tests []byte) Output {
+	inputs := strings.Split(strings.TrimSpace(string(tests)), "\n")
+	var out Output
+	for _, line := range inputs {
+		var in protocol.Input
+		json.Unmarshal([]byte(line), &in)
+		n, _ := strconv.Atoi(string(in.Args[0]))
+		switch n {
+		case 3:
+			out.Results = append(out.Results, fmt.Sprintf(`{"v": 1, "type": "result", "test": %d, "value": %d, "durationMs": 250, "nonce": %q}`+"\n", in.Test, n+1, in.Nonce)...)
+		case 4:
+			if len(inputs) > 1 {
+				out.Stderr = "Segmentation fault"
+				return out
+			}
+			out.Results = append(out.Results, fmt.Sprintf(`{"v": 1, "type": "error", "test": %d, "value": "boom", "nonce": %q}`+"\n", in.Test, in.Nonce)...)
+		default:
+			out.Results = append(out.Results, fmt.Sprintf(`{"v": 1, "type": "result", "test": %d, "value": %d, "durationMs": 1, "nonce": %q}`+"\n", in.Test, n+1, in.Nonce)...)
+		}
+	}
+	return out
+}
+
+func TestRunShared(t *testing.T) {
+	testCases := []struct {
+		language string
+		runs     int
+	}{
+		// node loops over every test until the fourth crashes the process,
+		// then runs the fifth alone
+		{"node", 2},
+		{"go", 5},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.language, func(t *testing.T) {
+			prepared, runs := 0, 0
+			prepare := func(language string, prog Program) (Runner, func(), error) {
+				prepared++
+				return func(tests []byte, stdin string, timeout time.Duration) (Output, error) {
+					runs++
+					if timeout != 100*time.Millisecond {
+						t.Errorf("Expected the problem time limit, but got %v", timeout)
+					}
+					return sharedOutput(tests), nil
+				}, func() {}, nil
+			}
+			res, err := Run(sharedProblem(), tc.language, "", ModeRun, prepare)
+			if err != nil {
+				t.Fatalf("Run failed: %v", err)
+			}
+			if prepared != 1 || runs != tc.runs {
+				t.Errorf("Expected 1 build and %d runs, but got %d and %d", tc.runs, prepared, runs)
+			}
+			verdicts := []string{}
+			for _, test := range res.Tests {
+				verdicts = append(verdicts, test.Verdict)
+			}
+			expected := []string{Accepted, Accepted, TimeLimitExceeded, RuntimeError, Accepted}
+			if !reflect.DeepEqual(verdicts, expected) {
+				t.Errorf("Expected verdicts %v, but got %v", expected, verdicts)
+			}
+			if res.Tests[3].Stderr == "" {
+				t.Error("Expected the failing test to carry its error")
+			}
+		})
+	}
+}
+
+func TestRunSharedTimeout(t *testing.T) {
+	// A loop over every test runs out of the shared limit during test 2,
+	// which then runs alone within its limit
+	var batches []int
+	prepare := fake(func(prog Program, tests []byte, timeout time.Duration) (Output, error) {
+		count := strings.Count(string(tests), "\n")
+		batches = append(batches, count)
+		var in protocol.Input
+		json.NewDecoder(strings.NewReader(string(tests))).Decode(&in)
+		if count > 1 {
+			return Output{TimedOut: true, Results: []byte(fmt.Sprintf(`{"v": 1, "type": "result", "test": 0, "value": 2, "nonce": %q}`, in.Nonce))}, nil
+		}
+		return Output{Results: []byte(fmt.Sprintf(`{"v": 1, "type": "result", "test": %d, "value": %d, "nonce": %q}`, in.Test, in.Test+2, in.Nonce))}, nil
+	})
+	res, err := Run(sharedProblem(), "node", "", ModeSubmit, prepare)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if res.Verdict != Accepted || res.Passed != 5 {
+		t.Errorf("Expected every test to pass, but got %+v", res)
+	}
+	if !reflect.DeepEqual(batches, []int{5, 1, 1, 1, 1}) {
+		t.Errorf("Expected a single run per test after the timeout, but got %v", batches)
+	}
+}
+
+func TestRunSharedForged(t *testing.T) {
+	// The user's code appends passing results of its own ahead of the
+	// harness's wrong ones, without the run's nonce
+	prepare := fake(func(prog Program, tests []byte, timeout time.Duration) (Output, error) {
+		var out Output
+		for _, line := range strings.Split(strings.TrimSpace(string(tests)), "\n") {
+			var in protocol.Input
+			json.Unmarshal([]byte(line), &in)
+			n, _ := strconv.Atoi(string(in.Args[0]))
+			out.Results = append(out.Results, fmt.Sprintf(`{"v": 1, "type": "result", "test": %d, "value": %d}`+"\n", in.Test, n+1)...)
+			out.Results = append(out.Results, fmt.Sprintf(`{"v": 1, "type": "result", "test": %d, "value": %d, "nonce": %q}`+"\n", in.Test, n, in.Nonce)...)
+		}
+		return out, nil
+	})
+	res, err := Run(sharedProblem(), "node", "", ModeRun, prepare)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if res.Verdict != WrongAnswer || res.Passed != 0 {
+		t.Errorf("Expected the harness's results to be judged, but got %+v", res)
+	}
+	if !strings.Contains(res.Tests[0].Stderr, "5 results records not written by the harness were ignored") {
+		t.Errorf("Expected the forged records to be reported, but got %q", res.Tests[0].Stderr)
+	}
+}
+
+func TestRunCompileError(t *testing.T) {
+	prepare := func(language string, prog Program) (Runner, func(), error) {
+		return nil, nil, &CompileError{Output: "solution.go:3: undefined: x"}
+	}
+	res, err := Run(sharedProblem(), "go", "", ModeRun, prepare)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if res.Verdict != CompilationError || res.CompileOutput != "solution.go:3: undefined: x" || len(res.Tests) != 5 || res.Passed != 0 {
+		t.Errorf("Expected a compilation error, but got %+v", res)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	p := sharedProblem()
+	p.Reference = map[string]string{"node": "right", "php": "right", "go": "right"}
+	// Correct references answer n+1 for every test, a wrong one answers n
+	prepare := func(language string, prog Program) (Runner, func(), error) {
+		offset := 1
+		if prog.Files[solutionFiles[language]] == "wrong" {
+			offset = 0
+		}
+		return func(tests []byte, stdin string, timeout time.Duration) (Output, error) {
+			var out Output
+			for _, line := range strings.Split(strings.TrimSpace(string(tests)), "\n") {
+				var in protocol.Input
+				json.Unmarshal([]byte(line), &in)
+				n, _ := strconv.Atoi(string(in.Args[0]))
+				out.Results = append(out.Results, fmt.Sprintf(`{"v": 1, "type": "result", "test": %d, "value": %d, "nonce": %q}`+"\n", in.Test, n+offset, in.Nonce)...)
+			}
+			return out, nil
+		}, func() {}, nil
+	}
+
+	results, err := Verify(p, prepare)
+	if err != nil || len(results) != 3 || results["go"].Passed != 5 {
+		t.Errorf("Expected every reference to pass all tests, but got %+v, %v", results, err)
+	}
+
+	p.Reference["php"] = "wrong"
+	results, err = Verify(p, prepare)
+	if err == nil || !strings.Contains(err.Error(), "php") || results["php"].Verdict != WrongAnswer || len(results["php"].Tests) != 5 {
+		t.Errorf("Expected the php reference to fail with details, but got %+v, %v", results, err)
+	}
+
+	delete(p.Reference, "go")
+	p.Reference["php"] = "right"
+	if _, err := Verify(p, prepare); err == nil || !strings.Contains(err.Error(), "no go reference") {
+		t.Errorf("Expected error for a missing reference, but got %v", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	got := Diff("[\n  1,\n  2\n]", "[\n  1,\n  3,\n  2\n]")
+	expected := []string{"  [", "    1,", "+   3,", "    2", "  ]"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, but got %q", expected, got)
+	}
+}
@@ -0,0 +1,167 @@
+package judge
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+	"github.com/afman42/go-web-code-interactive/internal/protocol"
+)
+
+// Program is a set of source files run together from one directory. The
+// user's code is stored unchanged in its own file so compiler errors and
+// stack traces point at the user's line numbers.
+type Program struct {
+	// Files maps file names to their content
+	Files map[string]string
+	// Entry is the file passed to the interpreter. Go builds every file, the
+	// entry last so the harness's package variables are initialized before
+	// the user's.
+	Entry string
+}
+
+// Names returns the file names in order, the user's solution first
+func (p Program) Names() []string {
+	names := make([]string, 0, len(p.Files))
+	for name := range p.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// File names of the user's solution per language
+var solutionFiles = map[string]string{
+	"node": "solution.js",
+	"php":  "solution.php",
+	"go":   "solution.go",
+}
+
+// SolutionFile returns the name of the file holding the user's code in the
+// programs Build lays out
+func SolutionFile(language string) string {
+	return solutionFiles[language]
+}
+
+// nodeLoader evaluates the solution and then the driver in the same global
+// scope, so the driver sees top-level functions, classes and constants
+// without the user having to export them. The tests file is read and
+// removed first, and the driver gets the tests along with functions writing
+// records signed with the run's nonce and reading the clock, taken before the
+// user's code could replace them. The nonce stays in the loader's scope.
+var nodeLoader = fmt.Sprintf(`const fs = require('fs');
+const path = require('path');
+const vm = require('vm');
+const append = fs.appendFileSync;
+const stringify = JSON.stringify;
+const now = performance.now.bind(performance);
+const testsFile = process.env.%[1]s;
+const results = process.env.%[2]s;
+let nonce = '';
+const tests = [];
+if (testsFile && fs.existsSync(testsFile)) {
+  for (const line of fs.readFileSync(testsFile, 'utf8').split('\n')) {
+    if (!line.trim()) continue;
+    const input = JSON.parse(line);
+    nonce = input.nonce || '';
+    tests.push({ test: input.test, args: input.args });
+  }
+  fs.unlinkSync(testsFile);
+}
+const write = (record) => append(results, stringify({ v: %[3]d, nonce, ...record }) + '\n');
+globalThis.require = require;
+vm.runInThisContext(fs.readFileSync(path.join(__dirname, 'solution.js'), 'utf8'), { filename: 'solution.js' });
+const driver = '(function (__tests, __write, __now) {' + fs.readFileSync(path.join(__dirname, 'stq_driver.js'), 'utf8') + '\n})';
+vm.runInThisContext(driver, { filename: 'stq_driver.js' })(tests, write, now);
+`, protocol.TestsEnv, protocol.ResultsEnv, protocol.Version)
+
+// phpLoader reads and removes the tests file, requires the solution and
+// then writes the records the generated driver's __sig_main yields, signed
+// with the run's nonce. The nonce is a local of a generator suspended while
+// the solution loads, so the user's code cannot reach it.
+var phpLoader = fmt.Sprintf(`<?php
+$__stq = (function () {
+    $tests = [];
+    $nonce = '';
+    foreach (file(getenv('%[1]s'), FILE_IGNORE_NEW_LINES | FILE_SKIP_EMPTY_LINES) as $line) {
+        $input = json_decode($line, true);
+        $nonce = $input['nonce'] ?? '';
+        $tests[] = ['test' => $input['test'], 'args' => $input['args']];
+    }
+    unlink(getenv('%[1]s'));
+    $results = getenv('%[2]s');
+    yield;
+    foreach (__sig_main($tests) as $record) {
+        file_put_contents($results, json_encode(['v' => %[3]d, 'nonce' => $nonce] + $record) . "\n", FILE_APPEND);
+    }
+})();
+$__stq->current();
+require __DIR__ . '/solution.php';
+$__stq->next();
+`, protocol.TestsEnv, protocol.ResultsEnv, protocol.Version)
+
+// goEntry is added when neither the user's file nor a hand-written harness
+// declares a main function
+const goEntry = "package main\n\nfunc main() {}\n"
+
+// hiddenMain renames the main function of the user's file when a generated
+// driver declares its own. It has the length of main so every position in
+// the user's file stays the same.
+const hiddenMain = "_stq"
+
+// Build lays out the user's code and the problem's harness for one test
+// case as separate files
+func Build(p problem.Problem, language, code string, tc problem.TestCase) (Program, error) {
+	driver, err := p.Driver(language, tc)
+	if err != nil {
+		return Program{}, err
+	}
+	solution := solutionFiles[language]
+	prog := Program{Files: map[string]string{solution: code}}
+	switch language {
+	case "node":
+		prog.Files["stq_driver.js"] = driver
+		prog.Files["stq_main.js"] = nodeLoader
+		prog.Entry = "stq_main.js"
+	case "php":
+		if p.ReadsTests(language) {
+			prog.Files["stq_main.php"] = phpLoader + driver
+		} else {
+			prog.Files["stq_main.php"] = "<?php\nrequire __DIR__ . '/" + solution + "';\n" + driver
+		}
+		prog.Entry = "stq_main.php"
+	case "go":
+		prog.Files["stq_driver.go"] = driver
+		// Generated drivers run the tests from their own main, after every
+		// init function of the user's file
+		offset := mainOffset(code)
+		if p.ReadsTests(language) && offset >= 0 {
+			prog.Files[solution] = code[:offset] + hiddenMain + code[offset+len("main"):]
+		} else if !p.ReadsTests(language) && offset < 0 {
+			prog.Files["stq_main.go"] = goEntry
+		}
+		prog.Entry = solution
+	}
+	return prog, nil
+}
+
+// mainOffset returns the offset of the name of the main function Go source
+// declares, or -1 when it declares none. Code that does not parse is
+// reported as not declaring one, the compiler then points at the user's
+// syntax error.
+func mainOffset(code string) int {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", code, parser.SkipObjectResolution)
+	if err != nil {
+		return -1
+	}
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == "main" {
+			return fset.Position(fn.Name.Pos()).Offset
+		}
+	}
+	return -1
+}
@@ -0,0 +1,126 @@
+package judge
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+	"github.com/afman42/go-web-code-interactive/internal/protocol"
+)
+
+func signatureProblem() problem.Problem {
+	return problem.Problem{
+		ID:        "inc",
+		Function:  "inc",
+		Signature: "inc(n: int) -> int",
+		Tests:     []problem.TestCase{{Args: []json.RawMessage{json.RawMessage("1")}, Expected: json.RawMessage("2")}},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	p := signatureProblem()
+	testCases := []struct {
+		language string
+		code     string
+		files    []string
+		entry    string
+		stored   string
+	}{
+		{"node", "function inc(n) { return n + 1 }", []string{"solution.js", "stq_driver.js", "stq_main.js"}, "stq_main.js", ""},
+		{"php", "<?php function inc($n) { return $n + 1; }", []string{"solution.php", "stq_main.php"}, "stq_main.php", ""},
+		{"go", "package main\n\nfunc inc(n int) int { return n + 1 }\n", []string{"solution.go", "stq_driver.go"}, "solution.go", ""},
+		// The driver declares main, the user's is renamed in place
+		{"go", "package main\n\nfunc inc(n int) int { return n + 1 }\n\nfunc main() {}\n", []string{"solution.go", "stq_driver.go"}, "solution.go",
+			"package main\n\nfunc inc(n int) int { return n + 1 }\n\nfunc _stq() {}\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.language, func(t *testing.T) {
+			prog, err := Build(p, tc.language, tc.code, p.Tests[0])
+			if err != nil {
+				t.Fatalf("Build failed: %v", err)
+			}
+			if got := strings.Join(prog.Names(), ","); got != strings.Join(tc.files, ",") {
+				t.Errorf("Expected files %v, but got %s", tc.files, got)
+			}
+			if prog.Entry != tc.entry {
+				t.Errorf("Expected entry %s, but got %s", tc.entry, prog.Entry)
+			}
+			stored := tc.stored
+			if stored == "" {
+				stored = tc.code
+			}
+			if prog.Files[prog.Names()[0]] != stored {
+				t.Errorf("Expected the user's code to be stored as %q, but got %q", stored, prog.Files[prog.Names()[0]])
+			}
+		})
+	}
+}
+
+// TestBuildRun runs built programs to check that the harness finds the
+// user's function and errors, reported on stderr or as error records, keep
+// the user's line numbers
+func TestBuildRun(t *testing.T) {
+	p := signatureProblem()
+	testCases := []struct {
+		language string
+		code     string
+		stderr   string
+	}{
+		{"node", "const inc = (n) => n + 1;\n", ""},
+		{"node", "function inc(n) {\n  return n.missing.field;\n}\n", "solution.js:2"},
+		{"go", "package main\n\nfunc inc(n int) int { return n + 1 }\n\nfunc main() { panic(\"not called\") }\n", ""},
+		{"go", "package main\n\nfunc inc(n int) int {\n\treturn undefinedName\n}\n", "solution.go:4"},
+		// The tests run after the user's init functions
+		{"go", "package main\n\nvar step int\n\nfunc init() { step = 1 }\n\nfunc inc(n int) int { return n + step }\n", ""},
+		{"go", "package main\n\nvar step int\n\nfunc init() { step = 1 }\n\nfunc inc(n int) int { return n + step }\n\nfunc main() {}\n", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.language, func(t *testing.T) {
+			if _, err := exec.LookPath(tc.language); err != nil {
+				t.Skipf("%s is not installed", tc.language)
+			}
+			prog, err := Build(p, tc.language, tc.code, p.Tests[0])
+			if err != nil {
+				t.Fatalf("Build failed: %v", err)
+			}
+			dir := t.TempDir()
+			args := []string{filepath.Join(dir, prog.Entry)}
+			if tc.language == "go" {
+				args = []string{"run"}
+			}
+			for _, name := range prog.Names() {
+				os.WriteFile(filepath.Join(dir, name), []byte(prog.Files[name]), 0o644)
+				if tc.language == "go" && name != prog.Entry {
+					args = append(args, filepath.Join(dir, name))
+				}
+			}
+			// Built the way the server builds it, the entry last
+			if tc.language == "go" {
+				args = append(args, filepath.Join(dir, prog.Entry))
+			}
+			var stderr strings.Builder
+			results := filepath.Join(dir, protocol.ResultsFile)
+			tests := filepath.Join(dir, protocol.TestsFile)
+			input, _ := protocol.EncodeInputs([]protocol.Input{{Test: 0, Args: p.Tests[0].Args}})
+			os.WriteFile(tests, input, 0o644)
+			cmd := exec.Command(tc.language, args...)
+			cmd.Env = append(os.Environ(), protocol.ResultsEnv+"="+results, protocol.TestsEnv+"="+tests)
+			cmd.Stderr = &stderr
+			cmd.Run()
+
+			data, _ := os.ReadFile(results)
+			if tc.stderr == "" {
+				records, err := protocol.Parse(data)
+				if err != nil || len(records) != 1 || string(records[0].Value) != "2" {
+					t.Errorf("Expected result 2, but got %q, %v, stderr %q", data, err, stderr.String())
+				}
+			} else if !strings.Contains(stderr.String()+string(data), tc.stderr) {
+				t.Errorf("Expected error at %s, but got %q, %q", tc.stderr, stderr.String(), data)
+			}
+		})
+	}
+}
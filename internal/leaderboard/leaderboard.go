@@ -0,0 +1,350 @@
+// Package leaderboard ranks graded STQ submissions. A Board keeps every
+// client's best submission per problem in rank order and folds each new
+// submission in as it arrives, so rankings never rescan the submission
+// store; filtered views are computed from the board's own entries.
+package leaderboard
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/submission"
+)
+
+// DefaultLimit is how many rows a ranking returns unless it asks for fewer
+const DefaultLimit = 100
+
+// Entry is a graded submission as the board ranks it
+type Entry struct {
+	SubmissionID string
+	Owner        string
+	ProblemID    string
+	Language     string
+	Passed       int
+	Total        int
+	Score        int
+	// DurationMs is the runtime of the judged tests, see judge.Result
+	DurationMs int64
+	Created    time.Time
+}
+
+// solved reports whether the entry passed every test
+func (e Entry) solved() bool {
+	return e.Passed == e.Total
+}
+
+// better reports whether a ranks above b: by pass rate, then runtime, then
+// the earlier submission
+func better(a, b Entry) bool {
+	if x, y := a.Passed*b.Total, b.Passed*a.Total; x != y {
+		return x > y
+	}
+	if a.DurationMs != b.DurationMs {
+		return a.DurationMs < b.DurationMs
+	}
+	return a.Created.Before(b.Created)
+}
+
+// Standing is a client's row in a problem's leaderboard
+type Standing struct {
+	Rank         int       `json:"rank"`
+	Owner        string    `json:"-"`
+	Language     string    `json:"lang"`
+	SubmissionID string    `json:"submissionId"`
+	Passed       int       `json:"passed"`
+	Total        int       `json:"total"`
+	PassRate     float64   `json:"passRate"`
+	DurationMs   int64     `json:"durationMs"`
+	Created      time.Time `json:"created"`
+}
+
+// Ranking is a client's row in the global leaderboard, totalled over the
+// best submission to each problem
+type Ranking struct {
+	Rank  int    `json:"rank"`
+	Owner string `json:"-"`
+	// Solved counts the problems passed in full, Attempted every problem
+	// with a graded submission
+	Solved    int `json:"solved"`
+	Attempted int `json:"attempted"`
+	// Score sums the scores out of 100
+	Score      int   `json:"score"`
+	DurationMs int64 `json:"durationMs"`
+	// LastImproved is when the latest of the counted submissions was made
+	LastImproved time.Time `json:"lastImproved"`
+}
+
+// above reports whether a ranks above b: by problems solved, then score,
+// then total runtime, then who got there first
+func (a Ranking) above(b Ranking) bool {
+	if a.Solved != b.Solved {
+		return a.Solved > b.Solved
+	}
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	if a.DurationMs != b.DurationMs {
+		return a.DurationMs < b.DurationMs
+	}
+	return a.LastImproved.Before(b.LastImproved)
+}
+
+// total sums the best entries of one client
+func total(owner string, best map[string]Entry) Ranking {
+	r := Ranking{Owner: owner}
+	for _, e := range best {
+		r.Attempted++
+		if e.solved() {
+			r.Solved++
+		}
+		r.Score += e.Score
+		r.DurationMs += e.DurationMs
+		if e.Created.After(r.LastImproved) {
+			r.LastImproved = e.Created
+		}
+	}
+	return r
+}
+
+// Filter narrows a ranking to a language and a time window. Only the
+// submissions passing it count.
+type Filter struct {
+	Language string
+	// Since and Until bound the submission time, zero values are unbounded
+	Since time.Time
+	Until time.Time
+	Limit int
+}
+
+// all reports whether the filter lets every submission through
+func (f Filter) all() bool {
+	return f.Language == "" && f.Since.IsZero() && f.Until.IsZero()
+}
+
+func (f Filter) match(e Entry) bool {
+	return (f.Language == "" || e.Language == f.Language) &&
+		(f.Since.IsZero() || !e.Created.Before(f.Since)) &&
+		(f.Until.IsZero() || e.Created.Before(f.Until))
+}
+
+func (f Filter) limit(n int) int {
+	if f.Limit <= 0 {
+		return min(n, DefaultLimit)
+	}
+	return min(n, f.Limit)
+}
+
+// Board holds the leaderboards of every problem
+type Board struct {
+	mu sync.RWMutex
+	// entries holds every graded submission per problem, for filtered views
+	entries map[string][]Entry
+	// ranked holds the best entry of each client per problem, best first
+	ranked map[string][]Entry
+	// best indexes the best entries by client, then problem
+	best map[string]map[string]Entry
+	// totals holds the global row of each client
+	totals map[string]Ranking
+	// failures counts the rejected submissions by client, then problem
+	failures map[string]map[string]int
+	// held are entries that only count from a later time on
+	held []held
+}
+
+// held is an entry kept off the board until its time comes
+type held struct {
+	entry Entry
+	after time.Time
+}
+
+// New creates an empty board
+func New() *Board {
+	return &Board{
+		entries:  make(map[string][]Entry),
+		ranked:   make(map[string][]Entry),
+		best:     make(map[string]map[string]Entry),
+		totals:   make(map[string]Ranking),
+		failures: make(map[string]map[string]int),
+	}
+}
+
+// Add folds a submission into the rankings. Only submissions graded on every
+// test of a problem (mode "submit") count; Add reports whether it did.
+func (b *Board) Add(s submission.Submission) bool {
+	return b.AddAfter(s, time.Time{})
+}
+
+// AddAfter is Add for a submission that only counts from the given time on,
+// such as one made during a contest, which must not show before the contest
+// ends. Until then it is held back from every ranking.
+func (b *Board) AddAfter(s submission.Submission, after time.Time) bool {
+	if s.ProblemID == "" || s.Mode != "submit" || s.Total == 0 {
+		return false
+	}
+	e := Entry{
+		SubmissionID: s.ID,
+		Owner:        s.Owner,
+		ProblemID:    s.ProblemID,
+		Language:     s.Language,
+		Passed:       s.Passed,
+		Total:        s.Total,
+		Score:        s.Score,
+		Created:      s.Created,
+	}
+	// The submission's own duration is the whole request's, builds and
+	// warm-up included
+	if s.Result != nil {
+		e.DurationMs = s.Result.DurationMs
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if time.Now().Before(after) {
+		b.held = append(b.held, held{entry: e, after: after})
+		return true
+	}
+	b.add(e)
+	return true
+}
+
+// add folds an entry into the rankings, b.mu must be held
+func (b *Board) add(e Entry) {
+	b.entries[e.ProblemID] = append(b.entries[e.ProblemID], e)
+	if !e.solved() {
+		if b.failures[e.Owner] == nil {
+			b.failures[e.Owner] = make(map[string]int)
+		}
+		b.failures[e.Owner][e.ProblemID]++
+	}
+	best := b.best[e.Owner]
+	if best == nil {
+		best = make(map[string]Entry)
+		b.best[e.Owner] = best
+	}
+	ranked := b.ranked[e.ProblemID]
+	if old, ok := best[e.ProblemID]; ok {
+		if !better(e, old) {
+			return
+		}
+		i := slices.IndexFunc(ranked, func(r Entry) bool { return r.Owner == e.Owner })
+		ranked = slices.Delete(ranked, i, i+1)
+	}
+	i := sort.Search(len(ranked), func(i int) bool { return better(e, ranked[i]) })
+	b.ranked[e.ProblemID] = slices.Insert(ranked, i, e)
+	best[e.ProblemID] = e
+	b.totals[e.Owner] = total(e.Owner, best)
+}
+
+// release folds in the held entries whose time has come, it runs before
+// every read
+func (b *Board) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.held = slices.DeleteFunc(b.held, func(h held) bool {
+		if now.Before(h.after) {
+			return false
+		}
+		b.add(h.entry)
+		return true
+	})
+}
+
+// Progress reports, per problem a client made a graded submission to,
+// whether the client solved it
+func (b *Board) Progress(owner string) map[string]bool {
+	b.release()
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	progress := make(map[string]bool, len(b.best[owner]))
+	for id, e := range b.best[owner] {
+		progress[id] = e.solved()
+	}
+	return progress
+}
+
+// Failures counts a client's graded submissions to a problem that failed a
+// test
+func (b *Board) Failures(owner, problemID string) int {
+	b.release()
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.failures[owner][problemID]
+}
+
+// Problem returns the leaderboard of a problem, best first
+func (b *Board) Problem(id string, f Filter) []Standing {
+	b.release()
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	ranked := b.ranked[id]
+	if !f.all() {
+		ranked = []Entry{}
+		for _, e := range bestOf(b.entries[id], f) {
+			ranked = append(ranked, e)
+		}
+		sort.Slice(ranked, func(i, j int) bool { return better(ranked[i], ranked[j]) })
+	}
+	list := []Standing{}
+	for i, e := range ranked[:f.limit(len(ranked))] {
+		list = append(list, Standing{
+			Rank:         i + 1,
+			Owner:        e.Owner,
+			Language:     e.Language,
+			SubmissionID: e.SubmissionID,
+			Passed:       e.Passed,
+			Total:        e.Total,
+			PassRate:     float64(e.Passed) / float64(e.Total),
+			DurationMs:   e.DurationMs,
+			Created:      e.Created,
+		})
+	}
+	return list
+}
+
+// Global returns the ranking across every problem, best first
+func (b *Board) Global(f Filter) []Ranking {
+	b.release()
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	list := []Ranking{}
+	if f.all() {
+		for _, r := range b.totals {
+			list = append(list, r)
+		}
+	} else {
+		best := make(map[string]map[string]Entry)
+		for id, entries := range b.entries {
+			for owner, e := range bestOf(entries, f) {
+				if best[owner] == nil {
+					best[owner] = make(map[string]Entry)
+				}
+				best[owner][id] = e
+			}
+		}
+		for owner, entries := range best {
+			list = append(list, total(owner, entries))
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].above(list[j]) })
+	list = list[:f.limit(len(list))]
+	for i := range list {
+		list[i].Rank = i + 1
+	}
+	return list
+}
+
+// bestOf returns the best entry of each client among those passing f
+func bestOf(entries []Entry, f Filter) map[string]Entry {
+	best := make(map[string]Entry)
+	for _, e := range entries {
+		if !f.match(e) {
+			continue
+		}
+		if old, ok := best[e.Owner]; !ok || better(e, old) {
+			best[e.Owner] = e
+		}
+	}
+	return best
+}
@@ -0,0 +1,186 @@
+package leaderboard
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/judge"
+	"github.com/afman42/go-web-code-interactive/internal/submission"
+)
+
+var base = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// graded returns a submit-mode submission made minutes after base
+func graded(owner, problem, lang string, passed, total int, durationMs int64, minutes int) submission.Submission {
+	return submission.Submission{
+		ID:        owner + "-" + problem + "-" + strconv.Itoa(minutes),
+		Owner:     owner,
+		ProblemID: problem,
+		Language:  lang,
+		Mode:      "submit",
+		Passed:    passed,
+		Total:     total,
+		Score:     passed * 100 / total,
+		Result:    &judge.Result{DurationMs: durationMs},
+		Created:   base.Add(time.Duration(minutes) * time.Minute),
+	}
+}
+
+func owners[T any](rows []T, owner func(T) string) []string {
+	list := []string{}
+	for _, r := range rows {
+		list = append(list, owner(r))
+	}
+	return list
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAdd(t *testing.T) {
+	b := New()
+	if b.Add(submission.Submission{Owner: "a", ProblemID: "sum", Mode: "run", Passed: 1, Total: 1}) {
+		t.Error("Expected a run of the samples not to count")
+	}
+	if b.Add(submission.Submission{Owner: "a", Mode: "submit"}) {
+		t.Error("Expected a submission without a problem not to count")
+	}
+	if !b.Add(graded("a", "sum", "go", 1, 2, 10, 0)) {
+		t.Error("Expected a graded submission to count")
+	}
+	if got := b.Problem("sum", Filter{}); len(got) != 1 || got[0].PassRate != 0.5 || got[0].Rank != 1 {
+		t.Errorf("Expected a's half passing submission, but got %+v", got)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	b := New()
+	b.Add(graded("a", "sum", "go", 1, 2, 10, 0))
+	b.Add(graded("a", "max", "go", 1, 2, 10, 1))
+	b.Add(graded("a", "max", "go", 2, 2, 10, 2))
+	if got := b.Progress("a"); len(got) != 2 || got["sum"] || !got["max"] {
+		t.Errorf("Expected sum attempted and max solved, but got %v", got)
+	}
+	if got := b.Progress("b"); len(got) != 0 {
+		t.Errorf("Expected no progress, but got %v", got)
+	}
+	if got := b.Failures("a", "max"); got != 1 {
+		t.Errorf("Expected 1 failure, but got %d", got)
+	}
+	if got := b.Failures("b", "max"); got != 0 {
+		t.Errorf("Expected no failures, but got %d", got)
+	}
+}
+
+func TestProblem(t *testing.T) {
+	b := New()
+	for _, s := range []submission.Submission{
+		graded("a", "sum", "go", 2, 2, 50, 0),
+		graded("b", "sum", "node", 2, 2, 20, 1),
+		graded("c", "sum", "node", 1, 2, 5, 2),
+		graded("d", "sum", "php", 2, 2, 20, 3),
+		// Worse than a's earlier submission, so it does not replace it
+		graded("a", "sum", "node", 1, 2, 1, 4),
+		// c improves and jumps ahead of everyone but b
+		graded("c", "sum", "php", 4, 4, 20, 5),
+	} {
+		b.Add(s)
+	}
+
+	testCases := []struct {
+		name   string
+		filter Filter
+		owners []string
+	}{
+		{"pass rate, runtime then time", Filter{}, []string{"b", "d", "c", "a"}},
+		{"language", Filter{Language: "node"}, []string{"b", "a", "c"}},
+		{"time window", Filter{Since: base.Add(time.Minute), Until: base.Add(5 * time.Minute)}, []string{"b", "d", "a", "c"}},
+		{"window before improvement", Filter{Until: base.Add(3 * time.Minute)}, []string{"b", "a", "c"}},
+		{"limit", Filter{Limit: 2}, []string{"b", "d"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := owners(b.Problem("sum", tc.filter), func(s Standing) string { return s.Owner })
+			if !equal(got, tc.owners) {
+				t.Errorf("Expected %v, but got %v", tc.owners, got)
+			}
+		})
+	}
+
+	got := b.Problem("sum", Filter{})
+	if got[2].Language != "php" || got[2].SubmissionID != "c-sum-5" || got[2].Rank != 3 {
+		t.Errorf("Expected c's improved submission at rank 3, but got %+v", got[2])
+	}
+	if got := b.Problem("missing", Filter{}); len(got) != 0 {
+		t.Errorf("Expected an empty leaderboard, but got %+v", got)
+	}
+}
+
+func TestGlobal(t *testing.T) {
+	b := New()
+	for _, s := range []submission.Submission{
+		graded("a", "sum", "go", 2, 2, 50, 0),
+		graded("a", "max", "go", 1, 2, 50, 1),
+		graded("b", "sum", "node", 2, 2, 10, 2),
+		graded("b", "max", "node", 2, 2, 10, 3),
+		graded("c", "sum", "node", 2, 2, 10, 4),
+		graded("c", "max", "go", 1, 2, 10, 5),
+	} {
+		b.Add(s)
+	}
+
+	got := b.Global(Filter{})
+	if want := []string{"b", "c", "a"}; !equal(owners(got, func(r Ranking) string { return r.Owner }), want) {
+		t.Fatalf("Expected %v, but got %+v", want, got)
+	}
+	if got[0].Solved != 2 || got[0].Attempted != 2 || got[0].Score != 200 || got[0].DurationMs != 20 || !got[0].LastImproved.Equal(base.Add(3*time.Minute)) {
+		t.Errorf("Expected b's totals, but got %+v", got[0])
+	}
+
+	// a solves max too and overtakes c
+	b.Add(graded("a", "max", "go", 2, 2, 50, 6))
+	got = b.Global(Filter{})
+	if want := []string{"b", "a", "c"}; !equal(owners(got, func(r Ranking) string { return r.Owner }), want) {
+		t.Errorf("Expected %v after the improvement, but got %+v", want, got)
+	}
+
+	got = b.Global(Filter{Language: "go"})
+	if want := []string{"a", "c"}; !equal(owners(got, func(r Ranking) string { return r.Owner }), want) {
+		t.Errorf("Expected %v for go, but got %+v", want, got)
+	}
+	if got[0].Solved != 2 || got[1].Solved != 0 || got[1].Score != 50 {
+		t.Errorf("Expected only go submissions to count, but got %+v", got)
+	}
+	got = b.Global(Filter{Since: base.Add(4 * time.Minute), Limit: 1})
+	if len(got) != 1 || got[0].Owner != "c" || got[0].Attempted != 2 || got[0].Score != 150 {
+		t.Errorf("Expected c on top of the window, but got %+v", got)
+	}
+}
+
+func TestAddAfter(t *testing.T) {
+	b := New()
+	b.Add(graded("a", "sum", "go", 1, 2, 10, 0))
+	if !b.AddAfter(graded("b", "sum", "go", 2, 2, 10, 1), time.Now().Add(50*time.Millisecond)) {
+		t.Fatal("Expected a held submission to count")
+	}
+	if got := owners(b.Problem("sum", Filter{}), func(s Standing) string { return s.Owner }); !equal(got, []string{"a"}) {
+		t.Errorf("Expected b's submission to be held back, but got %v", got)
+	}
+	if got := b.Progress("b"); len(got) != 0 {
+		t.Errorf("Expected no progress while held, but got %v", got)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if got := owners(b.Global(Filter{}), func(r Ranking) string { return r.Owner }); !equal(got, []string{"b", "a"}) {
+		t.Errorf("Expected b's submission once released, but got %v", got)
+	}
+}
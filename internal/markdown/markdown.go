@@ -0,0 +1,640 @@
+// Package markdown renders the Markdown used in problem statements to HTML:
+// headings, paragraphs, emphasis, code spans and fenced code blocks, lists,
+// blockquotes, links, images, GitHub style tables and TeX math between $
+// or $$ delimiters. Raw HTML is escaped rather than passed through and links
+// keep only safe URLs, so the output can be embedded in a page as is.
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	headingRe   = regexp.MustCompile(`^(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	listRe      = regexp.MustCompile(`^( {0,3})(?:([-*+])|(\d{1,9})[.)])(?:[ \t]+|$)`)
+	delimCellRe = regexp.MustCompile(`^:?-+:?$`)
+)
+
+// punctuation holds the characters a backslash escapes
+const punctuation = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+// Render converts Markdown to HTML
+func Render(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\t", "    ")
+	var b strings.Builder
+	renderBlocks(&b, strings.Split(src, "\n"), false)
+	return b.String()
+}
+
+// renderBlocks renders lines as a sequence of blocks. In a tight list item
+// paragraphs are written without <p> tags.
+func renderBlocks(b *strings.Builder, lines []string, tight bool) {
+	for i := 0; i < len(lines); {
+		trimmed := strings.TrimSpace(lines[i])
+		switch {
+		case trimmed == "":
+			i++
+		case fence(trimmed) != "":
+			i = codeBlock(b, lines, i)
+		case isMathBlock(trimmed):
+			i = mathBlock(b, lines, i)
+		case headingRe.MatchString(trimmed):
+			m := headingRe.FindStringSubmatch(trimmed)
+			fmt.Fprintf(b, "<h%d>%s</h%d>\n", len(m[1]), Inline(m[2]), len(m[1]))
+			i++
+		case isRule(trimmed):
+			b.WriteString("<hr>\n")
+			i++
+		case strings.HasPrefix(trimmed, ">"):
+			i = blockquote(b, lines, i)
+		case listRe.MatchString(lines[i]):
+			i = list(b, lines, i)
+		case isTable(lines, i):
+			i = table(b, lines, i)
+		default:
+			i = paragraph(b, lines, i, tight)
+		}
+	}
+}
+
+// interrupts reports whether a line starts a block that ends a paragraph
+func interrupts(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return fence(trimmed) != "" || isMathBlock(trimmed) || headingRe.MatchString(trimmed) ||
+		isRule(trimmed) || strings.HasPrefix(trimmed, ">") || listRe.MatchString(line)
+}
+
+func paragraph(b *strings.Builder, lines []string, i int, tight bool) int {
+	start := i
+	for i++; i < len(lines) && strings.TrimSpace(lines[i]) != "" && !interrupts(lines[i]); i++ {
+	}
+	text := make([]string, 0, i-start)
+	for _, line := range lines[start:i] {
+		text = append(text, strings.TrimLeft(line, " "))
+	}
+	content := Inline(strings.TrimRight(strings.Join(text, "\n"), " "))
+	if tight {
+		b.WriteString(content + "\n")
+	} else {
+		b.WriteString("<p>" + content + "</p>\n")
+	}
+	return i
+}
+
+// fence returns the opening fence of a fenced code block, or ""
+func fence(trimmed string) string {
+	for _, c := range []byte{'`', '~'} {
+		n := 0
+		for n < len(trimmed) && trimmed[n] == c {
+			n++
+		}
+		if n < 3 {
+			continue
+		}
+		if c == '`' && strings.ContainsRune(trimmed[n:], '`') {
+			return ""
+		}
+		return trimmed[:n]
+	}
+	return ""
+}
+
+func codeBlock(b *strings.Builder, lines []string, i int) int {
+	indent := len(lines[i]) - len(strings.TrimLeft(lines[i], " "))
+	trimmed := strings.TrimSpace(lines[i])
+	marker := fence(trimmed)
+	b.WriteString("<pre><code")
+	if info := strings.Fields(trimmed[len(marker):]); len(info) > 0 {
+		b.WriteString(` class="language-` + html.EscapeString(info[0]) + `"`)
+	}
+	b.WriteString(">")
+	for i++; i < len(lines); i++ {
+		closing := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(closing, marker) && strings.Trim(closing, marker[:1]) == "" {
+			i++
+			break
+		}
+		line := lines[i]
+		for n := 0; n < indent && strings.HasPrefix(line, " "); n++ {
+			line = line[1:]
+		}
+		b.WriteString(html.EscapeString(line) + "\n")
+	}
+	b.WriteString("</code></pre>\n")
+	return i
+}
+
+// isMathBlock reports whether a line opens display math: $$ alone, or a
+// line starting with $$ and ending with the closing $$
+func isMathBlock(trimmed string) bool {
+	if !strings.HasPrefix(trimmed, "$$") {
+		return false
+	}
+	rest := trimmed[2:]
+	j := strings.Index(rest, "$$")
+	return j == -1 || j > 0 && j == len(rest)-2
+}
+
+func mathBlock(b *strings.Builder, lines []string, i int) int {
+	tex := strings.TrimPrefix(strings.TrimSpace(lines[i]), "$$")
+	var body []string
+	if strings.HasSuffix(tex, "$$") {
+		body = append(body, strings.TrimSuffix(tex, "$$"))
+		i++
+	} else {
+		if strings.TrimSpace(tex) != "" {
+			body = append(body, tex)
+		}
+		for i++; i < len(lines); i++ {
+			line := strings.TrimSpace(lines[i])
+			if strings.HasSuffix(line, "$$") {
+				body = append(body, strings.TrimSuffix(line, "$$"))
+				i++
+				break
+			}
+			body = append(body, line)
+		}
+	}
+	b.WriteString(`<div class="math math-display">` + html.EscapeString(strings.TrimSpace(strings.Join(body, "\n"))) + "</div>\n")
+	return i
+}
+
+// isRule reports whether a line is a thematic break: three or more -, * or
+// _ and nothing else but spaces
+func isRule(trimmed string) bool {
+	if trimmed == "" || !strings.ContainsRune("-*_", rune(trimmed[0])) {
+		return false
+	}
+	s := strings.ReplaceAll(trimmed, " ", "")
+	return len(s) >= 3 && strings.Trim(s, s[:1]) == ""
+}
+
+func blockquote(b *strings.Builder, lines []string, i int) int {
+	var inner []string
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimLeft(lines[i], " ")
+		if !strings.HasPrefix(trimmed, ">") {
+			break
+		}
+		trimmed = strings.TrimPrefix(trimmed[1:], " ")
+		inner = append(inner, trimmed)
+	}
+	b.WriteString("<blockquote>\n")
+	renderBlocks(b, inner, false)
+	b.WriteString("</blockquote>\n")
+	return i
+}
+
+// listItem is the marker of a list item
+type listItem struct {
+	ordered bool
+	start   int
+	// width is the column the item's content starts at
+	width int
+}
+
+func parseItem(line string) (listItem, bool) {
+	m := listRe.FindStringSubmatch(line)
+	if m == nil {
+		return listItem{}, false
+	}
+	item := listItem{ordered: m[3] != "", width: len(m[0])}
+	if item.ordered {
+		item.start, _ = strconv.Atoi(m[3])
+	}
+	if strings.TrimSpace(line[len(m[0]):]) == "" {
+		// An empty item's content starts right after the marker
+		item.width = len(strings.TrimRight(m[0], " "))
+	}
+	return item, true
+}
+
+func list(b *strings.Builder, lines []string, i int) int {
+	first, _ := parseItem(lines[i])
+	var items [][]string
+	loose := false
+	for i < len(lines) {
+		item, ok := parseItem(lines[i])
+		if !ok || item.ordered != first.ordered {
+			break
+		}
+		body := []string{lines[i][item.width:]}
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			if strings.TrimSpace(line) == "" {
+				// A blank line continues the item only if indented
+				// content follows
+				j := i
+				for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+					j++
+				}
+				if j == len(lines) || indentOf(lines[j]) < item.width {
+					if j < len(lines) {
+						if next, ok := parseItem(lines[j]); ok && next.ordered == first.ordered {
+							loose = true
+						}
+					}
+					i = j
+					break
+				}
+				loose = true
+				body = append(body, "")
+				continue
+			}
+			if indentOf(line) >= item.width {
+				body = append(body, line[item.width:])
+				continue
+			}
+			if interrupts(line) {
+				break
+			}
+			// A lazy continuation of the item's paragraph
+			body = append(body, strings.TrimLeft(line, " "))
+		}
+		items = append(items, body)
+	}
+	tag := "ul"
+	if first.ordered {
+		tag = "ol"
+	}
+	if first.ordered && first.start != 1 {
+		fmt.Fprintf(b, "<ol start=\"%d\">\n", first.start)
+	} else {
+		b.WriteString("<" + tag + ">\n")
+	}
+	for _, body := range items {
+		var item strings.Builder
+		renderBlocks(&item, body, !loose)
+		content := item.String()
+		if loose {
+			content = "\n" + content
+		} else {
+			content = strings.TrimSuffix(content, "\n")
+		}
+		b.WriteString("<li>" + content + "</li>\n")
+	}
+	b.WriteString("</" + tag + ">\n")
+	return i
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// splitRow splits a table row into its cells. Pipes escaped with a
+// backslash or inside a code span do not split.
+func splitRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, "\\|") {
+		line = line[:len(line)-1]
+	}
+	var cells []string
+	start, code := 0, false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '`':
+			code = !code
+		case '|':
+			if !code {
+				cells = append(cells, strings.TrimSpace(line[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(cells, strings.TrimSpace(line[start:]))
+}
+
+// isTable reports whether a table starts at line i: a header row followed
+// by a delimiter row with as many cells
+func isTable(lines []string, i int) bool {
+	if i+1 >= len(lines) || !strings.Contains(lines[i], "|") || !strings.Contains(lines[i+1], "-") {
+		return false
+	}
+	delims := splitRow(lines[i+1])
+	for _, cell := range delims {
+		if !delimCellRe.MatchString(cell) {
+			return false
+		}
+	}
+	return len(delims) == len(splitRow(lines[i]))
+}
+
+func table(b *strings.Builder, lines []string, i int) int {
+	header := splitRow(lines[i])
+	var align []string
+	for _, cell := range splitRow(lines[i+1]) {
+		left, right := strings.HasPrefix(cell, ":"), strings.HasSuffix(cell, ":")
+		switch {
+		case left && right:
+			align = append(align, "center")
+		case right:
+			align = append(align, "right")
+		case left:
+			align = append(align, "left")
+		default:
+			align = append(align, "")
+		}
+	}
+	row := func(tag string, cells []string) {
+		b.WriteString("<tr>")
+		for j := range header {
+			cell := ""
+			if j < len(cells) {
+				cell = cells[j]
+			}
+			if align[j] != "" {
+				fmt.Fprintf(b, "<%s style=\"text-align:%s\">", tag, align[j])
+			} else {
+				b.WriteString("<" + tag + ">")
+			}
+			b.WriteString(Inline(cell) + "</" + tag + ">")
+		}
+		b.WriteString("</tr>\n")
+	}
+	b.WriteString("<table>\n<thead>\n")
+	row("th", header)
+	b.WriteString("</thead>\n")
+	i += 2
+	if i < len(lines) && strings.TrimSpace(lines[i]) != "" && strings.Contains(lines[i], "|") && !interrupts(lines[i]) {
+		b.WriteString("<tbody>\n")
+		for ; i < len(lines) && strings.TrimSpace(lines[i]) != "" && strings.Contains(lines[i], "|") && !interrupts(lines[i]); i++ {
+			row("td", splitRow(lines[i]))
+		}
+		b.WriteString("</tbody>\n")
+	}
+	b.WriteString("</table>\n")
+	return i
+}
+
+// Inline renders the inline Markdown of a single block
+func Inline(s string) string {
+	var b strings.Builder
+	inline(&b, s)
+	return b.String()
+}
+
+func inline(b *strings.Builder, s string) {
+	for i := 0; i < len(s); {
+		n := 0
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				b.WriteString("<br>\n")
+				n = 2
+			} else if i+1 < len(s) && strings.IndexByte(punctuation, s[i+1]) >= 0 {
+				b.WriteString(html.EscapeString(s[i+1 : i+2]))
+				n = 2
+			}
+		case '`':
+			n = codeSpan(b, s[i:])
+		case '$':
+			n = inlineMath(b, s[i:])
+		case '*', '_':
+			n = emphasis(b, s, i)
+		case '!':
+			if strings.HasPrefix(s[i+1:], "[") {
+				if n = link(b, s[i+1:], true); n > 0 {
+					n++
+				}
+			}
+		case '[':
+			n = link(b, s[i:], false)
+		case '<':
+			n = autolink(b, s[i:])
+		case ' ':
+			// Two or more trailing spaces make a hard line break
+			j := i
+			for j < len(s) && s[j] == ' ' {
+				j++
+			}
+			if j-i >= 2 && j < len(s) && s[j] == '\n' {
+				b.WriteString("<br>\n")
+				n = j + 1 - i
+			}
+		}
+		if n == 0 {
+			b.WriteString(html.EscapeString(s[i : i+1]))
+			n = 1
+		}
+		i += n
+	}
+}
+
+// codeSpan writes the code span s starts with, returning its length or 0
+func codeSpan(b *strings.Builder, s string) int {
+	n := len(s) - len(strings.TrimLeft(s, "`"))
+	for j := n; j < len(s); {
+		k := strings.Index(s[j:], s[:n])
+		if k < 0 {
+			break
+		}
+		k += j
+		end := k + n
+		for end < len(s) && s[end] == '`' {
+			end++
+		}
+		if end-k != n {
+			// A longer run of backticks does not close the span
+			j = end
+			continue
+		}
+		code := strings.ReplaceAll(s[n:k], "\n", " ")
+		if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+			code = code[1 : len(code)-1]
+		}
+		b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+		return end
+	}
+	b.WriteString(s[:n])
+	return n
+}
+
+// inlineMath writes the $...$ or $$...$$ math s starts with, returning its
+// length or 0. Like in Pandoc, the opening $ must be followed by a non-space
+// and the closing $ preceded by a non-space and not followed by a digit, so
+// prices such as $5 and $10 stay text.
+func inlineMath(b *strings.Builder, s string) int {
+	if strings.HasPrefix(s, "$$") {
+		end := strings.Index(s[2:], "$$")
+		if end <= 0 {
+			return 0
+		}
+		b.WriteString(`<span class="math math-display">` + html.EscapeString(strings.TrimSpace(s[2:2+end])) + "</span>")
+		return end + 4
+	}
+	if len(s) < 3 || s[1] == ' ' || s[1] == '$' {
+		return 0
+	}
+	for j := 2; j < len(s); j++ {
+		switch {
+		case s[j] == '\\':
+			j++
+		case s[j] == '$':
+			if s[j-1] == ' ' || j+1 < len(s) && s[j+1] >= '0' && s[j+1] <= '9' {
+				return 0
+			}
+			b.WriteString(`<span class="math math-inline">` + html.EscapeString(s[1:j]) + "</span>")
+			return j + 1
+		}
+	}
+	return 0
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// emphasis writes the *em*, _em_, **strong** or __strong__ starting at
+// s[i], returning its length or 0. Underscores inside words, as in
+// snake_case, do not emphasize.
+func emphasis(b *strings.Builder, s string, i int) int {
+	c := s[i]
+	n := 1
+	if strings.HasPrefix(s[i:], string([]byte{c, c})) {
+		n = 2
+	}
+	if c == '_' && i > 0 && isAlnum(s[i-1]) {
+		return 0
+	}
+	if i+n >= len(s) || s[i+n] == ' ' || s[i+n] == '\n' {
+		return 0
+	}
+	marker := s[i : i+n]
+	for j := i + n + 1; j+n <= len(s); j++ {
+		if s[j] == '`' {
+			// Delimiters inside a code span do not close
+			if k := strings.IndexByte(s[j+1:], '`'); k >= 0 {
+				j += k + 1
+			}
+			continue
+		}
+		if s[j:j+n] != marker || s[j-1] == ' ' || s[j-1] == '\n' {
+			continue
+		}
+		if n == 1 && j+1 < len(s) && s[j+1] == c {
+			// Skip the delimiters of nested strong emphasis
+			j++
+			continue
+		}
+		if c == '_' && j+n < len(s) && isAlnum(s[j+n]) {
+			continue
+		}
+		tag := "em"
+		if n == 2 {
+			tag = "strong"
+		}
+		b.WriteString("<" + tag + ">")
+		inline(b, s[i+n:j])
+		b.WriteString("</" + tag + ">")
+		return j + n - i
+	}
+	return 0
+}
+
+// link writes the [text](url) link or, for an image, the ![alt](url) image
+// s starts with (s begins at the "["), returning its length or 0. A link
+// with an unsafe URL is written as its text.
+func link(b *strings.Builder, s string, image bool) int {
+	depth, end := 0, -1
+	for j := 0; j < len(s) && end < 0; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			if depth--; depth == 0 {
+				end = j
+			}
+		}
+	}
+	if end < 0 || !strings.HasPrefix(s[end+1:], "(") {
+		return 0
+	}
+	// The destination may hold balanced parentheses
+	closing := -1
+	depth = 0
+	for j := end + 2; j < len(s) && closing < 0; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				closing = j
+			}
+			depth--
+		}
+	}
+	if closing < 0 {
+		return 0
+	}
+	text := s[1:end]
+	dest := ""
+	fields := strings.Fields(s[end+2 : closing])
+	if len(fields) > 0 {
+		dest = strings.TrimSuffix(strings.TrimPrefix(fields[0], "<"), ">")
+	}
+	if len(fields) > 1 && !strings.HasPrefix(fields[1], "\"") && !strings.HasPrefix(fields[1], "'") {
+		// Anything after the destination but a title is not a link
+		return 0
+	}
+	length := closing + 1
+	switch {
+	case image && safeURL(dest, false):
+		b.WriteString(`<img src="` + html.EscapeString(dest) + `" alt="` + html.EscapeString(text) + `">`)
+	case image:
+		b.WriteString(html.EscapeString(text))
+	case safeURL(dest, true):
+		b.WriteString(`<a href="` + html.EscapeString(dest) + `" rel="nofollow noopener">`)
+		inline(b, text)
+		b.WriteString("</a>")
+	default:
+		inline(b, text)
+	}
+	return length
+}
+
+// autolink writes the <https://...> link s starts with, returning its
+// length or 0. Any other < is escaped like the rest of the text.
+func autolink(b *strings.Builder, s string) int {
+	end := strings.IndexAny(s, "> \n")
+	if end < 0 || s[end] != '>' {
+		return 0
+	}
+	url := s[1:end]
+	lower := strings.ToLower(url)
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") || !safeURL(url, false) {
+		return 0
+	}
+	b.WriteString(`<a href="` + html.EscapeString(url) + `" rel="nofollow noopener">` + html.EscapeString(url) + "</a>")
+	return end + 1
+}
+
+// safeURL reports whether a URL may be linked: relative URLs and http(s)
+// ones, and mailto for links
+func safeURL(url string, mailto bool) bool {
+	if url == "" || strings.ContainsFunc(url, func(r rune) bool { return r < ' ' || r == 0x7f }) {
+		return false
+	}
+	i := strings.IndexAny(url, ":/?#")
+	if i < 0 || url[i] != ':' {
+		return true
+	}
+	switch strings.ToLower(url[:i]) {
+	case "http", "https":
+		return true
+	case "mailto":
+		return mailto
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package markdown
+
+import (
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"Heading", "## Two *Sum*", "<h2>Two <em>Sum</em></h2>\n"},
+		{"Paragraphs", "Write a **function**\nthat `adds <a>`.\n\nDone_ok snake_case_name.",
+			"<p>Write a <strong>function</strong>\nthat <code>adds &lt;a&gt;</code>.</p>\n<p>Done_ok snake_case_name.</p>\n"},
+		{"Hard break", "one  \ntwo\\\nthree", "<p>one<br>\ntwo<br>\nthree</p>\n"},
+		{"Code block", "```go\nfunc f() {\n\treturn \"<b>\"\n}\n```",
+			"<pre><code class=\"language-go\">func f() {\n    return &#34;&lt;b&gt;&#34;\n}\n</code></pre>\n"},
+		{"Unclosed code block", "~~~\nx", "<pre><code>x\n</code></pre>\n"},
+		{"Inline math", "Return $a_i + b_i$ for $1 \\le i < n$, costs $5 or $10.",
+			"<p>Return <span class=\"math math-inline\">a_i + b_i</span> for <span class=\"math math-inline\">1 \\le i &lt; n</span>, costs $5 or $10.</p>\n"},
+		{"Display math", "$$\n\\sum_{i=1}^n i\n$$\n\n$$x^2$$",
+			"<div class=\"math math-display\">\\sum_{i=1}^n i</div>\n<div class=\"math math-display\">x^2</div>\n"},
+		{"Table", "| n | `a|b` | result |\n|:--|:-:|--:|\n| 1 | x \\| y | **ok** |\n| 2 |",
+			"<table>\n<thead>\n<tr><th style=\"text-align:left\">n</th><th style=\"text-align:center\"><code>a|b</code></th><th style=\"text-align:right\">result</th></tr>\n</thead>\n<tbody>\n" +
+				"<tr><td style=\"text-align:left\">1</td><td style=\"text-align:center\">x | y</td><td style=\"text-align:right\"><strong>ok</strong></td></tr>\n" +
+				"<tr><td style=\"text-align:left\">2</td><td style=\"text-align:center\"></td><td style=\"text-align:right\"></td></tr>\n</tbody>\n</table>\n"},
+		{"Tight list", "- one\n- two\n  - nested\n- three", "<ul>\n<li>one</li>\n<li>two\n<ul>\n<li>nested</li>\n</ul></li>\n<li>three</li>\n</ul>\n"},
+		{"Loose list", "3. one\n\n4. two", "<ol start=\"3\">\n<li>\n<p>one</p>\n</li>\n<li>\n<p>two</p>\n</li>\n</ol>\n"},
+		{"Blockquote", "> Note:\n> *n* fits", "<blockquote>\n<p>Note:\n<em>n</em> fits</p>\n</blockquote>\n"},
+		{"Rule", "a\n\n---\n* * *", "<p>a</p>\n<hr>\n<hr>\n"},
+		{"Links", "[docs](https://go.dev/doc) [rel](/problems?tag=dp) <https://x.io> [mail](mailto:a@b.c)",
+			"<p><a href=\"https://go.dev/doc\" rel=\"nofollow noopener\">docs</a> <a href=\"/problems?tag=dp\" rel=\"nofollow noopener\">rel</a> " +
+				"<a href=\"https://x.io\" rel=\"nofollow noopener\">https://x.io</a> <a href=\"mailto:a@b.c\" rel=\"nofollow noopener\">mail</a></p>\n"},
+		{"Image", "![a \"tree\"](tree.png)", "<p><img src=\"tree.png\" alt=\"a &#34;tree&#34;\"></p>\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Render(tc.src); got != tc.want {
+				t.Errorf("Expected %q, but got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRenderSanitizes(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"Raw HTML", "<script>alert(1)</script><img src=x onerror=alert(1)>",
+			"<p>&lt;script&gt;alert(1)&lt;/script&gt;&lt;img src=x onerror=alert(1)&gt;</p>\n"},
+		{"Script link", "[click](javascript:alert(1))", "<p>click</p>\n"},
+		{"Obfuscated scheme", "[click](JavaScript:alert(1)) [x](java\tscript:alert(1))", "<p>click [x](java    script:alert(1))</p>\n"},
+		{"Data image", "![x](data:image/svg+xml;base64,PHN2Zz4=)", "<p>x</p>\n"},
+		{"Mailto image", "![x](mailto:a@b.c)", "<p>x</p>\n"},
+		{"Attribute breakout", "[x](https://a.io/\"onmouseover=\"alert(1))", "<p><a href=\"https://a.io/&#34;onmouseover=&#34;alert(1)\" rel=\"nofollow noopener\">x</a></p>\n"},
+		{"Code language", "```\"><script>\nx\n```", "<pre><code class=\"language-&#34;&gt;&lt;script&gt;\">x\n</code></pre>\n"},
+		{"Autolink", "<javascript:alert(1)>", "<p>&lt;javascript:alert(1)&gt;</p>\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Render(tc.src); got != tc.want {
+				t.Errorf("Expected %q, but got %q", tc.want, got)
+			}
+		})
+	}
+}
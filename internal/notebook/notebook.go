@@ -0,0 +1,84 @@
+package notebook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// Cell types
+const (
+	CellCode     = "code"
+	CellMarkdown = "markdown"
+)
+
+var (
+	ErrNotFound     = errors.New("notebook not found")
+	ErrCellNotFound = errors.New("cell not found")
+	ErrInvalidCell  = errors.New("cell type must be code or markdown")
+)
+
+// Output is the result of executing one code cell
+type Output struct {
+	Stdout     string `json:"out"`
+	Stderr     string `json:"errout"`
+	Value      string `json:"value,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// Cell is a code or markdown block of a notebook
+type Cell struct {
+	ID             string  `json:"id"`
+	Type           string  `json:"type"`
+	Source         string  `json:"source"`
+	Output         *Output `json:"output,omitempty"`
+	ExecutionCount int     `json:"executionCount,omitempty"`
+}
+
+// Notebook is an ordered document of cells executed against one session
+type Notebook struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Language  string    `json:"lang"`
+	Cells     []Cell    `json:"cells"`
+	SessionID string    `json:"sessionId,omitempty"`
+	Executed  int       `json:"executed"`
+	Created   time.Time `json:"created"`
+	Updated   time.Time `json:"updated"`
+	// Owner is the client that created the notebook, saved with it but never
+	// served
+	Owner string `json:"-"`
+}
+
+// Normalize assigns IDs to new cells and checks cell types
+func (nb *Notebook) Normalize() error {
+	for i := range nb.Cells {
+		if nb.Cells[i].Type == "" {
+			nb.Cells[i].Type = CellCode
+		}
+		if nb.Cells[i].Type != CellCode && nb.Cells[i].Type != CellMarkdown {
+			return ErrInvalidCell
+		}
+		if nb.Cells[i].ID == "" {
+			nb.Cells[i].ID = newID()
+		}
+	}
+	return nil
+}
+
+func (nb *Notebook) cell(id string) (*Cell, error) {
+	for i := range nb.Cells {
+		if nb.Cells[i].ID == id {
+			return &nb.Cells[i], nil
+		}
+	}
+	return nil, ErrCellNotFound
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
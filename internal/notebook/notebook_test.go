@@ -0,0 +1,206 @@
+package notebook
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeKernel records evaluated code per session and fails on "fail"
+type fakeKernel struct {
+	started  int
+	stopped  []string
+	sessions map[string][]string
+}
+
+func newFakeKernel() *fakeKernel {
+	return &fakeKernel{sessions: make(map[string][]string)}
+}
+
+func (k *fakeKernel) Start(language string) (string, error) {
+	k.started++
+	id := language + "-" + strings.Repeat("x", k.started)
+	k.sessions[id] = nil
+	return id, nil
+}
+
+func (k *fakeKernel) Eval(sessionID, code string) (Output, error) {
+	history, ok := k.sessions[sessionID]
+	if !ok {
+		return Output{}, ErrKernelGone
+	}
+	k.sessions[sessionID] = append(history, code)
+	if code == "fail" {
+		return Output{Error: "failed"}, nil
+	}
+	return Output{Stdout: code + "\n"}, nil
+}
+
+func (k *fakeKernel) Stop(sessionID string) error {
+	k.stopped = append(k.stopped, sessionID)
+	delete(k.sessions, sessionID)
+	return nil
+}
+
+func newTestNotebook(t *testing.T, cells ...Cell) (*Runner, Notebook) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	nb, err := store.Create(Notebook{Title: "Tutorial", Language: "node", Cells: cells})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	return NewRunner(store), nb
+}
+
+func TestStore_CRUD(t *testing.T) {
+	runner, nb := newTestNotebook(t, Cell{Source: "a"}, Cell{Type: CellMarkdown, Source: "# Intro"})
+	store := runner.Store()
+
+	if nb.ID == "" || nb.Cells[0].ID == "" || nb.Cells[0].Type != CellCode {
+		t.Errorf("Expected IDs and default cell type to be assigned, got %+v", nb)
+	}
+
+	got, err := store.Get(nb.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got.Title != "Tutorial" || len(got.Cells) != 2 {
+		t.Errorf("Unexpected notebook: %+v", got)
+	}
+
+	list, err := store.List()
+	if err != nil || len(list) != 1 {
+		t.Errorf("Expected one notebook in list, got %d (%v)", len(list), err)
+	}
+
+	if _, err := store.Create(Notebook{Cells: []Cell{{Type: "raw"}}}); err != ErrInvalidCell {
+		t.Errorf("Expected ErrInvalidCell, got: %v", err)
+	}
+
+	if err := store.Delete(nb.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Get(nb.ID); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound after delete, got: %v", err)
+	}
+}
+
+func TestRunner_RunAllInOrder(t *testing.T) {
+	runner, nb := newTestNotebook(t,
+		Cell{Source: "one"},
+		Cell{Type: CellMarkdown, Source: "text"},
+		Cell{Source: "two"},
+		Cell{Source: "fail"},
+		Cell{Source: "never"},
+	)
+	k := newFakeKernel()
+
+	nb, err := runner.RunAll(k, nb.ID)
+	if err != nil {
+		t.Fatalf("RunAll failed: %v", err)
+	}
+	if k.started != 1 {
+		t.Errorf("Expected one session, got %d", k.started)
+	}
+	history := k.sessions[nb.SessionID]
+	if strings.Join(history, ",") != "one,two,fail" {
+		t.Errorf("Expected cells to run in order and stop at failure, got %v", history)
+	}
+	if nb.Cells[0].Output.Stdout != "one\n" || nb.Cells[0].ExecutionCount != 1 {
+		t.Errorf("Unexpected first cell: %+v", nb.Cells[0])
+	}
+	if nb.Cells[1].Output != nil || nb.Cells[4].Output != nil {
+		t.Error("Expected markdown and unreached cells to have no output")
+	}
+
+	// Outputs are persisted with the document
+	stored, _ := runner.Store().Get(nb.ID)
+	if stored.Cells[3].Output == nil || stored.Cells[3].Output.Error != "failed" {
+		t.Errorf("Expected failing output to be stored, got %+v", stored.Cells[3])
+	}
+}
+
+func TestRunner_RunCellAndRestart(t *testing.T) {
+	runner, nb := newTestNotebook(t, Cell{Source: "one"}, Cell{Source: "two"})
+	k := newFakeKernel()
+
+	nb, err := runner.RunCell(k, nb.ID, nb.Cells[1].ID)
+	if err != nil {
+		t.Fatalf("RunCell failed: %v", err)
+	}
+	if nb.Cells[1].ExecutionCount != 1 || nb.Cells[0].Output != nil {
+		t.Errorf("Expected only the second cell to run, got %+v", nb.Cells)
+	}
+	if _, err := runner.RunCell(k, nb.ID, "missing"); !errors.Is(err, ErrCellNotFound) {
+		t.Errorf("Expected ErrCellNotFound, got: %v", err)
+	}
+
+	oldSession := nb.SessionID
+	nb, err = runner.Restart(k, nb.ID)
+	if err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	if nb.SessionID != "" || nb.Cells[1].Output != nil || nb.Executed != 0 {
+		t.Errorf("Expected restart to clear state, got %+v", nb)
+	}
+	if len(k.stopped) != 1 || k.stopped[0] != oldSession {
+		t.Errorf("Expected old session to be stopped, got %v", k.stopped)
+	}
+
+	// An expired session is replaced transparently
+	nb, _ = runner.RunCell(k, nb.ID, nb.Cells[0].ID)
+	delete(k.sessions, nb.SessionID)
+	nb, err = runner.RunCell(k, nb.ID, nb.Cells[1].ID)
+	if err != nil {
+		t.Fatalf("RunCell failed: %v", err)
+	}
+	if k.started != 3 || nb.Cells[1].Output.Stdout != "two\n" {
+		t.Errorf("Expected a new session after expiry, got %d sessions and %+v", k.started, nb.Cells[1])
+	}
+	// The executed cells above run again first to restore their state
+	if history := k.sessions[nb.SessionID]; strings.Join(history, ",") != "one,two" {
+		t.Errorf("Expected the first cell to run again in the new session, got %v", history)
+	}
+	if !strings.Contains(nb.Cells[1].Output.Stderr, "1 earlier cells ran again") {
+		t.Errorf("Expected a notice of the restart, got %+v", nb.Cells[1].Output)
+	}
+}
+
+func TestRunner_ReplayFailure(t *testing.T) {
+	runner, nb := newTestNotebook(t, Cell{Source: "fail"}, Cell{Source: "two"})
+	k := newFakeKernel()
+	runner.RunCell(k, nb.ID, nb.Cells[0].ID)
+	nb, _ = runner.Store().Get(nb.ID)
+	delete(k.sessions, nb.SessionID)
+
+	nb, err := runner.RunCell(k, nb.ID, nb.Cells[1].ID)
+	if err != nil {
+		t.Fatalf("RunCell failed: %v", err)
+	}
+	if out := nb.Cells[1].Output; out == nil || !strings.Contains(out.Error, "restart the notebook") {
+		t.Errorf("Expected the cell to ask for a restart, got %+v", out)
+	}
+	if nb.SessionID != "" || len(k.stopped) != 1 {
+		t.Errorf("Expected the new session to be stopped, got %q and %v", nb.SessionID, k.stopped)
+	}
+}
+
+func TestStore_Owner(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	nb, err := store.Create(Notebook{Language: "node", Owner: "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if got, _ := store.Get(nb.ID); got.Owner != "10.0.0.1" {
+		t.Errorf("Expected the owner to be saved, got %q", got.Owner)
+	}
+	if data, _ := json.Marshal(nb); strings.Contains(string(data), "10.0.0.1") {
+		t.Errorf("Expected the owner to be left out of the JSON, got %s", data)
+	}
+}
@@ -0,0 +1,188 @@
+package notebook
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrKernelGone is returned by a Kernel when the session behind a notebook no
+// longer exists, for example after idle expiry, or cannot be used by the
+// caller. The runner then starts a new one and runs the executed cells above
+// the current one again to restore their state.
+var ErrKernelGone = errors.New("kernel session no longer exists")
+
+// Kernel evaluates code for notebooks, backed by an interactive session
+type Kernel interface {
+	Start(language string) (string, error)
+	Eval(sessionID, code string) (Output, error)
+	Stop(sessionID string) error
+}
+
+// Runner executes notebook cells in document order against one kernel
+// session and persists every output with the document.
+type Runner struct {
+	store *Store
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// NewRunner creates a runner for notebooks kept in store
+func NewRunner(store *Store) *Runner {
+	return &Runner{store: store, locks: make(map[string]*sync.Mutex)}
+}
+
+// Store returns the notebook store used by the runner
+func (r *Runner) Store() *Store {
+	return r.store
+}
+
+// RunCell executes a single code cell
+func (r *Runner) RunCell(k Kernel, id, cellID string) (Notebook, error) {
+	unlock := r.lock(id)
+	defer unlock()
+
+	nb, err := r.store.Get(id)
+	if err != nil {
+		return Notebook{}, err
+	}
+	cell, err := nb.cell(cellID)
+	if err != nil {
+		return Notebook{}, err
+	}
+	if cell.Type != CellCode {
+		return nb, nil
+	}
+	nb, _, err = r.run(k, nb, cellID)
+	return nb, err
+}
+
+// RunAll executes every code cell in order, stopping at the first cell that
+// fails the way a notebook kernel would
+func (r *Runner) RunAll(k Kernel, id string) (Notebook, error) {
+	unlock := r.lock(id)
+	defer unlock()
+
+	nb, err := r.store.Get(id)
+	if err != nil {
+		return Notebook{}, err
+	}
+	ids := []string{}
+	for _, c := range nb.Cells {
+		if c.Type == CellCode {
+			ids = append(ids, c.ID)
+		}
+	}
+	for _, cellID := range ids {
+		var out Output
+		nb, out, err = r.run(k, nb, cellID)
+		if err != nil {
+			return nb, err
+		}
+		if out.Error != "" {
+			break
+		}
+	}
+	return nb, nil
+}
+
+// Restart stops the notebook's session and clears all outputs
+func (r *Runner) Restart(k Kernel, id string) (Notebook, error) {
+	unlock := r.lock(id)
+	defer unlock()
+
+	return r.store.Update(id, func(nb *Notebook) error {
+		if nb.SessionID != "" {
+			k.Stop(nb.SessionID)
+		}
+		nb.SessionID = ""
+		nb.Executed = 0
+		for i := range nb.Cells {
+			nb.Cells[i].Output = nil
+			nb.Cells[i].ExecutionCount = 0
+		}
+		return nil
+	})
+}
+
+// run evaluates one cell and saves its output, starting a session first when
+// the notebook has none
+func (r *Runner) run(k Kernel, nb Notebook, cellID string) (Notebook, Output, error) {
+	cell, err := nb.cell(cellID)
+	if err != nil {
+		return nb, Output{}, err
+	}
+	sessionID := nb.SessionID
+	if sessionID == "" {
+		if sessionID, err = k.Start(nb.Language); err != nil {
+			return nb, Output{}, err
+		}
+	}
+	out, err := k.Eval(sessionID, cell.Source)
+	if err == ErrKernelGone {
+		if sessionID, err = k.Start(nb.Language); err != nil {
+			return nb, Output{}, err
+		}
+		var replayed int
+		if replayed, err = replay(k, sessionID, nb, cellID); err != nil {
+			k.Stop(sessionID)
+		} else if out, err = k.Eval(sessionID, cell.Source); replayed > 0 {
+			out.Stderr = fmt.Sprintf("The kernel was restarted, %d earlier cells ran again\n", replayed) + out.Stderr
+		}
+	}
+	if err != nil {
+		// The session died while running, the next run starts a fresh one
+		out.Error = err.Error()
+		sessionID = ""
+	}
+
+	nb, err = r.store.Update(nb.ID, func(stored *Notebook) error {
+		stored.SessionID = sessionID
+		c, err := stored.cell(cellID)
+		if err != nil {
+			return err
+		}
+		stored.Executed++
+		c.ExecutionCount = stored.Executed
+		c.Output = &out
+		return nil
+	})
+	return nb, out, err
+}
+
+// replay runs the code cells above cellID that were executed before in a
+// new session, in document order, and returns how many ran. Their outputs
+// are not saved again. When one of them fails the state cannot be restored
+// and the notebook has to be restarted.
+func replay(k Kernel, sessionID string, nb Notebook, cellID string) (int, error) {
+	replayed := 0
+	for _, c := range nb.Cells {
+		if c.ID == cellID {
+			break
+		}
+		if c.Type != CellCode || c.ExecutionCount == 0 {
+			continue
+		}
+		out, err := k.Eval(sessionID, c.Source)
+		if err == nil && out.Error != "" {
+			err = errors.New(out.Error)
+		}
+		if err != nil {
+			return replayed, fmt.Errorf("the kernel was restarted and an earlier cell failed to run again (%v), restart the notebook", err)
+		}
+		replayed++
+	}
+	return replayed, nil
+}
+
+func (r *Runner) lock(id string) func() {
+	r.mu.Lock()
+	l, ok := r.locks[id]
+	if !ok {
+		l = &sync.Mutex{}
+		r.locks[id] = l
+	}
+	r.mu.Unlock()
+	l.Lock()
+	return l.Unlock
+}
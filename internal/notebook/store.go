@@ -0,0 +1,132 @@
+package notebook
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stored is the file of a notebook, which keeps the owner the API leaves out
+type stored struct {
+	Notebook
+	Owner string `json:"owner,omitempty"`
+}
+
+// Store persists notebooks as one JSON file per document
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewStore creates a store rooted at dir, creating the directory if needed
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Create saves a new notebook and assigns its ID
+func (s *Store) Create(nb Notebook) (Notebook, error) {
+	if err := nb.Normalize(); err != nil {
+		return Notebook{}, err
+	}
+	now := time.Now()
+	nb.ID = newID()
+	nb.Created = now
+	nb.Updated = now
+	nb.SessionID = ""
+	nb.Executed = 0
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return nb, s.write(nb)
+}
+
+// Get loads a notebook by ID
+func (s *Store) Get(id string) (Notebook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read(id)
+}
+
+// List returns every notebook, most recently updated first
+func (s *Store) List() ([]Notebook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	notebooks := []Notebook{}
+	for _, f := range files {
+		nb, err := s.read(strings.TrimSuffix(filepath.Base(f), ".json"))
+		if err != nil {
+			continue
+		}
+		notebooks = append(notebooks, nb)
+	}
+	sort.Slice(notebooks, func(i, j int) bool {
+		return notebooks[i].Updated.After(notebooks[j].Updated)
+	})
+	return notebooks, nil
+}
+
+// Update applies fn to the stored notebook and saves the result
+func (s *Store) Update(id string, fn func(nb *Notebook) error) (Notebook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	nb, err := s.read(id)
+	if err != nil {
+		return Notebook{}, err
+	}
+	if err := fn(&nb); err != nil {
+		return Notebook{}, err
+	}
+	if err := nb.Normalize(); err != nil {
+		return Notebook{}, err
+	}
+	nb.Updated = time.Now()
+	return nb, s.write(nb)
+}
+
+// Delete removes a notebook
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.read(id); err != nil {
+		return err
+	}
+	return os.Remove(s.path(id))
+}
+
+func (s *Store) read(id string) (Notebook, error) {
+	var file stored
+	data, err := os.ReadFile(s.path(id))
+	if os.IsNotExist(err) {
+		return Notebook{}, ErrNotFound
+	}
+	if err != nil {
+		return Notebook{}, err
+	}
+	err = json.Unmarshal(data, &file)
+	file.Notebook.Owner = file.Owner
+	return file.Notebook, err
+}
+
+func (s *Store) write(nb Notebook) error {
+	data, err := json.MarshalIndent(stored{Notebook: nb, Owner: nb.Owner}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(nb.ID), data, 0o644)
+}
+
+// path maps an ID to its file, rejecting anything that is not a plain name
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, filepath.Base(filepath.Clean("/"+id))+".json")
+}
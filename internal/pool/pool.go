@@ -0,0 +1,132 @@
+package pool
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/interp"
+)
+
+// Metrics describes the state of a worker pool
+type Metrics struct {
+	Language string  `json:"lang"`
+	Size     int     `json:"size"`
+	Idle     int     `json:"idle"`
+	Hits     int64   `json:"hits"`
+	Misses   int64   `json:"misses"`
+	HitRate  float64 `json:"hitRate"`
+}
+
+// Pool keeps pre-started interpreter workers for one language so a request
+// does not pay interpreter startup. A worker runs a single program, it is
+// replaced by a fresh one as soon as it is taken.
+type Pool struct {
+	language string
+	size     int
+	start    func(language string) (interp.Worker, error)
+	idle     chan interp.Worker
+
+	hits   atomic.Int64
+	misses atomic.Int64
+
+	closeOnce sync.Once
+	closed    atomic.Bool
+}
+
+// New creates a pool of size workers and starts filling it in the background
+func New(language string, size int) *Pool {
+	p := newPool(language, size, interp.StartWorker)
+	for i := 0; i < size; i++ {
+		go p.spawn()
+	}
+	return p
+}
+
+func newPool(language string, size int, start func(string) (interp.Worker, error)) *Pool {
+	return &Pool{
+		language: language,
+		size:     size,
+		start:    start,
+		idle:     make(chan interp.Worker, size),
+	}
+}
+
+// Run runs a program file on an idle worker, starting one on demand when the
+// pool is empty. The returned flag reports whether a warm worker was used.
+func (p *Pool) Run(file string, timeout time.Duration) (interp.Result, bool, error) {
+	var (
+		w    interp.Worker
+		warm bool
+	)
+	select {
+	case w = <-p.idle:
+		warm = true
+		p.hits.Add(1)
+	default:
+		p.misses.Add(1)
+		var err error
+		if w, err = p.start(p.language); err != nil {
+			return interp.Result{}, false, err
+		}
+	}
+	// The taken worker runs this program only, a fresh one replaces it
+	go p.spawn()
+	res, err := w.Run(file, timeout)
+	w.Close()
+	return res, warm, err
+}
+
+// Metrics returns the pool counters
+func (p *Pool) Metrics() Metrics {
+	m := Metrics{
+		Language: p.language,
+		Size:     p.size,
+		Idle:     len(p.idle),
+		Hits:     p.hits.Load(),
+		Misses:   p.misses.Load(),
+	}
+	if total := m.Hits + m.Misses; total > 0 {
+		m.HitRate = float64(m.Hits) / float64(total)
+	}
+	return m
+}
+
+// Close stops all idle workers
+func (p *Pool) Close() {
+	p.closeOnce.Do(func() {
+		p.closed.Store(true)
+		for {
+			select {
+			case w := <-p.idle:
+				w.Close()
+			default:
+				return
+			}
+		}
+	})
+}
+
+// release returns a worker to the pool, closing it if the pool is full
+func (p *Pool) release(w interp.Worker) {
+	if p.closed.Load() {
+		w.Close()
+		return
+	}
+	select {
+	case p.idle <- w:
+	default:
+		w.Close()
+	}
+}
+
+func (p *Pool) spawn() {
+	if p.closed.Load() || len(p.idle) >= p.size {
+		return
+	}
+	in, err := p.start(p.language)
+	if err != nil {
+		return
+	}
+	p.release(in)
+}
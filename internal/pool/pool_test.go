@@ -0,0 +1,114 @@
+package pool
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/interp"
+)
+
+// fakeWorker echoes the file it runs
+type fakeWorker struct {
+	mu     sync.Mutex
+	closed bool
+}
+
+func (f *fakeWorker) Run(file string, timeout time.Duration) (interp.Result, error) {
+	return interp.Result{Stdout: file}, nil
+}
+
+func (f *fakeWorker) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func fakeStart(language string) (interp.Worker, error) {
+	return &fakeWorker{}, nil
+}
+
+// waitIdle waits for background spawns to refill the pool
+func waitIdle(t *testing.T, p *Pool, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for len(p.idle) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d idle workers, got %d", n, len(p.idle))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPool_HitsAndMisses(t *testing.T) {
+	p := newPool("node", 1, fakeStart)
+
+	// Empty pool starts a cold worker
+	res, warm, err := p.Run("a", time.Second)
+	if err != nil || warm || res.Stdout != "a" {
+		t.Errorf("Expected cold run, got %+v warm=%v err=%v", res, warm, err)
+	}
+	// A fresh worker replaced the cold one and is warm now
+	waitIdle(t, p, 1)
+	_, warm, _ = p.Run("b", time.Second)
+	if !warm {
+		t.Error("Expected warm run after the pool was refilled")
+	}
+
+	m := p.Metrics()
+	if m.Hits != 1 || m.Misses != 1 || m.HitRate != 0.5 {
+		t.Errorf("Unexpected metrics: %+v", m)
+	}
+}
+
+func TestPool_Recycle(t *testing.T) {
+	p := newPool("node", 1, fakeStart)
+	p.spawn()
+
+	// Every worker runs a single program
+	w := (<-p.idle).(*fakeWorker)
+	p.idle <- w
+	p.Run("a", time.Second)
+	waitIdle(t, p, 1)
+	if !w.closed {
+		t.Error("Expected the worker to be closed after its run")
+	}
+	if next := <-p.idle; next == interp.Worker(w) {
+		t.Error("Expected a fresh worker in the pool")
+	}
+}
+
+func TestPool_Close(t *testing.T) {
+	p := newPool("node", 2, fakeStart)
+	p.spawn()
+	w := <-p.idle
+	p.idle <- w
+	p.Close()
+	if !w.(*fakeWorker).closed {
+		t.Error("Expected idle worker to be closed")
+	}
+	if m := p.Metrics(); m.Idle != 0 {
+		t.Errorf("Expected no idle workers after close, got %+v", m)
+	}
+}
+
+func TestPool_Node(t *testing.T) {
+	p := New("node", 1)
+	defer p.Close()
+	waitIdle(t, p, 1)
+
+	file := filepath.Join(t.TempDir(), "main.js")
+	if err := os.WriteFile(file, []byte("console.log('hello world')"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res, warm, err := p.Run(file, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !warm || res.Stdout != "hello world\n" {
+		t.Errorf("Expected warm run with output, got %+v warm=%v", res, warm)
+	}
+}
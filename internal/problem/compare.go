@@ -0,0 +1,84 @@
+package problem
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Comparator types
+const (
+	// CompareJSON accepts a structurally equal value, the default
+	CompareJSON = "json"
+	// CompareExact accepts the same canonical JSON text only, so 1 and 1.0
+	// or differently ordered object keys in the output differ
+	CompareExact = "exact"
+	// CompareWhitespace compares strings token by token, ignoring CRLF,
+	// indentation, trailing spaces and blank lines
+	CompareWhitespace = "whitespace"
+	// CompareFloat compares numbers within an absolute or relative epsilon
+	CompareFloat = "float"
+	// CompareUnordered compares the top-level list as a multiset
+	CompareUnordered = "unordered"
+	// CompareSet compares the top-level list as a set, ignoring duplicates
+	CompareSet = "set"
+	// CompareChecker runs the problem author's checker program
+	CompareChecker = "checker"
+)
+
+// DefaultFloatEpsilon applies to float comparators that set no epsilon
+const DefaultFloatEpsilon = 1e-9
+
+// Comparator decides whether an actual value matches the expected one. It can
+// be set for a whole problem and overridden per test case.
+type Comparator struct {
+	Type string `json:"type"`
+	// AbsEps and RelEps bound the difference of numbers for CompareFloat, a
+	// value is accepted when it is within either bound
+	AbsEps float64 `json:"absEps,omitempty"`
+	RelEps float64 `json:"relEps,omitempty"`
+	// Checker is the program deciding CompareChecker tests
+	Checker *Checker `json:"checker,omitempty"`
+}
+
+// Checker is a program run in the same sandbox as submissions. It reads a
+// JSON object with the test's args, expected and actual values on stdin and
+// writes a verdict record with {"ok": bool, "message": string} as its value
+// to the results file.
+type Checker struct {
+	Language string `json:"language"`
+	Source   string `json:"source"`
+}
+
+// Validate checks that the comparator can be used
+func (c Comparator) Validate() error {
+	switch c.Type {
+	case CompareJSON, CompareExact, CompareWhitespace, CompareUnordered, CompareSet:
+		return nil
+	case CompareFloat:
+		if c.AbsEps < 0 || c.RelEps < 0 {
+			return fmt.Errorf("float comparator epsilon must not be negative")
+		}
+		return nil
+	case CompareChecker:
+		if c.Checker == nil || c.Checker.Source == "" {
+			return fmt.Errorf("checker comparator needs a checker program")
+		}
+		if !slices.Contains(Languages, c.Checker.Language) {
+			return fmt.Errorf("unsupported checker language: %s", c.Checker.Language)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown comparator: %s", c.Type)
+}
+
+// Comparator returns the comparator of a test case, falling back to the
+// problem's and then to JSON deep-equality
+func (p Problem) Comparator(tc TestCase) Comparator {
+	if tc.Compare != nil {
+		return *tc.Compare
+	}
+	if p.Compare != nil {
+		return *p.Compare
+	}
+	return Comparator{Type: CompareJSON}
+}
@@ -0,0 +1,56 @@
+package problem
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Generator is a program producing random test inputs, run in the same
+// sandbox as submissions. It reads {"seeds": [...]} on stdin and, for the
+// seed at each index, appends an input record with that index as its test
+// and the arguments as its value to the results file. The same seed must
+// always give the same arguments.
+type Generator struct {
+	Language string `json:"language"`
+	Source   string `json:"source"`
+	// Seeds are turned into hidden tests whenever the problem is saved,
+	// with the reference solution's results as expected values
+	Seeds []int64 `json:"seeds,omitempty"`
+}
+
+// Validate checks that the generator can be run
+func (g Generator) Validate() error {
+	if g.Source == "" {
+		return fmt.Errorf("generator has no source")
+	}
+	if !slices.Contains(Languages, g.Language) {
+		return fmt.Errorf("unsupported generator language: %s", g.Language)
+	}
+	return nil
+}
+
+// ReferenceLanguage returns the language whose reference solution computes
+// the expected values of generated tests, preferring the generator's own
+func (p Problem) ReferenceLanguage() (string, bool) {
+	if p.Generator != nil && p.Reference[p.Generator.Language] != "" && slices.Contains(p.Languages(), p.Generator.Language) {
+		return p.Generator.Language, true
+	}
+	for _, lang := range p.Languages() {
+		if p.Reference[lang] != "" {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+// Written returns the tests written by the author, without those generated
+// from the generator's seeds
+func (p Problem) Written() []TestCase {
+	written := []TestCase{}
+	for _, tc := range p.Tests {
+		if tc.Seed == nil {
+			written = append(written, tc)
+		}
+	}
+	return written
+}
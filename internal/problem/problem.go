@@ -0,0 +1,424 @@
+package problem
+
+import (
+	"errors"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/signature"
+)
+
+// Languages a problem can be solved in
+var Languages = []string{"node", "php", "go"}
+
+// ErrExists is returned when creating a problem whose ID is taken
+var ErrExists = errors.New("problem already exists")
+
+// ArgsPlaceholder is replaced in a hand-written harness with the arguments
+// of a test case
+const ArgsPlaceholder = "{{args}}"
+
+// validID matches problem IDs, which also name files and URL segments
+var validID = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Difficulties are the levels a problem can be rated at, easiest first
+var Difficulties = []string{"easy", "medium", "hard"}
+
+// DefaultTimeLimit applies to each test case when a problem does not set one
+const DefaultTimeLimit = 10 * time.Second
+
+// TestCase is one call of the problem's function with its expected result.
+// Sample tests are shown to users, hidden tests are only used to grade
+// submissions and never leave the server.
+type TestCase struct {
+	Args     []json.RawMessage `json:"args"`
+	Expected json.RawMessage   `json:"expected"`
+	Hidden   bool              `json:"hidden,omitempty"`
+	// Compare overrides the problem's comparator for this test
+	Compare *Comparator `json:"compare,omitempty"`
+	// Seed is the generator seed the test was generated from, such tests
+	// are replaced whenever the problem is saved
+	Seed *int64 `json:"seed,omitempty"`
+}
+
+// Problem is a challenge defined as data: a statement, the function users
+// implement, starter code and the harness that calls it per language. With a
+// typed signature the harness and missing starter code are generated for
+// every language; a hand-written harness still takes precedence.
+type Problem struct {
+	ID        string            `json:"id"`
+	Title     string            `json:"title"`
+	Statement string            `json:"statement"`
+	Function  string            `json:"function"`
+	Signature string            `json:"signature,omitempty"`
+	Starter   map[string]string `json:"starter"`
+	Harness   map[string]string `json:"harness"`
+	Tests     []TestCase        `json:"tests"`
+	// TimeLimitMs is the time limit of a single test case run
+	TimeLimitMs int `json:"timeLimitMs,omitempty"`
+	// Compare is how results are compared, JSON deep-equality by default
+	Compare *Comparator `json:"compare,omitempty"`
+	// Reference holds the author's solution per language, which must pass
+	// every test before a problem is saved over the API
+	Reference map[string]string `json:"reference,omitempty"`
+	// Version counts the saved revisions of the problem, starting at 1
+	Version int `json:"version,omitempty"`
+	// Generator produces random inputs for generated tests and stress runs
+	Generator *Generator `json:"generator,omitempty"`
+	// Tags and Difficulty (one of Difficulties) help users find problems
+	Tags       []string `json:"tags,omitempty"`
+	Difficulty string   `json:"difficulty,omitempty"`
+	// Hints are Markdown, revealed to a user one at a time in order
+	Hints []string `json:"hints,omitempty"`
+	// Editorial is the Markdown write-up of the solution. It unlocks for a
+	// user once they solve the problem or, when EditorialAfter is set,
+	// after that many rejected submissions.
+	Editorial      string `json:"editorial,omitempty"`
+	EditorialAfter int    `json:"editorialAfter,omitempty"`
+}
+
+// Summary is the public listing entry of a problem
+type Summary struct {
+	ID         string   `json:"id"`
+	Title      string   `json:"title"`
+	Languages  []string `json:"languages"`
+	Tags       []string `json:"tags"`
+	Difficulty string   `json:"difficulty,omitempty"`
+}
+
+// Validate checks that the problem can be served
+func (p Problem) Validate() error {
+	if p.ID == "" {
+		return fmt.Errorf("problem has no id")
+	}
+	if !validID.MatchString(p.ID) {
+		return fmt.Errorf("problem id %q may only contain lowercase letters, digits, - and _", p.ID)
+	}
+	if p.Title == "" || p.Function == "" {
+		return fmt.Errorf("problem %s: title and function are required", p.ID)
+	}
+	if p.Difficulty != "" && !slices.Contains(Difficulties, p.Difficulty) {
+		return fmt.Errorf("problem %s: difficulty %q is not one of %s", p.ID, p.Difficulty, strings.Join(Difficulties, ", "))
+	}
+	for _, tag := range p.Tags {
+		if !validID.MatchString(tag) {
+			return fmt.Errorf("problem %s: tag %q may only contain lowercase letters, digits, - and _", p.ID, tag)
+		}
+	}
+	for i, hint := range p.Hints {
+		if strings.TrimSpace(hint) == "" {
+			return fmt.Errorf("problem %s: hint %d is empty", p.ID, i+1)
+		}
+	}
+	if p.EditorialAfter < 0 {
+		return fmt.Errorf("problem %s: editorialAfter cannot be negative", p.ID)
+	}
+	if p.Compare != nil {
+		if err := p.Compare.Validate(); err != nil {
+			return fmt.Errorf("problem %s: %v", p.ID, err)
+		}
+	}
+	if p.Generator != nil {
+		if err := p.Generator.Validate(); err != nil {
+			return fmt.Errorf("problem %s: %v", p.ID, err)
+		}
+	}
+	for i, tc := range p.Tests {
+		if tc.Compare != nil {
+			if err := tc.Compare.Validate(); err != nil {
+				return fmt.Errorf("problem %s: test %d: %v", p.ID, i, err)
+			}
+		}
+	}
+	if p.Signature != "" {
+		sig, err := signature.Parse(p.Signature)
+		if err != nil {
+			return fmt.Errorf("problem %s: %v", p.ID, err)
+		}
+		if sig.Function != p.Function {
+			return fmt.Errorf("problem %s: signature declares %s instead of %s", p.ID, sig.Function, p.Function)
+		}
+		for i, tc := range p.Tests {
+			if err := sig.Check(tc.Args, tc.Expected); err != nil {
+				return fmt.Errorf("problem %s: test %d: %v", p.ID, i, err)
+			}
+		}
+	}
+	if len(p.Languages()) == 0 {
+		return fmt.Errorf("problem %s: no language has a harness", p.ID)
+	}
+	if len(p.Samples()) == 0 {
+		return fmt.Errorf("problem %s: at least one sample test case is required", p.ID)
+	}
+	return nil
+}
+
+// Samples returns the test cases users can see
+func (p Problem) Samples() []TestCase {
+	samples := []TestCase{}
+	for _, tc := range p.Tests {
+		if !tc.Hidden {
+			samples = append(samples, tc)
+		}
+	}
+	return samples
+}
+
+// TimeLimit returns how long a single test case may run
+func (p Problem) TimeLimit() time.Duration {
+	if p.TimeLimitMs <= 0 {
+		return DefaultTimeLimit
+	}
+	return time.Duration(p.TimeLimitMs) * time.Millisecond
+}
+
+// Languages returns the languages the problem supports, in display order
+func (p Problem) Languages() []string {
+	langs := []string{}
+	for _, lang := range Languages {
+		if p.Harness[lang] != "" || p.Signature != "" {
+			langs = append(langs, lang)
+		}
+	}
+	return langs
+}
+
+// Driver returns the harness code in the given language. A hand-written
+// harness calls the function with the arguments of the test case, the
+// generated driver runs whatever tests the tests file lists.
+func (p Problem) Driver(language string, tc TestCase) (string, error) {
+	if harness := p.Harness[language]; harness != "" {
+		args := make([]string, len(tc.Args))
+		for i, arg := range tc.Args {
+			args[i] = string(arg)
+		}
+		return strings.ReplaceAll(harness, ArgsPlaceholder, strings.Join(args, ", ")), nil
+	}
+	if p.Signature == "" {
+		return "", fmt.Errorf("problem %s does not support %s", p.ID, language)
+	}
+	sig, err := signature.Parse(p.Signature)
+	if err != nil {
+		return "", err
+	}
+	return sig.Driver(language)
+}
+
+// ReadsTests reports whether the driver in the given language reads its
+// tests from the tests file, so that one build serves every test case
+func (p Problem) ReadsTests(language string) bool {
+	return p.Harness[language] == "" && p.Signature != ""
+}
+
+// Summary returns the listing entry for the problem
+func (p Problem) Summary() Summary {
+	tags := p.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return Summary{ID: p.ID, Title: p.Title, Languages: p.Languages(), Tags: tags, Difficulty: p.Difficulty}
+}
+
+// Bank holds the current version of every problem by ID, along with each
+// earlier version so results stay tied to the tests they were graded
+// against
+type Bank struct {
+	mu       sync.RWMutex
+	problems map[string]Problem
+	versions map[string][]Problem
+}
+
+// NewBank creates an empty bank
+func NewBank() *Bank {
+	return &Bank{problems: make(map[string]Problem), versions: make(map[string][]Problem)}
+}
+
+// Load reads every *.json file in dir of fsys as a problem
+func Load(fsys fs.FS, dir string) (*Bank, error) {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	b := NewBank()
+	for _, f := range files {
+		data, err := fs.ReadFile(fsys, f)
+		if err != nil {
+			return nil, err
+		}
+		var p Problem
+		if err := json.Unmarshal(data, &p); err != nil {
+			return nil, fmt.Errorf("%s: %v", f, err)
+		}
+		if err := b.Add(p); err != nil {
+			return nil, fmt.Errorf("%s: %v", f, err)
+		}
+	}
+	return b, nil
+}
+
+// MustLoad is like Load but panics if the problems cannot be loaded
+func MustLoad(fsys fs.FS, dir string) *Bank {
+	b, err := Load(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// fill sets the function name and missing starter code from a typed
+// signature
+func (p *Problem) fill() {
+	sig, err := signature.Parse(p.Signature)
+	if err != nil {
+		return
+	}
+	if p.Function == "" {
+		p.Function = sig.Function
+	}
+	starter := make(map[string]string, len(Languages))
+	for _, lang := range Languages {
+		starter[lang] = p.Starter[lang]
+		if starter[lang] == "" {
+			starter[lang], _ = sig.Stub(lang)
+		}
+	}
+	p.Starter = starter
+}
+
+// Add validates and stores a problem, rejecting duplicate IDs. The function
+// name and missing starter code are filled in from a typed signature. A
+// problem without a version becomes version 1.
+func (b *Bank) Add(p Problem) error {
+	p, err := Prepare(p)
+	if err != nil {
+		return err
+	}
+	if p.Version == 0 {
+		p.Version = 1
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, exists := b.problems[p.ID]; exists {
+		return fmt.Errorf("duplicate problem id %s", p.ID)
+	}
+	b.problems[p.ID] = p
+	b.versions[p.ID] = append(b.versions[p.ID], p)
+	return nil
+}
+
+// Prepare fills in and validates a problem the way Add does without storing
+// it, so it can be checked further before it is saved
+func Prepare(p Problem) (Problem, error) {
+	p.fill()
+	return p, p.Validate()
+}
+
+// Put stores a new version of a problem, creating it when the ID is unused,
+// and returns it with its version number. With create the problem must not
+// exist yet, ErrExists is returned otherwise. The version is passed to save
+// before it becomes current, a save error leaves the bank unchanged.
+func (b *Bank) Put(p Problem, create bool, save func(Problem) error) (Problem, error) {
+	p, err := Prepare(p)
+	if err != nil {
+		return Problem{}, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, exists := b.problems[p.ID]; exists && create {
+		return Problem{}, ErrExists
+	}
+	p.Version = 1
+	if versions := b.versions[p.ID]; len(versions) > 0 {
+		p.Version = versions[len(versions)-1].Version + 1
+	}
+	if save != nil {
+		if err := save(p); err != nil {
+			return Problem{}, err
+		}
+	}
+	b.problems[p.ID] = p
+	b.versions[p.ID] = append(b.versions[p.ID], p)
+	return p, nil
+}
+
+// Delete removes the current version of a problem, earlier versions stay
+// available. It reports whether the problem existed.
+func (b *Bank) Delete(id string, save func(id string) error) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.problems[id]; !ok {
+		return false, nil
+	}
+	if save != nil {
+		if err := save(id); err != nil {
+			return true, err
+		}
+	}
+	delete(b.problems, id)
+	return true, nil
+}
+
+// restore adds stored versions of a problem, replacing any version with the
+// same number, and makes the latest current unless it was deleted
+func (b *Bank) restore(id string, stored []Problem, deleted bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, p := range stored {
+		b.versions[id] = slices.DeleteFunc(b.versions[id], func(v Problem) bool { return v.Version == p.Version })
+		b.versions[id] = append(b.versions[id], p)
+	}
+	slices.SortFunc(b.versions[id], func(x, y Problem) int { return x.Version - y.Version })
+	if versions := b.versions[id]; len(versions) > 0 && !deleted {
+		b.problems[id] = versions[len(versions)-1]
+	} else {
+		delete(b.problems, id)
+	}
+}
+
+// Get returns the current version of the problem with the given ID
+func (b *Bank) Get(id string) (Problem, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	p, ok := b.problems[id]
+	return p, ok
+}
+
+// Version returns a specific version of a problem, also after the problem
+// was changed or deleted
+func (b *Bank) Version(id string, version int) (Problem, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, p := range b.versions[id] {
+		if p.Version == version {
+			return p, true
+		}
+	}
+	return Problem{}, false
+}
+
+// Versions returns every version of a problem, oldest first
+func (b *Bank) Versions(id string) []Problem {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return slices.Clone(b.versions[id])
+}
+
+// List returns every problem sorted by ID
+func (b *Bank) List() []Problem {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	list := make([]Problem, 0, len(b.problems))
+	for _, p := range b.problems {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
@@ -0,0 +1,250 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+const validProblem = `{
+  "id": "sum",
+  "title": "Sum",
+  "statement": "Add two numbers",
+  "function": "sum",
+  "starter": {"node": "function sum(a, b) {}"},
+  "harness": {"node": "console.log(sum(1, 2))", "go": "func main() {}"},
+  "tests": [{"args": [1, 2], "expected": 3}]
+}`
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"problems/sum.json":  {Data: []byte(validProblem)},
+		"problems/README.md": {Data: []byte("not a problem")},
+		"other/ignored.json": {Data: []byte("{}")},
+	}
+	b, err := Load(fsys, "problems")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	p, ok := b.Get("sum")
+	if !ok {
+		t.Fatal("Expected problem 'sum' to be loaded")
+	}
+	if p.Function != "sum" || len(p.Tests) != 1 || string(p.Tests[0].Expected) != "3" {
+		t.Errorf("Unexpected problem: %+v", p)
+	}
+	if got := strings.Join(p.Languages(), ","); got != "node,go" {
+		t.Errorf("Expected languages node,go, got %s", got)
+	}
+	if len(b.List()) != 1 {
+		t.Errorf("Expected 1 problem, got %d", len(b.List()))
+	}
+	if _, ok := b.Get("missing"); ok {
+		t.Error("Did not expect to find a missing problem")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	testCases := map[string]string{
+		"invalid json":   `{"id": `,
+		"missing title":  `{"id": "x", "function": "f", "harness": {"node": "f()"}, "tests": [{"args": [], "expected": 1}]}`,
+		"no harness":     `{"id": "x", "title": "X", "function": "f", "tests": [{"args": [], "expected": 1}]}`,
+		"no tests":       `{"id": "x", "title": "X", "function": "f", "harness": {"node": "f()"}}`,
+		"only hidden":    `{"id": "x", "title": "X", "function": "f", "harness": {"node": "f()"}, "tests": [{"args": [], "expected": 1, "hidden": true}]}`,
+		"bad compare":    `{"id": "x", "title": "X", "function": "f", "harness": {"node": "f()"}, "compare": {"type": "fuzzy"}, "tests": [{"args": [], "expected": 1}]}`,
+		"bad checker":    `{"id": "x", "title": "X", "function": "f", "harness": {"node": "f()"}, "tests": [{"args": [], "expected": 1, "compare": {"type": "checker"}}]}`,
+		"bad difficulty": `{"id": "x", "title": "X", "function": "f", "harness": {"node": "f()"}, "difficulty": "trivial", "tests": [{"args": [], "expected": 1}]}`,
+		"bad tag":        `{"id": "x", "title": "X", "function": "f", "harness": {"node": "f()"}, "tags": ["Dynamic Programming"], "tests": [{"args": [], "expected": 1}]}`,
+	}
+	for name, data := range testCases {
+		t.Run(name, func(t *testing.T) {
+			fsys := fstest.MapFS{"p/x.json": {Data: []byte(data)}}
+			if _, err := Load(fsys, "p"); err == nil {
+				t.Error("Expected error, but got none")
+			}
+		})
+	}
+
+	fsys := fstest.MapFS{
+		"p/a.json": {Data: []byte(validProblem)},
+		"p/b.json": {Data: []byte(validProblem)},
+	}
+	if _, err := Load(fsys, "p"); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("Expected duplicate id error, got: %v", err)
+	}
+}
+
+func TestTimeLimit(t *testing.T) {
+	if got := (Problem{}).TimeLimit(); got != DefaultTimeLimit {
+		t.Errorf("Expected default time limit %v, got %v", DefaultTimeLimit, got)
+	}
+	if got := (Problem{TimeLimitMs: 1500}).TimeLimit(); got != 1500*time.Millisecond {
+		t.Errorf("Expected time limit 1.5s, got %v", got)
+	}
+}
+
+func TestSamples(t *testing.T) {
+	p := Problem{Tests: []TestCase{{Expected: []byte("1")}, {Expected: []byte("2"), Hidden: true}, {Expected: []byte("3")}}}
+	samples := p.Samples()
+	if len(samples) != 2 || string(samples[0].Expected) != "1" || string(samples[1].Expected) != "3" {
+		t.Errorf("Expected the two visible tests, got %+v", samples)
+	}
+}
+
+func TestSignatureProblem(t *testing.T) {
+	const typed = `{
+  "id": "sum",
+  "title": "Sum",
+  "signature": "sum(xs: []int) -> int",
+  "starter": {"node": "// custom\nfunction sum(xs) {}"},
+  "tests": [{"args": [[1, 2]], "expected": 3}]
+}`
+	b, err := Load(fstest.MapFS{"p/sum.json": {Data: []byte(typed)}}, "p")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	p, _ := b.Get("sum")
+	if p.Function != "sum" {
+		t.Errorf("Expected function name from signature, got %q", p.Function)
+	}
+	if got := strings.Join(p.Languages(), ","); got != "node,php,go" {
+		t.Errorf("Expected every language to be generated, got %s", got)
+	}
+	if !strings.HasPrefix(p.Starter["node"], "// custom") || !strings.Contains(p.Starter["go"], "func sum(xs []int) int") {
+		t.Errorf("Expected missing starters to be generated, got %+v", p.Starter)
+	}
+	driver, err := p.Driver("node", p.Tests[0])
+	if err != nil || !strings.Contains(driver, "sum(...__test.args)") {
+		t.Errorf("Expected generated node driver, got %q, %v", driver, err)
+	}
+
+	invalid := strings.Replace(typed, `"expected": 3`, `"expected": "3"`, 1)
+	if _, err := Load(fstest.MapFS{"p/sum.json": {Data: []byte(invalid)}}, "p"); err == nil {
+		t.Error("Expected error for test data not matching the signature, but got none")
+	}
+}
+
+func TestDriverHarness(t *testing.T) {
+	p := Problem{ID: "f", Harness: map[string]string{"node": "f({{args}})"}}
+	tc := TestCase{Args: []json.RawMessage{json.RawMessage("1"), json.RawMessage(`"a"`)}}
+	if got, _ := p.Driver("node", tc); got != `f(1, "a")` {
+		t.Errorf("Expected arguments to be substituted, got %q", got)
+	}
+	if _, err := p.Driver("go", tc); err == nil {
+		t.Error("Expected error for a language without harness, but got none")
+	}
+
+	p.Signature = "f(n: int, s: string) -> int"
+	if p.ReadsTests("node") || !p.ReadsTests("go") {
+		t.Error("Expected only generated drivers to read the tests file")
+	}
+}
+
+func TestComparator(t *testing.T) {
+	float := &Comparator{Type: CompareFloat, AbsEps: 1e-6}
+	set := &Comparator{Type: CompareSet}
+	p := Problem{Compare: float, Tests: []TestCase{{}, {Compare: set}}}
+	if got := p.Comparator(p.Tests[0]); got.Type != CompareFloat {
+		t.Errorf("Expected the problem comparator, but got %+v", got)
+	}
+	if got := p.Comparator(p.Tests[1]); got.Type != CompareSet {
+		t.Errorf("Expected the test comparator, but got %+v", got)
+	}
+	if got := (Problem{}).Comparator(TestCase{}); got.Type != CompareJSON {
+		t.Errorf("Expected the json comparator by default, but got %+v", got)
+	}
+
+	invalid := []Comparator{
+		{Type: CompareFloat, AbsEps: -1},
+		{Type: CompareChecker, Checker: &Checker{Language: "ruby", Source: "x"}},
+		{Type: CompareChecker},
+	}
+	for _, c := range invalid {
+		if err := c.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", c)
+		}
+	}
+}
+
+func TestBankVersions(t *testing.T) {
+	var p Problem
+	json.Unmarshal([]byte(validProblem), &p)
+	b := NewBank()
+	if err := b.Add(p); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if got, _ := b.Get("sum"); got.Version != 1 {
+		t.Errorf("Expected loaded problems to be version 1, got %d", got.Version)
+	}
+
+	p.Title = "Sum v2"
+	saved := []int{}
+	updated, err := b.Put(p, false, func(p Problem) error {
+		saved = append(saved, p.Version)
+		return nil
+	})
+	if err != nil || updated.Version != 2 || len(saved) != 1 || saved[0] != 2 {
+		t.Fatalf("Expected version 2 to be saved, got %+v, %v, %v", updated, saved, err)
+	}
+	if _, err := b.Put(p, false, func(Problem) error { return errors.New("disk full") }); err == nil {
+		t.Error("Expected save error, but got none")
+	}
+	if got, _ := b.Get("sum"); got.Title != "Sum v2" || got.Version != 2 {
+		t.Errorf("Expected version 2 to be current, got %+v", got)
+	}
+
+	if existed, err := b.Delete("sum", nil); !existed || err != nil {
+		t.Fatalf("Expected delete to succeed, got %v, %v", existed, err)
+	}
+	if _, ok := b.Get("sum"); ok {
+		t.Error("Expected deleted problem to be gone")
+	}
+	if old, ok := b.Version("sum", 1); !ok || old.Title != "Sum" {
+		t.Errorf("Expected version 1 to stay available, got %+v", old)
+	}
+	if existed, _ := b.Delete("sum", nil); existed {
+		t.Error("Expected a second delete to find nothing")
+	}
+	if recreated, _ := b.Put(p, true, nil); recreated.Version != 3 {
+		t.Errorf("Expected a recreated problem to continue at version 3, got %d", recreated.Version)
+	}
+	if _, err := b.Put(p, true, nil); err != ErrExists {
+		t.Errorf("Expected ErrExists when creating an existing problem, but got %v", err)
+	}
+
+	p.ID = "../sum"
+	if _, err := b.Put(p, false, nil); err == nil {
+		t.Error("Expected error for an invalid id, but got none")
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	seed := int64(1)
+	p := Problem{
+		Signature: "f(n: int) -> int",
+		Harness:   map[string]string{"node": "f({{args}})"},
+		Reference: map[string]string{"node": "a", "go": "b"},
+		Generator: &Generator{Language: "go", Source: "package main"},
+		Tests:     []TestCase{{}, {Seed: &seed}},
+	}
+	if lang, ok := p.ReferenceLanguage(); !ok || lang != "go" {
+		t.Errorf("Expected the generator's language, but got %q", lang)
+	}
+	p.Generator.Language = "php"
+	if lang, _ := p.ReferenceLanguage(); lang != "node" {
+		t.Errorf("Expected the first language with a reference, but got %q", lang)
+	}
+	if written := p.Written(); len(written) != 1 || written[0].Seed != nil {
+		t.Errorf("Expected only the written test, but got %+v", written)
+	}
+
+	for _, g := range []Generator{{Language: "go"}, {Language: "ruby", Source: "x"}} {
+		if err := g.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", g)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package problem
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// deletedMarker is the file left in a problem's directory once it is deleted
+const deletedMarker = "deleted"
+
+// Store persists problems authored over the API. Every version is kept in
+// its own file, <id>/<version>.json, and a deleted problem leaves a marker
+// instead of losing its history.
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewStore creates a store rooted at dir, creating the directory if needed
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Save writes a version of a problem, undeleting it if needed
+func (s *Store) Save(p Problem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dir := s.path(p.ID)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, strconv.Itoa(p.Version)+".json"), data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, deletedMarker)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Delete marks a problem as deleted, its versions are kept
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dir := s.path(id)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, deletedMarker), nil, 0o644)
+}
+
+// Restore loads every stored version into the bank. Stored versions follow
+// the ones already in the bank, the latest becomes current unless the
+// problem was deleted.
+func (s *Store) Restore(b *Bank) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		id := entry.Name()
+		files, err := filepath.Glob(filepath.Join(s.dir, id, "*.json"))
+		if err != nil {
+			return err
+		}
+		versions := []Problem{}
+		for _, f := range files {
+			data, err := os.ReadFile(f)
+			if err != nil {
+				return err
+			}
+			var p Problem
+			if err := json.Unmarshal(data, &p); err != nil {
+				return fmt.Errorf("%s: %v", f, err)
+			}
+			if p.ID != id || strconv.Itoa(p.Version)+".json" != filepath.Base(f) {
+				return fmt.Errorf("%s: stored as %s version %d", f, p.ID, p.Version)
+			}
+			versions = append(versions, p)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+		_, err = os.Stat(filepath.Join(s.dir, id, deletedMarker))
+		b.restore(id, versions, err == nil)
+	}
+	return nil
+}
+
+// path maps an ID to its directory, rejecting anything that is not a plain
+// name
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, strings.TrimPrefix(filepath.Base(filepath.Clean("/"+id)), "."))
+}
@@ -0,0 +1,60 @@
+package problem
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	var p Problem
+	json.Unmarshal([]byte(validProblem), &p)
+
+	// The first bank saves two versions of sum and deletes it
+	b := NewBank()
+	if err := b.Add(p); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	p.Title = "Sum v2"
+	if _, err := b.Put(p, false, store.Save); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	p.ID, p.Title = "other", "Other"
+	if _, err := b.Put(p, false, store.Save); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if _, err := b.Delete("other", store.Delete); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	// A bank loaded from the same files gets the stored versions back
+	restored := NewBank()
+	json.Unmarshal([]byte(validProblem), &p)
+	restored.Add(p)
+	if err := store.Restore(restored); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if got, ok := restored.Get("sum"); !ok || got.Version != 2 || got.Title != "Sum v2" {
+		t.Errorf("Expected version 2 of sum to be current, got %+v", got)
+	}
+	if _, ok := restored.Get("other"); ok {
+		t.Error("Expected the deleted problem to stay deleted")
+	}
+	if old, ok := restored.Version("other", 1); !ok || old.Title != "Other" {
+		t.Errorf("Expected the deleted problem's versions to be restored, got %+v", old)
+	}
+	if versions := restored.Versions("sum"); len(versions) != 2 || versions[0].Version != 1 {
+		t.Errorf("Expected both versions of sum in order, got %+v", versions)
+	}
+
+	// Saving again undeletes the problem
+	store.Save(Problem{ID: "other", Title: "Back", Version: 2})
+	restored = NewBank()
+	store.Restore(restored)
+	if got, ok := restored.Get("other"); !ok || got.Title != "Back" {
+		t.Errorf("Expected the saved problem to be current again, got %+v", got)
+	}
+}
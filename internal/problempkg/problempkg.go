@@ -0,0 +1,661 @@
+// Package problempkg reads and writes problems as portable packages: a
+// directory or zip archive with the metadata in problem.yaml, the statement
+// in statement.md, one tests/<name>.in and tests/<name>.out pair per test
+// case and source files per language.
+//
+//	problem.yaml           id, title, signature, limits, comparator
+//	statement.md
+//	editorial.md
+//	hints/1.md             hints in the order they are revealed
+//	tests/01.in            the arguments, one JSON value each
+//	tests/01.out           the expected JSON value
+//	starter/solution.js    starter, reference and harness code per
+//	reference/solution.go  language, named by its extension
+//	harness/harness.php
+//	checker/checker.js     a checker program named by a comparator
+//	generator/generator.js a test generator with its seeds in problem.yaml
+//
+// Tests generated from the generator's seeds are not written, they are
+// generated again when the package is saved.
+//
+// Packages in the Kattis problem package layout, with test data under
+// data/sample and data/secret, are read as well. Their programs read stdin
+// and write stdout, so each test becomes a call of
+// solve(input: string) -> string compared token by token, unless
+// problem.yaml declares its own signature.
+package problempkg
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+)
+
+// Manifest is the metadata file of a package
+const Manifest = "problem.yaml"
+
+// MaxFileSize bounds every file read from a package, so a small archive
+// cannot expand into an arbitrarily large one
+const MaxFileSize = 8 << 20
+
+// KattisSignature is the function Kattis-layout tests are called with
+const KattisSignature = "solve(input: string) -> string"
+
+// extensions of source files per language
+var extensions = map[string]string{"node": ".js", "php": ".php", "go": ".go"}
+
+// manifest is problem.yaml of the native layout
+type manifest struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Function    string   `json:"function"`
+	Signature   string   `json:"signature"`
+	TimeLimitMs int      `json:"timeLimitMs"`
+	Tags        []string `json:"tags"`
+	Difficulty  string   `json:"difficulty"`
+	// EditorialAfter is the rejected submissions unlocking editorial.md
+	EditorialAfter int                 `json:"editorialAfter"`
+	Compare        *compareSpec        `json:"compare"`
+	Generator      *generatorSpec      `json:"generator"`
+	Tests          map[string]testSpec `json:"tests"`
+}
+
+// generatorSpec is a generator whose program is a package file
+type generatorSpec struct {
+	Source string  `json:"source"`
+	Seeds  []int64 `json:"seeds"`
+}
+
+// compareSpec is a comparator whose checker program is a package file
+type compareSpec struct {
+	Type    string  `json:"type"`
+	AbsEps  float64 `json:"absEps"`
+	RelEps  float64 `json:"relEps"`
+	Checker string  `json:"checker"`
+}
+
+// testSpec holds the settings of one test case, keyed by its name
+type testSpec struct {
+	Hidden  bool         `json:"hidden"`
+	Compare *compareSpec `json:"compare"`
+}
+
+// kattisManifest is the part of a Kattis problem.yaml that maps onto a
+// problem. Name is a string or a map from language code to name.
+type kattisManifest struct {
+	Name      any    `json:"name"`
+	Signature string `json:"signature"`
+	Limits    struct {
+		TimeLimit float64 `json:"time_limit"`
+	} `json:"limits"`
+}
+
+// Open opens a package directory or zip archive. It also returns the base
+// name of the path, which names problems whose package does not.
+func Open(name string) (fs.FS, string, error) {
+	id := strings.TrimSuffix(filepath.Base(name), ".zip")
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, "", err
+	}
+	if info.IsDir() {
+		return os.DirFS(name), id, nil
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, "", err
+	}
+	fsys, err := OpenZip(data)
+	return fsys, id, err
+}
+
+// OpenZip reads a zip archive as a package
+func OpenZip(data []byte) (fs.FS, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive: %v", err)
+	}
+	return r, nil
+}
+
+// Read reads the package in fsys, which may also hold it in its only
+// top-level directory as archives often do. id names the problem when the
+// package does not.
+func Read(fsys fs.FS, id string) (problem.Problem, error) {
+	fsys, err := root(fsys)
+	if err != nil {
+		return problem.Problem{}, err
+	}
+	if isDir(fsys, "data") && !isDir(fsys, "tests") {
+		return readKattis(fsys, id)
+	}
+
+	data, err := readFile(fsys, Manifest)
+	if err != nil {
+		return problem.Problem{}, err
+	}
+	var m manifest
+	if err := decodeYAML(data, &m); err != nil {
+		return problem.Problem{}, err
+	}
+	p := problem.Problem{
+		ID:             m.ID,
+		Title:          m.Title,
+		Function:       m.Function,
+		Signature:      m.Signature,
+		TimeLimitMs:    m.TimeLimitMs,
+		Tags:           m.Tags,
+		Difficulty:     m.Difficulty,
+		EditorialAfter: m.EditorialAfter,
+	}
+	if p.ID == "" {
+		p.ID = id
+	}
+	if p.Statement, err = readOptional(fsys, "statement.md"); err != nil {
+		return problem.Problem{}, err
+	}
+	if p.Editorial, err = readOptional(fsys, "editorial.md"); err != nil {
+		return problem.Problem{}, err
+	}
+	for i := 1; ; i++ {
+		hint, err := readOptional(fsys, fmt.Sprintf("hints/%d.md", i))
+		if err != nil {
+			return problem.Problem{}, err
+		}
+		if hint == "" {
+			break
+		}
+		p.Hints = append(p.Hints, hint)
+	}
+	if p.Compare, err = m.Compare.comparator(fsys); err != nil {
+		return problem.Problem{}, err
+	}
+	if err := readSources(fsys, &p); err != nil {
+		return problem.Problem{}, err
+	}
+	if g := m.Generator; g != nil {
+		source, err := readFile(fsys, g.Source)
+		if err != nil {
+			return problem.Problem{}, err
+		}
+		p.Generator = &problem.Generator{Language: language(g.Source), Source: string(source), Seeds: g.Seeds}
+	}
+
+	names, err := testNames(fsys, "tests")
+	if err != nil {
+		return problem.Problem{}, err
+	}
+	for _, name := range names {
+		tc, err := readTest(fsys, path.Join("tests", name))
+		if err != nil {
+			return problem.Problem{}, err
+		}
+		spec := m.Tests[name]
+		tc.Hidden = spec.Hidden
+		if tc.Compare, err = spec.Compare.comparator(fsys); err != nil {
+			return problem.Problem{}, fmt.Errorf("test %s: %v", name, err)
+		}
+		p.Tests = append(p.Tests, tc)
+	}
+	for name := range m.Tests {
+		if !slices.Contains(names, name) {
+			return problem.Problem{}, fmt.Errorf("%s lists unknown test %s", Manifest, name)
+		}
+	}
+	return p, nil
+}
+
+// readKattis reads a package in the Kattis layout
+func readKattis(fsys fs.FS, id string) (problem.Problem, error) {
+	var m kattisManifest
+	if data, err := readOptional(fsys, Manifest); err != nil {
+		return problem.Problem{}, err
+	} else if err := decodeYAML([]byte(data), &m); err != nil {
+		return problem.Problem{}, err
+	}
+	p := problem.Problem{
+		ID:          id,
+		Title:       kattisName(m.Name, id),
+		Signature:   m.Signature,
+		TimeLimitMs: int(m.Limits.TimeLimit * 1000),
+	}
+	if p.Signature == "" {
+		p.Signature = KattisSignature
+		p.Compare = &problem.Comparator{Type: problem.CompareWhitespace}
+	}
+	for _, name := range []string{"statement/problem.en.md", "statement/problem.md", "problem_statement/problem.en.md", "problem_statement/problem.md"} {
+		statement, err := readOptional(fsys, name)
+		if err != nil {
+			return problem.Problem{}, err
+		}
+		if statement != "" {
+			p.Statement = statement
+			break
+		}
+	}
+	if err := readSources(fsys, &p); err != nil {
+		return problem.Problem{}, err
+	}
+
+	for _, group := range []string{"sample", "secret"} {
+		dir := path.Join("data", group)
+		var dirs []string
+		err := fs.WalkDir(fsys, dir, func(name string, d fs.DirEntry, err error) error {
+			if err == nil && d.IsDir() {
+				dirs = append(dirs, name)
+			}
+			if errors.Is(err, fs.ErrNotExist) && name == dir {
+				return fs.SkipDir
+			}
+			return err
+		})
+		if err != nil {
+			return problem.Problem{}, err
+		}
+		for _, dir := range dirs {
+			names, err := testNames(fsys, dir)
+			if err != nil {
+				return problem.Problem{}, err
+			}
+			for _, name := range names {
+				input, err := readFile(fsys, path.Join(dir, name+".in"))
+				if err != nil {
+					return problem.Problem{}, err
+				}
+				answer, err := readFile(fsys, path.Join(dir, name+".ans"))
+				if err != nil {
+					return problem.Problem{}, err
+				}
+				tc := problem.TestCase{Hidden: group == "secret"}
+				if p.Signature == KattisSignature {
+					arg, _ := json.Marshal(string(input))
+					tc.Args = []json.RawMessage{arg}
+					tc.Expected, _ = json.Marshal(string(answer))
+				} else if tc.Args, err = decodeArgs(input); err != nil {
+					return problem.Problem{}, fmt.Errorf("%s/%s.in: %v", dir, name, err)
+				} else if tc.Expected, err = decodeValue(answer); err != nil {
+					return problem.Problem{}, fmt.Errorf("%s/%s.ans: %v", dir, name, err)
+				}
+				p.Tests = append(p.Tests, tc)
+			}
+		}
+	}
+	return p, nil
+}
+
+// kattisName picks the English name of a Kattis problem
+func kattisName(name any, id string) string {
+	switch name := name.(type) {
+	case string:
+		return name
+	case map[string]any:
+		if en, ok := name["en"].(string); ok {
+			return en
+		}
+		langs := make([]string, 0, len(name))
+		for lang := range name {
+			langs = append(langs, lang)
+		}
+		sort.Strings(langs)
+		for _, lang := range langs {
+			if s, ok := name[lang].(string); ok {
+				return s
+			}
+		}
+	}
+	return id
+}
+
+// readSources reads the starter, reference and harness code per language
+func readSources(fsys fs.FS, p *problem.Problem) error {
+	dirs := map[string]*map[string]string{
+		"starter/solution":   &p.Starter,
+		"reference/solution": &p.Reference,
+		"harness/harness":    &p.Harness,
+	}
+	for prefix, sources := range dirs {
+		for _, lang := range problem.Languages {
+			source, err := readOptional(fsys, prefix+extensions[lang])
+			if err != nil {
+				return err
+			}
+			if source == "" {
+				continue
+			}
+			if *sources == nil {
+				*sources = map[string]string{}
+			}
+			(*sources)[lang] = source
+		}
+	}
+	return nil
+}
+
+// comparator builds the comparator, reading its checker program
+func (c *compareSpec) comparator(fsys fs.FS) (*problem.Comparator, error) {
+	if c == nil {
+		return nil, nil
+	}
+	cmp := &problem.Comparator{Type: c.Type, AbsEps: c.AbsEps, RelEps: c.RelEps}
+	if c.Checker != "" {
+		lang := language(c.Checker)
+		if lang == "" {
+			return nil, fmt.Errorf("checker %s has no known source extension", c.Checker)
+		}
+		source, err := readFile(fsys, c.Checker)
+		if err != nil {
+			return nil, err
+		}
+		cmp.Checker = &problem.Checker{Language: lang, Source: string(source)}
+	}
+	return cmp, nil
+}
+
+// language returns the language of a source file by its extension
+func language(name string) string {
+	for lang, ext := range extensions {
+		if path.Ext(name) == ext {
+			return lang
+		}
+	}
+	return ""
+}
+
+// readTest reads the <name>.in and <name>.out pair of a test case
+func readTest(fsys fs.FS, name string) (problem.TestCase, error) {
+	input, err := readFile(fsys, name+".in")
+	if err != nil {
+		return problem.TestCase{}, err
+	}
+	output, err := readFile(fsys, name+".out")
+	if err != nil {
+		return problem.TestCase{}, err
+	}
+	args, err := decodeArgs(input)
+	if err != nil {
+		return problem.TestCase{}, fmt.Errorf("%s.in: %v", name, err)
+	}
+	expected, err := decodeValue(output)
+	if err != nil {
+		return problem.TestCase{}, fmt.Errorf("%s.out: %v", name, err)
+	}
+	return problem.TestCase{Args: args, Expected: expected}, nil
+}
+
+// decodeArgs reads a sequence of JSON values separated by whitespace
+func decodeArgs(data []byte) ([]json.RawMessage, error) {
+	args := []json.RawMessage{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var arg json.RawMessage
+		if err := dec.Decode(&arg); err == io.EOF {
+			return args, nil
+		} else if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+}
+
+// decodeValue reads exactly one JSON value
+func decodeValue(data []byte) (json.RawMessage, error) {
+	values, err := decodeArgs(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("expected one JSON value, got %d", len(values))
+	}
+	return values[0], nil
+}
+
+// testNames returns the names of the tests in dir that have an input file,
+// in natural order so 2 sorts before 10
+func testNames(fsys fs.FS, dir string) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".in"); ok && !e.IsDir() {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, errA := strconv.Atoi(names[i])
+		b, errB := strconv.Atoi(names[j])
+		if errA == nil && errB == nil && a != b {
+			return a < b
+		}
+		return names[i] < names[j]
+	})
+	return names, nil
+}
+
+// root returns the directory holding the package
+func root(fsys fs.FS) (fs.FS, error) {
+	if _, err := fs.Stat(fsys, Manifest); err == nil || isDir(fsys, "data") || isDir(fsys, "tests") {
+		return fsys, nil
+	}
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return fs.Sub(fsys, entries[0].Name())
+	}
+	return nil, fmt.Errorf("package has no %s", Manifest)
+}
+
+func isDir(fsys fs.FS, name string) bool {
+	info, err := fs.Stat(fsys, name)
+	return err == nil && info.IsDir()
+}
+
+// readFile reads a package file of at most MaxFileSize bytes
+func readFile(fsys fs.FS, name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, MaxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxFileSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", name, MaxFileSize)
+	}
+	return data, nil
+}
+
+// readOptional reads a package file, returning "" when it does not exist
+func readOptional(fsys fs.FS, name string) (string, error) {
+	data, err := readFile(fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	return string(data), err
+}
+
+// decodeYAML decodes YAML into v through its JSON form
+func decodeYAML(data []byte, v any) error {
+	doc, err := parseYAML(data)
+	if err != nil {
+		return err
+	}
+	if _, ok := doc.(map[string]any); !ok {
+		return fmt.Errorf("%s must be a mapping", Manifest)
+	}
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("%s: %v", Manifest, err)
+	}
+	return nil
+}
+
+// Files returns the files of a problem's package by path
+func Files(p problem.Problem) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	if p.Statement != "" {
+		files["statement.md"] = []byte(p.Statement)
+	}
+	if p.Editorial != "" {
+		files["editorial.md"] = []byte(p.Editorial)
+	}
+	for i, hint := range p.Hints {
+		files[fmt.Sprintf("hints/%d.md", i+1)] = []byte(hint)
+	}
+	fields := []yamlField{{"id", p.ID}, {"title", p.Title}, {"function", p.Function}}
+	if p.Signature != "" {
+		fields = append(fields, yamlField{"signature", p.Signature})
+	}
+	if p.TimeLimitMs > 0 {
+		fields = append(fields, yamlField{"timeLimitMs", p.TimeLimitMs})
+	}
+	if len(p.Tags) > 0 {
+		fields = append(fields, yamlField{"tags", p.Tags})
+	}
+	if p.Difficulty != "" {
+		fields = append(fields, yamlField{"difficulty", p.Difficulty})
+	}
+	if p.EditorialAfter > 0 {
+		fields = append(fields, yamlField{"editorialAfter", p.EditorialAfter})
+	}
+	if p.Compare != nil {
+		fields = append(fields, yamlField{"compare", compareFields(*p.Compare, "checker/checker", files)})
+	}
+
+	if g := p.Generator; g != nil {
+		name := "generator/generator" + extensions[g.Language]
+		files[name] = []byte(g.Source)
+		fields = append(fields, yamlField{"generator", []yamlField{{"source", name}, {"seeds", g.Seeds}}})
+	}
+
+	written := p.Written()
+	width := max(2, len(strconv.Itoa(len(written))))
+	var tests []yamlField
+	for i, tc := range written {
+		name := fmt.Sprintf("%0*d", width, i+1)
+		var args bytes.Buffer
+		for _, arg := range tc.Args {
+			if err := json.Compact(&args, arg); err != nil {
+				return nil, fmt.Errorf("test %d: %v", i, err)
+			}
+			args.WriteString("\n")
+		}
+		var expected bytes.Buffer
+		if err := json.Compact(&expected, tc.Expected); err != nil {
+			return nil, fmt.Errorf("test %d: %v", i, err)
+		}
+		expected.WriteString("\n")
+		files["tests/"+name+".in"] = args.Bytes()
+		files["tests/"+name+".out"] = expected.Bytes()
+
+		var spec []yamlField
+		if tc.Hidden {
+			spec = append(spec, yamlField{"hidden", true})
+		}
+		if tc.Compare != nil {
+			spec = append(spec, yamlField{"compare", compareFields(*tc.Compare, "checker/"+name, files)})
+		}
+		if spec != nil {
+			tests = append(tests, yamlField{name, spec})
+		}
+	}
+	if tests != nil {
+		fields = append(fields, yamlField{"tests", tests})
+	}
+	var manifest strings.Builder
+	writeYAML(&manifest, fields, 0)
+	files[Manifest] = []byte(manifest.String())
+
+	for prefix, sources := range map[string]map[string]string{
+		"starter/solution":   p.Starter,
+		"reference/solution": p.Reference,
+		"harness/harness":    p.Harness,
+	} {
+		for lang, source := range sources {
+			if ext, ok := extensions[lang]; ok && source != "" {
+				files[prefix+ext] = []byte(source)
+			}
+		}
+	}
+	return files, nil
+}
+
+// compareFields writes a comparator, adding its checker program to files
+func compareFields(c problem.Comparator, checker string, files map[string][]byte) []yamlField {
+	fields := []yamlField{{"type", c.Type}}
+	if c.AbsEps != 0 {
+		fields = append(fields, yamlField{"absEps", c.AbsEps})
+	}
+	if c.RelEps != 0 {
+		fields = append(fields, yamlField{"relEps", c.RelEps})
+	}
+	if c.Checker != nil {
+		name := checker + extensions[c.Checker.Language]
+		files[name] = []byte(c.Checker.Source)
+		fields = append(fields, yamlField{"checker", name})
+	}
+	return fields
+}
+
+// WriteZip writes a problem's package as a zip archive
+func WriteZip(w io.Writer, p problem.Problem) error {
+	files, err := Files(p)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	zw := zip.NewWriter(w)
+	for _, name := range names {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(files[name]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// WriteDir writes a problem's package into dir, creating it if needed
+func WriteDir(dir string, p problem.Problem) error {
+	files, err := Files(p)
+	if err != nil {
+		return err
+	}
+	for name, data := range files {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
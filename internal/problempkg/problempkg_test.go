@@ -0,0 +1,134 @@
+package problempkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+)
+
+func TestRoundTrip(t *testing.T) {
+	seed := int64(3)
+	p := problem.Problem{
+		ID:             "sum",
+		Title:          "Sum",
+		Statement:      "Add the numbers",
+		Function:       "sum",
+		Signature:      "sum(xs: []int) -> int",
+		TimeLimitMs:    1500,
+		Tags:           []string{"arrays", "math"},
+		Difficulty:     "easy",
+		Hints:          []string{"Loop over `xs`", "Keep a running total"},
+		Editorial:      "Add every number to an accumulator.",
+		EditorialAfter: 3,
+		Compare:        &problem.Comparator{Type: problem.CompareFloat, AbsEps: 1e-6},
+		Starter:        map[string]string{"node": "function sum(xs) {}"},
+		Reference:      map[string]string{"node": "const sum = (xs) => xs.reduce((a, b) => a + b, 0)"},
+		Generator:      &problem.Generator{Language: "php", Source: "<?php // generate", Seeds: []int64{3, 4}},
+		Tests: []problem.TestCase{
+			{Args: []json.RawMessage{json.RawMessage("[1, 2]")}, Expected: json.RawMessage("3")},
+			{Args: []json.RawMessage{json.RawMessage("[]")}, Expected: json.RawMessage("0"), Hidden: true,
+				Compare: &problem.Comparator{Type: problem.CompareChecker, Checker: &problem.Checker{Language: "go", Source: "package main"}}},
+			{Args: []json.RawMessage{json.RawMessage("[7]")}, Expected: json.RawMessage("7"), Hidden: true, Seed: &seed},
+		},
+	}
+	var zipped bytes.Buffer
+	if err := WriteZip(&zipped, p); err != nil {
+		t.Fatalf("WriteZip failed: %v", err)
+	}
+	fsys, err := OpenZip(zipped.Bytes())
+	if err != nil {
+		t.Fatalf("OpenZip failed: %v", err)
+	}
+	got, err := Read(fsys, "ignored")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	// Generated tests are generated again on save
+	p.Tests = p.Written()
+	p.Tests[0].Args[0] = json.RawMessage("[1,2]")
+	if !reflect.DeepEqual(got, p) {
+		t.Errorf("Expected %+v, but got %+v", p, got)
+	}
+
+	files, _ := Files(p)
+	if string(files["hints/2.md"]) != "Keep a running total" || !strings.Contains(string(files["problem.yaml"]), "editorialAfter: 3") {
+		t.Errorf("Expected hint files and editorialAfter, but got %v", files)
+	}
+	if string(files["tests/02.in"]) != "[]\n" || string(files["checker/02.go"]) != "package main" || files["tests/03.in"] != nil ||
+		!strings.Contains(string(files["problem.yaml"]), "seeds: [3, 4]") || !strings.Contains(string(files["problem.yaml"]), "tags: [arrays, math]") {
+		t.Errorf("Expected tests and checker files, but got %v", files)
+	}
+}
+
+func TestRead(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pkg/problem.yaml":        {Data: []byte("title: Echo\nsignature: \"echo(s: string, n: int) -> string\"\ntests:\n  \"10\": {hidden: true}\n")},
+		"pkg/tests/2.in":          {Data: []byte("\"a\"\n2\n")},
+		"pkg/tests/2.out":         {Data: []byte("\"aa\"")},
+		"pkg/tests/10.in":         {Data: []byte("\"b\" 1")},
+		"pkg/tests/10.out":        {Data: []byte("\"b\"")},
+		"pkg/harness/harness.php": {Data: []byte("<?php echo echo_({{args}});")},
+	}
+	p, err := Read(fsys, "echo")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if p.ID != "echo" || len(p.Tests) != 2 || len(p.Tests[0].Args) != 2 || p.Tests[0].Hidden || !p.Tests[1].Hidden {
+		t.Errorf("Expected tests in natural order with the package name as id, but got %+v", p)
+	}
+	if p.Harness["php"] == "" {
+		t.Errorf("Expected the php harness, but got %+v", p.Harness)
+	}
+
+	invalid := map[string]fstest.MapFS{
+		"no manifest":    {"tests/1.in": {Data: []byte("1")}, "tests/1.out": {Data: []byte("1")}},
+		"missing output": {"problem.yaml": {Data: []byte("id: x\n")}, "tests/1.in": {Data: []byte("1")}},
+		"two outputs":    {"problem.yaml": {Data: []byte("id: x\n")}, "tests/1.in": {Data: []byte("1")}, "tests/1.out": {Data: []byte("1 2")}},
+		"unknown test":   {"problem.yaml": {Data: []byte("id: x\ntests:\n  \"2\": {hidden: true}\n")}, "tests/1.in": {Data: []byte("1")}, "tests/1.out": {Data: []byte("1")}},
+		"bad checker":    {"problem.yaml": {Data: []byte("id: x\ncompare: {type: checker, checker: checker.rb}\n")}, "tests/1.in": {Data: []byte("1")}, "tests/1.out": {Data: []byte("1")}},
+	}
+	for name, fsys := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Read(fsys, "x"); err == nil {
+				t.Error("Expected error, but got none")
+			}
+		})
+	}
+}
+
+func TestReadKattis(t *testing.T) {
+	fsys := fstest.MapFS{
+		"problem.yaml":                     {Data: []byte("name:\n  en: Hello\n  de: Hallo\nlimits:\n  time_limit: 2\n")},
+		"problem_statement/problem.en.md":  {Data: []byte("Say hello")},
+		"data/sample/1.in":                 {Data: []byte("World\n")},
+		"data/sample/1.ans":                {Data: []byte("Hello World!\n")},
+		"data/secret/group1/a.in":          {Data: []byte("Go\n")},
+		"data/secret/group1/a.ans":         {Data: []byte("Hello Go!\n")},
+		"submissions/accepted/solution.py": {Data: []byte("print('Hello', input() + '!')")},
+		"reference/solution.js":            {Data: []byte("const solve = (input) => `Hello ${input.trim()}!`")},
+	}
+	p, err := Read(fsys, "hello")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if p.ID != "hello" || p.Title != "Hello" || p.Statement != "Say hello" || p.TimeLimitMs != 2000 || p.Signature != KattisSignature {
+		t.Errorf("Unexpected problem: %+v", p)
+	}
+	if len(p.Tests) != 2 || string(p.Tests[0].Args[0]) != `"World\n"` || string(p.Tests[1].Expected) != `"Hello Go!\n"` || !p.Tests[1].Hidden {
+		t.Errorf("Expected the sample and secret tests, but got %+v", p.Tests)
+	}
+	if p.Compare == nil || p.Compare.Type != problem.CompareWhitespace || p.Reference["node"] == "" {
+		t.Errorf("Expected whitespace comparison and the reference, but got %+v", p)
+	}
+	if _, err := problem.Prepare(p); err != nil {
+		t.Errorf("Expected a valid problem, but got %v", err)
+	}
+	if !strings.Contains(p.Reference["node"], "solve") {
+		t.Errorf("Unexpected reference: %q", p.Reference["node"])
+	}
+}
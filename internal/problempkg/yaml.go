@@ -0,0 +1,452 @@
+package problempkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseYAML reads the subset of YAML used by problem packages: block
+// mappings and sequences, flow sequences and mappings of scalars, literal
+// and folded block scalars, quoted and plain scalars and comments. Anchors,
+// tags and multiple documents are not supported.
+func parseYAML(data []byte) (any, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.TrimPrefix(text, "---\n")
+	p := &yamlParser{lines: strings.Split(text, "\n")}
+	indent, ok, err := p.next()
+	if err != nil || !ok {
+		return map[string]any{}, err
+	}
+	v, err := p.block(indent)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok, _ := p.next(); ok {
+		return nil, p.errorf("unexpected indentation")
+	}
+	return v, nil
+}
+
+type yamlParser struct {
+	lines []string
+	pos   int
+}
+
+func (p *yamlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("problem.yaml line %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+// next skips blank and comment lines and returns the indentation of the next
+// significant line
+func (p *yamlParser) next() (int, bool, error) {
+	for ; p.pos < len(p.lines); p.pos++ {
+		line := p.lines[p.pos]
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || line == "..." {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return 0, false, p.errorf("tabs are not allowed for indentation")
+		}
+		return len(line) - len(trimmed), true, nil
+	}
+	return 0, false, nil
+}
+
+func (p *yamlParser) text() string {
+	return strings.TrimSpace(p.lines[p.pos])
+}
+
+func isItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// block reads the mapping or sequence starting at the current line
+func (p *yamlParser) block(indent int) (any, error) {
+	if isItem(p.text()) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) mapping(indent int) (any, error) {
+	m := map[string]any{}
+	for {
+		current, ok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok || current < indent {
+			return m, nil
+		}
+		if current > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+		text := p.text()
+		if isItem(text) {
+			return m, nil
+		}
+		key, rest, ok := splitKey(text)
+		if !ok {
+			return nil, p.errorf("expected key: value")
+		}
+		if _, exists := m[key]; exists {
+			return nil, p.errorf("duplicate key %s", key)
+		}
+		p.pos++
+		v, err := p.value(rest, indent, true)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+}
+
+func (p *yamlParser) sequence(indent int) (any, error) {
+	list := []any{}
+	for {
+		current, ok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok || current < indent || !isItem(p.text()) {
+			return list, nil
+		}
+		if current > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+		line := p.lines[p.pos]
+		item := strings.TrimLeft(strings.TrimPrefix(strings.TrimLeft(line, " "), "-"), " ")
+		if _, _, isMap := splitKey(item); isMap && !strings.HasPrefix(item, "[") && !strings.HasPrefix(item, "{") {
+			// "- key: value" starts a mapping indented to the key
+			p.lines[p.pos] = strings.Repeat(" ", len(line)-len(item)) + item
+			v, err := p.mapping(len(line) - len(item))
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			continue
+		}
+		p.pos++
+		v, err := p.value(item, indent, false)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+}
+
+// value reads what follows a key or an item marker: a scalar on the same
+// line, a block scalar or a nested block on the following lines. Sequences
+// may sit at the indentation of their mapping key.
+func (p *yamlParser) value(rest string, indent int, inMapping bool) (any, error) {
+	rest = stripComment(rest)
+	switch {
+	case rest == "":
+		current, ok, err := p.next()
+		if err != nil || !ok {
+			return nil, err
+		}
+		if current > indent || (inMapping && current == indent && isItem(p.text())) {
+			return p.block(current)
+		}
+		return nil, nil
+	case strings.HasPrefix(rest, "|") || strings.HasPrefix(rest, ">"):
+		return p.blockScalar(rest, indent), nil
+	}
+	v, err := parseScalar(rest)
+	if err != nil {
+		return nil, p.errorf("%v", err)
+	}
+	return v, nil
+}
+
+// blockScalar reads a literal (|) or folded (>) block, with the optional
+// strip (-) or keep (+) chomping indicator
+func (p *yamlParser) blockScalar(header string, indent int) string {
+	var lines []string
+	blockIndent := -1
+	for ; p.pos < len(p.lines); p.pos++ {
+		line := p.lines[p.pos]
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" {
+			lines = append(lines, "")
+			continue
+		}
+		current := len(line) - len(trimmed)
+		if current <= indent {
+			break
+		}
+		if blockIndent < 0 {
+			blockIndent = current
+		}
+		if current < blockIndent {
+			break
+		}
+		lines = append(lines, line[blockIndent:])
+	}
+	content := 0
+	for i, line := range lines {
+		if line != "" {
+			content = i + 1
+		}
+	}
+	trailing := len(lines) - content
+	lines = lines[:content]
+
+	var text string
+	if strings.HasPrefix(header, ">") {
+		var b strings.Builder
+		for i, line := range lines {
+			switch {
+			case i == 0:
+			case line == "" || lines[i-1] == "":
+				b.WriteString("\n")
+			default:
+				b.WriteString(" ")
+			}
+			b.WriteString(line)
+		}
+		text = b.String()
+	} else {
+		text = strings.Join(lines, "\n")
+	}
+	switch {
+	case strings.Contains(header, "-"):
+		return text
+	case strings.Contains(header, "+"):
+		return text + "\n" + strings.Repeat("\n", trailing)
+	case text == "":
+		return ""
+	}
+	return text + "\n"
+}
+
+// splitKey splits "key: value" outside of quotes
+func splitKey(text string) (string, string, bool) {
+	if text == "" {
+		return "", "", false
+	}
+	if strings.ContainsAny(text[:1], `"'`) {
+		end := closingQuote(text)
+		if end < 0 || !strings.HasPrefix(text[end+1:], ":") {
+			return "", "", false
+		}
+		key, err := parseScalar(text[:end+1])
+		if err != nil {
+			return "", "", false
+		}
+		rest := text[end+2:]
+		if rest != "" && rest[0] != ' ' {
+			return "", "", false
+		}
+		return fmt.Sprint(key), strings.TrimSpace(rest), true
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i == len(text)-1 || text[i+1] == ' ') {
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), true
+		}
+		if text[i] == '#' && i > 0 && text[i-1] == ' ' {
+			break
+		}
+	}
+	return "", "", false
+}
+
+// closingQuote returns the index of the quote closing the string that
+// starts text
+func closingQuote(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] == quote && quote == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// stripComment removes a trailing comment outside of quotes
+func stripComment(text string) string {
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '"', '\'':
+			if i == 0 || text[i-1] == ' ' || text[i-1] == '[' || text[i-1] == '{' || text[i-1] == ',' {
+				if end := closingQuote(text[i:]); end > 0 {
+					i += end
+				}
+			}
+		case '#':
+			if i == 0 || text[i-1] == ' ' {
+				return strings.TrimSpace(text[:i])
+			}
+		}
+	}
+	return strings.TrimSpace(text)
+}
+
+func parseScalar(text string) (any, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+	switch text[0] {
+	case '"':
+		if closingQuote(text) != len(text)-1 {
+			return nil, fmt.Errorf("unterminated string %s", text)
+		}
+		var s string
+		if err := json.Unmarshal([]byte(text), &s); err != nil {
+			return strconv.Unquote(text)
+		}
+		return s, nil
+	case '\'':
+		if closingQuote(text) != len(text)-1 {
+			return nil, fmt.Errorf("unterminated string %s", text)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	case '[', '{':
+		return parseFlow(text)
+	}
+	switch text {
+	case "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil && !strings.ContainsAny(text, "xXpP_") {
+		return f, nil
+	}
+	return text, nil
+}
+
+// parseFlow reads a flow sequence or mapping, [a, b] or {k: v}
+func parseFlow(text string) (any, error) {
+	closing := map[byte]byte{'[': ']', '{': '}'}[text[0]]
+	if text[len(text)-1] != closing {
+		return nil, fmt.Errorf("unterminated flow collection %s", text)
+	}
+	var parts []string
+	depth, start := 0, 1
+	for i := 1; i < len(text)-1; i++ {
+		switch text[i] {
+		case '"', '\'':
+			if end := closingQuote(text[i:]); end > 0 {
+				i += end
+			}
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, text[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if last := strings.TrimSpace(text[start : len(text)-1]); last != "" {
+		parts = append(parts, last)
+	}
+
+	if text[0] == '[' {
+		list := []any{}
+		for _, part := range parts {
+			v, err := parseScalar(part)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	}
+	m := map[string]any{}
+	for _, part := range parts {
+		key, rest, ok := splitKey(strings.TrimSpace(part))
+		if !ok {
+			return nil, fmt.Errorf("expected key: value in %s", text)
+		}
+		v, err := parseScalar(rest)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	return m, nil
+}
+
+// yamlField is one key of a mapping written in order
+type yamlField struct {
+	Key   string
+	Value any
+}
+
+// writeYAML writes an ordered mapping. Values are scalars, string or int64
+// slices, nested ordered mappings or maps, which are written with sorted
+// keys.
+func writeYAML(b *strings.Builder, fields []yamlField, indent int) {
+	pad := strings.Repeat(" ", indent)
+	for _, f := range fields {
+		switch v := f.Value.(type) {
+		case []yamlField:
+			fmt.Fprintf(b, "%s%s:\n", pad, yamlScalar(f.Key))
+			writeYAML(b, v, indent+2)
+		case map[string]any:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			nested := make([]yamlField, len(keys))
+			for i, k := range keys {
+				nested[i] = yamlField{k, v[k]}
+			}
+			fmt.Fprintf(b, "%s%s:\n", pad, yamlScalar(f.Key))
+			writeYAML(b, nested, indent+2)
+		case []string:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = yamlScalar(item)
+			}
+			fmt.Fprintf(b, "%s%s: [%s]\n", pad, yamlScalar(f.Key), strings.Join(items, ", "))
+		case []int64:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = strconv.FormatInt(item, 10)
+			}
+			fmt.Fprintf(b, "%s%s: [%s]\n", pad, yamlScalar(f.Key), strings.Join(items, ", "))
+		default:
+			fmt.Fprintf(b, "%s%s: %s\n", pad, yamlScalar(f.Key), yamlScalar(v))
+		}
+	}
+}
+
+// yamlScalar formats a scalar, quoting strings that would read back as
+// another value or break the line structure
+func yamlScalar(v any) string {
+	s, ok := v.(string)
+	if !ok {
+		if f, isFloat := v.(float64); isFloat {
+			return strconv.FormatFloat(f, 'g', -1, 64)
+		}
+		return fmt.Sprint(v)
+	}
+	if parsed, err := parseScalar(s); err == nil && parsed == s && s == strings.TrimSpace(s) &&
+		!strings.ContainsAny(s, ":#\n\"'") && !strings.ContainsAny(s[:1], "-[]{},&*!|>%@`") {
+		return s
+	}
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
@@ -0,0 +1,82 @@
+package problempkg
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	const doc = `# a problem
+id: two-sum
+title: "Two: Sum" # quoted
+limits:
+  time_limit: 1.5
+  memory: 256
+tags: [arrays, 'hash map']
+keywords:
+- easy
+-
+  - deep
+- name: nested
+  weight: 2
+statement: |
+  First line
+
+  # not a comment
+folded: >-
+  one
+  two
+empty:
+flag: true
+`
+	got, err := parseYAML([]byte(doc))
+	if err != nil {
+		t.Fatalf("parseYAML failed: %v", err)
+	}
+	want := map[string]any{
+		"id":        "two-sum",
+		"title":     "Two: Sum",
+		"limits":    map[string]any{"time_limit": 1.5, "memory": int64(256)},
+		"tags":      []any{"arrays", "hash map"},
+		"keywords":  []any{"easy", []any{"deep"}, map[string]any{"name": "nested", "weight": int64(2)}},
+		"statement": "First line\n\n# not a comment\n",
+		"folded":    "one two",
+		"empty":     nil,
+		"flag":      true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %#v, but got %#v", want, got)
+	}
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, doc := range []string{"a: 1\n  b: 2\n", "a: 1\na: 2\n", "just text\n", "a: \"open\n", "a:\n\tb: 1\n"} {
+			if _, err := parseYAML([]byte(doc)); err == nil {
+				t.Errorf("Expected error for %q, but got none", doc)
+			}
+		}
+	})
+}
+
+func TestWriteYAML(t *testing.T) {
+	var b strings.Builder
+	writeYAML(&b, []yamlField{
+		{"id", "x"},
+		{"title", "A: B"},
+		{"count", 3},
+		{"eps", 1e-06},
+		{"tests", []yamlField{{"01", []yamlField{{"hidden", true}}}}},
+		{"tags", []string{"true", "plain"}},
+	}, 0)
+	want := "id: x\ntitle: \"A: B\"\ncount: 3\neps: 1e-06\ntests:\n  \"01\":\n    hidden: true\ntags: [\"true\", plain]\n"
+	if b.String() != want {
+		t.Errorf("Expected %q, but got %q", want, b.String())
+	}
+	got, err := parseYAML([]byte(b.String()))
+	if err != nil {
+		t.Fatalf("parseYAML failed: %v", err)
+	}
+	if m := got.(map[string]any); m["title"] != "A: B" || m["eps"] != 1e-06 || m["tests"].(map[string]any)["01"] == nil {
+		t.Errorf("Expected the written YAML to read back, but got %#v", got)
+	}
+}
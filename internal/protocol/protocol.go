@@ -0,0 +1,140 @@
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// Version of the records harnesses write. Parsers reject records of any
+// other version so a stale harness fails loudly instead of being misread.
+const Version = 1
+
+// ResultsEnv names the environment variable holding the path of the results
+// file. Harnesses append one JSON record per line to it, keeping their
+// output apart from whatever the user's code prints.
+const ResultsEnv = "STQ_RESULTS"
+
+// ResultsFile is the name of the results file in the scratch directory
+const ResultsFile = "stq_results.jsonl"
+
+// TestsEnv names the environment variable holding the path of the tests
+// file. Generated harnesses read the tests to run from it, one input per
+// line, so a program built once can run any of them.
+const TestsEnv = "STQ_TESTS"
+
+// TestsFile is the name of the tests file in the scratch directory
+const TestsFile = "stq_tests.jsonl"
+
+// Record types
+const (
+	// TypeResult carries the value returned by the user's function
+	TypeResult = "result"
+	// TypeVerdict carries a checker's decision as {"ok": bool, "message": string}
+	TypeVerdict = "verdict"
+	// TypeError carries the message of an exception the function raised
+	TypeError = "error"
+	// TypeStdout carries what the function printed during a test, harnesses
+	// running several tests in one process capture it per test
+	TypeStdout = "stdout"
+	// TypeInput carries the arguments a generator produced for a seed
+	TypeInput = "input"
+	// TypeTest carries the outcome of a user-written unit test as
+	// {"name", "status", "failure", "output"}
+	TypeTest = "test"
+)
+
+// Record is one line of the results file
+type Record struct {
+	V     int             `json:"v"`
+	Type  string          `json:"type"`
+	Test  int             `json:"test"`
+	Value json.RawMessage `json:"value,omitempty"`
+	// DurationMs is how long the function ran, measured by the harness
+	DurationMs float64 `json:"durationMs,omitempty"`
+	// Nonce is copied from the tests file by generated harnesses
+	Nonce string `json:"nonce,omitempty"`
+}
+
+// Input is one line of the tests file
+type Input struct {
+	V    int               `json:"v"`
+	Test int               `json:"test"`
+	Args []json.RawMessage `json:"args"`
+	// Nonce is the run's secret. Generated harnesses take it, and remove the
+	// tests file, before the user's code runs and sign every record with it,
+	// so records the user's code appends to the results file are told apart.
+	Nonce string `json:"nonce,omitempty"`
+}
+
+// NewNonce returns a random nonce for one run of a generated harness
+func NewNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// EncodeInputs writes the content of a tests file
+func EncodeInputs(inputs []Input) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, in := range inputs {
+		in.V = Version
+		if err := enc.Encode(in); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Parse reads every record of a results file. A missing value is read as
+// JSON null.
+func Parse(data []byte) ([]Record, error) {
+	records := []Record{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal(text, &r); err != nil {
+			return nil, fmt.Errorf("results line %d: %v", line, err)
+		}
+		if r.V != Version {
+			return nil, fmt.Errorf("results line %d: unsupported protocol version %d", line, r.V)
+		}
+		if len(r.Value) == 0 {
+			r.Value = json.RawMessage("null")
+		}
+		records = append(records, r)
+	}
+	return records, scanner.Err()
+}
+
+// Signed returns the records carrying the nonce and how many did not
+func Signed(records []Record, nonce string) ([]Record, int) {
+	signed := make([]Record, 0, len(records))
+	for _, r := range records {
+		if r.Nonce == nonce {
+			signed = append(signed, r)
+		}
+	}
+	return signed, len(records) - len(signed)
+}
+
+// Find returns the record of the given type for a test
+func Find(records []Record, typ string, test int) (Record, bool) {
+	for _, r := range records {
+		if r.Type == typ && r.Test == test {
+			return r, true
+		}
+	}
+	return Record{}, false
+}
@@ -0,0 +1,89 @@
+package protocol
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	data := []byte(`{"v": 1, "type": "result", "test": 0, "value": "1"}
+
+{"v": 1, "type": "result", "test": 1}
+`)
+	records, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	if string(records[0].Value) != `"1"` {
+		t.Errorf("Expected value \"1\", got %s", records[0].Value)
+	}
+	if string(records[1].Value) != "null" {
+		t.Errorf("Expected missing value to read as null, got %s", records[1].Value)
+	}
+
+	r, ok := Find(records, TypeResult, 1)
+	if !ok || r.Test != 1 {
+		t.Errorf("Expected to find the record of test 1, got %+v", r)
+	}
+	if _, ok := Find(records, TypeResult, 2); ok {
+		t.Error("Did not expect a record for test 2")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	testCases := map[string]string{
+		"invalid json":    "not json\n",
+		"unknown version": `{"v": 2, "type": "result", "test": 0, "value": 1}`,
+		"missing version": `{"type": "result", "test": 0, "value": 1}`,
+	}
+	for name, data := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse([]byte(data)); err == nil || !strings.Contains(err.Error(), "line 1") {
+				t.Errorf("Expected error on line 1, but got: %v", err)
+			}
+		})
+	}
+
+	if records, err := Parse(nil); err != nil || len(records) != 0 {
+		t.Errorf("Expected no records for an empty file, got %v, %v", records, err)
+	}
+}
+
+func TestEncodeInputs(t *testing.T) {
+	data, err := EncodeInputs([]Input{
+		{Test: 0, Args: []json.RawMessage{json.RawMessage("1")}},
+		{Test: 3, Args: []json.RawMessage{json.RawMessage(`"a"`), json.RawMessage("[]")}},
+	})
+	if err != nil {
+		t.Fatalf("EncodeInputs failed: %v", err)
+	}
+	expected := `{"v":1,"test":0,"args":[1]}` + "\n" + `{"v":1,"test":3,"args":["a",[]]}` + "\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, but got %q", expected, data)
+	}
+}
+
+func TestSigned(t *testing.T) {
+	nonce, err := NewNonce()
+	if err != nil {
+		t.Fatalf("NewNonce failed: %v", err)
+	}
+	if other, _ := NewNonce(); other == nonce || len(nonce) != 32 {
+		t.Errorf("Expected distinct random nonces, but got %q and %q", nonce, other)
+	}
+	records, err := Parse([]byte(`{"v": 1, "type": "result", "test": 0, "value": 1}
+{"v": 1, "type": "result", "test": 0, "value": 2, "nonce": "` + nonce + `"}
+{"v": 1, "type": "result", "test": 1, "value": 3, "nonce": "guess"}
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	signed, forged := Signed(records, nonce)
+	if len(signed) != 1 || string(signed[0].Value) != "2" || forged != 2 {
+		t.Errorf("Expected only the signed record, but got %+v and %d unsigned", signed, forged)
+	}
+}
@@ -68,15 +68,22 @@ func (rl *RateLimiter) cleanupVisitors() {
 	for {
 		time.Sleep(CleanupInterval)
 		
-		rl.mu.Lock()
-		for ip, requests := range rl.visitors {
-			// Remove users with no recent requests
-			if len(requests) == 0 || time.Since(requests[len(requests)-1]) > MaxInactivityDuration {
-				delete(rl.visitors, ip)
-			}
+		rl.sweep()
+	}
+}
+
+// sweep removes the visitors with no request in MaxInactivityDuration and
+// returns how many are left
+func (rl *RateLimiter) sweep() int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	for ip, requests := range rl.visitors {
+		// Remove users with no recent requests
+		if len(requests) == 0 || time.Since(requests[len(requests)-1]) > MaxInactivityDuration {
+			delete(rl.visitors, ip)
 		}
-		rl.mu.Unlock()
 	}
+	return len(rl.visitors)
 }
 
 // GetVisitorIP extracts the real IP address from the request
@@ -112,4 +119,58 @@ func (rl *RateLimiter) RateLimitHandler(next http.Handler) http.Handler {
 		
 		next.ServeHTTP(w, r)
 	})
-}
\ No newline at end of file
+}
+
+// Quotas holds a separate limit per scope, such as a contest, on top of the
+// server-wide limit. A scope's limiter is created on first use; one cleanup
+// goroutine sweeps every scope and forgets those left without visitors.
+type Quotas struct {
+	mu       sync.Mutex
+	limiters map[string]*RateLimiter
+}
+
+// NewQuotas creates an empty set of scoped limits and starts its cleanup
+func NewQuotas() *Quotas {
+	q := &Quotas{limiters: make(map[string]*RateLimiter)}
+	go q.cleanup()
+	return q
+}
+
+// Allow checks a request from ip against the limit of scope, maxReqs per
+// window. A changed limit applies from the next request on.
+func (q *Quotas) Allow(scope string, window time.Duration, maxReqs int, ip string) bool {
+	// Holding q.mu throughout keeps the sweep from dropping the limiter
+	// between its lookup and the request it counts
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	rl, exists := q.limiters[scope]
+	if !exists {
+		// Without NewRateLimiter's goroutine, the sweep covers it
+		rl = &RateLimiter{visitors: make(map[string][]time.Time)}
+		q.limiters[scope] = rl
+	}
+
+	rl.mu.Lock()
+	rl.window, rl.maxReqs = window, maxReqs
+	rl.mu.Unlock()
+	return rl.Allow(ip)
+}
+
+// cleanup sweeps every scope each CleanupInterval
+func (q *Quotas) cleanup() {
+	for {
+		time.Sleep(CleanupInterval)
+		q.sweep()
+	}
+}
+
+// sweep removes the idle visitors of every scope and the scopes left empty
+func (q *Quotas) sweep() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for scope, rl := range q.limiters {
+		if rl.sweep() == 0 {
+			delete(q.limiters, scope)
+		}
+	}
+}
@@ -171,4 +171,36 @@ func TestRateLimiter_LargeNumberOfRequests(t *testing.T) {
 	if rl.Allow("127.0.0.100") {
 		t.Error("Expected 6th request to be blocked")
 	}
-}
\ No newline at end of file
+}
+
+func TestQuotas_Allow(t *testing.T) {
+	q := NewQuotas()
+	if !q.Allow("contest-a", time.Minute, 1, "127.0.0.1") {
+		t.Error("Expected first request to be allowed")
+	}
+	if q.Allow("contest-a", time.Minute, 1, "127.0.0.1") {
+		t.Error("Expected second request in the same scope to be blocked")
+	}
+	if !q.Allow("contest-b", time.Minute, 1, "127.0.0.1") {
+		t.Error("Expected a request in another scope to be allowed")
+	}
+	// Raising the limit lets the next request through
+	if !q.Allow("contest-a", time.Minute, 3, "127.0.0.1") {
+		t.Error("Expected the raised limit to allow the request")
+	}
+}
+
+func TestQuotas_Sweep(t *testing.T) {
+	q := NewQuotas()
+	q.Allow("contest-a", time.Minute, 1, "127.0.0.1")
+	q.Allow("contest-b", time.Minute, 1, "127.0.0.1")
+	// Age the visitor of contest-a past the inactivity limit
+	q.limiters["contest-a"].visitors["127.0.0.1"] = []time.Time{time.Now().Add(-MaxInactivityDuration - time.Minute)}
+	q.sweep()
+	if _, ok := q.limiters["contest-a"]; ok {
+		t.Error("Expected the idle scope to be removed")
+	}
+	if _, ok := q.limiters["contest-b"]; !ok {
+		t.Error("Expected the active scope to be kept")
+	}
+}
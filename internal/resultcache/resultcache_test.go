@@ -0,0 +1,67 @@
+package resultcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKey(t *testing.T) {
+	type fields struct {
+		Code string
+		Lang string
+	}
+	a := Key(fields{"console.log(1)", "node"})
+	b := Key(fields{"console.log(1)", "node"})
+	c := Key(fields{"console.log(1)", "php"})
+	if a == "" || a != b {
+		t.Errorf("Expected identical fields to produce the same key, got %q and %q", a, b)
+	}
+	if a == c {
+		t.Error("Expected different fields to produce different keys")
+	}
+}
+
+func TestCache_GetAdd(t *testing.T) {
+	c := New(2, time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected miss on empty cache")
+	}
+	c.Add("a", 1)
+	if v, ok := c.Get("a"); !ok || v.(int) != 1 {
+		t.Errorf("Expected hit with value 1, got %v %v", v, ok)
+	}
+	c.Add("a", 2)
+	if v, _ := c.Get("a"); v.(int) != 2 || c.Len() != 1 {
+		t.Errorf("Expected value to be replaced, got %v (len %d)", v, c.Len())
+	}
+}
+
+func TestCache_LRUEviction(t *testing.T) {
+	c := New(2, time.Minute)
+	c.Add("a", 1)
+	c.Add("b", 2)
+	c.Get("a") // a is now most recently used
+	c.Add("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected least recently used entry to be evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("Expected recently used entry to be kept")
+	}
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", c.Len())
+	}
+}
+
+func TestCache_TTL(t *testing.T) {
+	c := New(10, 10*time.Millisecond)
+	c.Add("a", 1)
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected expired entry to miss")
+	}
+	if c.Len() != 0 {
+		t.Errorf("Expected expired entry to be removed, got len %d", c.Len())
+	}
+}
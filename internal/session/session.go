@@ -0,0 +1,193 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/interp"
+)
+
+// Constants for session manager configuration
+const (
+	CleanupInterval = time.Minute
+	EvalTimeout     = 10 * time.Second
+)
+
+var (
+	ErrNotFound        = errors.New("session not found")
+	ErrTooManySessions = errors.New("too many open sessions")
+)
+
+// Cell is the output of one snippet evaluated in a session
+type Cell struct {
+	Index      int    `json:"index"`
+	Stdout     string `json:"out"`
+	Stderr     string `json:"errout"`
+	Value      string `json:"value,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// Session is a long-lived interpreter owned by one client
+type Session struct {
+	ID       string    `json:"id"`
+	Language string    `json:"lang"`
+	Owner    string    `json:"-"`
+	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"lastUsed"`
+	Cells    int       `json:"cells"`
+
+	interp interp.Interpreter
+}
+
+// Manager tracks open sessions, enforcing a per-owner limit and idle expiry
+type Manager struct {
+	sessions map[string]*Session
+	// starting counts the sessions per owner whose interpreter is starting,
+	// they hold a slot of the limit already
+	starting    map[string]int
+	mu          sync.Mutex
+	idleTimeout time.Duration
+	maxPerOwner int
+	start       func(language string) (interp.Interpreter, error)
+}
+
+// NewManager creates a new session manager
+func NewManager(idleTimeout time.Duration, maxPerOwner int) *Manager {
+	m := &Manager{
+		sessions:    make(map[string]*Session),
+		starting:    make(map[string]int),
+		idleTimeout: idleTimeout,
+		maxPerOwner: maxPerOwner,
+		start:       interp.Start,
+	}
+
+	// Start cleanup goroutine
+	go m.cleanupSessions()
+
+	return m
+}
+
+// Create starts a new interpreter for owner. The owner's slot is reserved
+// while the interpreter starts, so concurrent creates cannot exceed the limit.
+func (m *Manager) Create(owner, language string) (*Session, error) {
+	m.mu.Lock()
+	open := m.starting[owner]
+	for _, s := range m.sessions {
+		if s.Owner == owner {
+			open++
+		}
+	}
+	if open >= m.maxPerOwner {
+		m.mu.Unlock()
+		return nil, ErrTooManySessions
+	}
+	m.starting[owner]++
+	m.mu.Unlock()
+
+	in, err := m.start(language)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.starting[owner]--; m.starting[owner] == 0 {
+		delete(m.starting, owner)
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	s := &Session{
+		ID:       newID(),
+		Language: language,
+		Owner:    owner,
+		Created:  now,
+		LastUsed: now,
+		interp:   in,
+	}
+	m.sessions[s.ID] = s
+	return s, nil
+}
+
+// Get returns a copy of the session's metadata
+func (m *Manager) Get(id string) (Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok {
+		return Session{}, ErrNotFound
+	}
+	return *s, nil
+}
+
+// Eval runs code in the session and returns the cell output. A session whose
+// interpreter died or timed out is closed.
+func (m *Manager) Eval(id, code string) (Cell, error) {
+	m.mu.Lock()
+	s, ok := m.sessions[id]
+	if !ok {
+		m.mu.Unlock()
+		return Cell{}, ErrNotFound
+	}
+	s.LastUsed = time.Now()
+	s.Cells++
+	cell := Cell{Index: s.Cells}
+	in := s.interp
+	m.mu.Unlock()
+
+	start := time.Now()
+	res, err := in.Eval(code, EvalTimeout)
+	cell.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		m.Close(id)
+		return cell, err
+	}
+	cell.Stdout = res.Stdout
+	cell.Stderr = res.Stderr
+	cell.Value = res.Value
+	cell.Error = res.Error
+	return cell, nil
+}
+
+// Close stops the session's interpreter
+func (m *Manager) Close(id string) error {
+	m.mu.Lock()
+	s, ok := m.sessions[id]
+	delete(m.sessions, id)
+	m.mu.Unlock()
+	if !ok {
+		return ErrNotFound
+	}
+	s.interp.Close()
+	return nil
+}
+
+// cleanupSessions closes sessions that have been idle for too long
+func (m *Manager) cleanupSessions() {
+	for {
+		time.Sleep(CleanupInterval)
+		m.closeIdle()
+	}
+}
+
+func (m *Manager) closeIdle() {
+	m.mu.Lock()
+	var idle []string
+	for id, s := range m.sessions {
+		if time.Since(s.LastUsed) > m.idleTimeout {
+			idle = append(idle, id)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, id := range idle {
+		m.Close(id)
+	}
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
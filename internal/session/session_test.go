@@ -0,0 +1,158 @@
+package session
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/interp"
+)
+
+// fakeInterpreter echoes the evaluated code back as stdout
+type fakeInterpreter struct {
+	closed bool
+}
+
+func (f *fakeInterpreter) Eval(code string, timeout time.Duration) (interp.Result, error) {
+	return interp.Result{Stdout: code}, nil
+}
+
+func (f *fakeInterpreter) Close() error {
+	f.closed = true
+	return nil
+}
+
+func newTestManager(idleTimeout time.Duration, maxPerOwner int) (*Manager, *[]*fakeInterpreter) {
+	started := &[]*fakeInterpreter{}
+	m := NewManager(idleTimeout, maxPerOwner)
+	m.start = func(language string) (interp.Interpreter, error) {
+		f := &fakeInterpreter{}
+		*started = append(*started, f)
+		return f, nil
+	}
+	return m, started
+}
+
+func TestManager_CreateEvalClose(t *testing.T) {
+	m, started := newTestManager(time.Minute, 2)
+
+	s, err := m.Create("127.0.0.1", "node")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if s.ID == "" || s.Language != "node" {
+		t.Errorf("Unexpected session: %+v", s)
+	}
+
+	cell, err := m.Eval(s.ID, "first")
+	if err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+	if cell.Index != 1 || cell.Stdout != "first" {
+		t.Errorf("Unexpected cell: %+v", cell)
+	}
+	cell, _ = m.Eval(s.ID, "second")
+	if cell.Index != 2 {
+		t.Errorf("Expected cell index 2, but got %d", cell.Index)
+	}
+
+	if err := m.Close(s.ID); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if !(*started)[0].closed {
+		t.Error("Expected interpreter to be closed")
+	}
+	if _, err := m.Eval(s.ID, "third"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound after close, but got: %v", err)
+	}
+	if err := m.Close(s.ID); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound on second close, but got: %v", err)
+	}
+}
+
+func TestManager_PerOwnerLimit(t *testing.T) {
+	m, _ := newTestManager(time.Minute, 1)
+
+	if _, err := m.Create("127.0.0.1", "node"); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := m.Create("127.0.0.1", "php"); err != ErrTooManySessions {
+		t.Errorf("Expected ErrTooManySessions, but got: %v", err)
+	}
+	// Other owners are not affected
+	if _, err := m.Create("127.0.0.2", "node"); err != nil {
+		t.Errorf("Expected session for a different owner, but got: %v", err)
+	}
+}
+
+func TestManager_PerOwnerLimitConcurrent(t *testing.T) {
+	m := NewManager(time.Minute, 2)
+	release := make(chan struct{})
+	m.start = func(language string) (interp.Interpreter, error) {
+		<-release
+		return &fakeInterpreter{}, nil
+	}
+
+	// Slow interpreters still hold their slot while they start
+	errs := make(chan error, 5)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := m.Create("127.0.0.1", "node")
+			errs <- err
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	created := 0
+	for err := range errs {
+		if err == nil {
+			created++
+		} else if err != ErrTooManySessions {
+			t.Errorf("Expected ErrTooManySessions, but got: %v", err)
+		}
+	}
+	if created != 2 {
+		t.Errorf("Expected 2 sessions within the limit, but got %d", created)
+	}
+}
+
+func TestManager_StartFailureReleasesSlot(t *testing.T) {
+	m := NewManager(time.Minute, 1)
+	m.start = func(language string) (interp.Interpreter, error) {
+		return nil, errors.New("no interpreter")
+	}
+	if _, err := m.Create("127.0.0.1", "node"); err == nil {
+		t.Fatal("Expected the start error, but got none")
+	}
+
+	m.start = func(language string) (interp.Interpreter, error) {
+		return &fakeInterpreter{}, nil
+	}
+	if _, err := m.Create("127.0.0.1", "node"); err != nil {
+		t.Errorf("Expected the failed start to free its slot, but got: %v", err)
+	}
+}
+
+func TestManager_IdleExpiry(t *testing.T) {
+	m, started := newTestManager(10*time.Millisecond, 1)
+
+	s, err := m.Create("127.0.0.1", "node")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	m.closeIdle()
+
+	if _, err := m.Get(s.ID); err != ErrNotFound {
+		t.Errorf("Expected idle session to be removed, but got: %v", err)
+	}
+	if !(*started)[0].closed {
+		t.Error("Expected idle interpreter to be closed")
+	}
+}
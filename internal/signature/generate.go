@@ -0,0 +1,242 @@
+package signature
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/afman42/go-web-code-interactive/internal/protocol"
+)
+
+// Driver generates the harness that calls the user's function once for
+// every test in the tests file and appends each result as a protocol record
+// to the results file, leaving standard output to the user. It never
+// touches the user's source: for Node it runs after the solution in the same
+// global scope, for PHP it follows a require of the solution and for Go it is
+// a second file of package main. Since the tests are read at run time, one
+// build of the program serves every test case. The tests and the run's nonce
+// are read before the user's code runs, every record is signed with the
+// nonce: Node and PHP drivers leave that to the judge's loader, which hands
+// the Node driver the tests and a writer and writes what the PHP driver's
+// __sig_main yields.
+func (s Signature) Driver(language string) (string, error) {
+	switch language {
+	case "node":
+		return s.nodeDriver(), nil
+	case "php":
+		return s.phpDriver()
+	case "go":
+		return s.goDriver(), nil
+	}
+	return "", fmt.Errorf("unsupported language: %s", language)
+}
+
+// Stub returns starter code declaring the function for the language
+func (s Signature) Stub(language string) (string, error) {
+	names := make([]string, len(s.Params))
+	for i, p := range s.Params {
+		names[i] = p.Name
+	}
+	switch language {
+	case "node":
+		return fmt.Sprintf("function %s(%s) {\n\t\n}\n", s.Function, strings.Join(names, ", ")), nil
+	case "php":
+		for i := range names {
+			names[i] = "$" + names[i]
+		}
+		return fmt.Sprintf("<?php\n\nfunction %s(%s) {\n\t\n}\n", s.Function, strings.Join(names, ", ")), nil
+	case "go":
+		for i, p := range s.Params {
+			names[i] = p.Name + " " + p.Type.goType()
+		}
+		return fmt.Sprintf("package main\n\nfunc %s(%s) %s {\n\t\n}\n",
+			s.Function, strings.Join(names, ", "), s.Result.goType()), nil
+	}
+	return "", fmt.Errorf("unsupported language: %s", language)
+}
+
+// nodeDriver spreads the decoded argument list into the call, JavaScript
+// numbers cover both ints and floats. Exceptions and output are captured per
+// test so the remaining tests still run in the same process. It is the body
+// of a strict function called with __tests, __write and __now, strict so the
+// user's function cannot reach the writer through its caller's arguments.
+func (s Signature) nodeDriver() string {
+	return fmt.Sprintf(`"use strict";
+for (const __test of __tests) {
+  const __stdoutWrite = process.stdout.write;
+  let __stdout = "";
+  process.stdout.write = (chunk) => { __stdout += chunk; return true; };
+  const __start = __now();
+  let __type, __value;
+  try {
+    const __result = %s(...__test.args);
+    __type = %q;
+    __value = __result === undefined ? null : __result;
+  } catch (__e) {
+    __type = %q;
+    __value = String((__e && __e.stack) || __e);
+  } finally {
+    process.stdout.write = __stdoutWrite;
+  }
+  const __durationMs = __now() - __start;
+  if (__stdout) __write({ type: %q, test: __test.test, value: __stdout });
+  __write({ type: __type, test: __test.test, value: __value, durationMs: __durationMs });
+}
+`, s.Function, protocol.TypeResult, protocol.TypeError, protocol.TypeStdout)
+}
+
+// phpDriver decodes arguments into PHP arrays, casting floats that JSON
+// wrote without a fraction, and turns associative results back into JSON
+// objects even when they are empty. Exceptions and output are captured per
+// test so the remaining tests still run in the same process. __sig_main is a
+// generator yielding the records, the loader signs and writes them.
+func (s Signature) phpDriver() (string, error) {
+	types := make([]Type, len(s.Params))
+	for i, p := range s.Params {
+		types[i] = p.Type
+	}
+	meta, err := json.Marshal(struct {
+		Types  []Type `json:"types"`
+		Result Type   `json:"result"`
+	}{types, s.Result})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`
+function __sig_arg($v, $t) {
+    switch ($t['kind']) {
+        case 'float':
+            return (float)$v;
+        case 'array':
+        case 'map':
+            return array_map(fn($e) => __sig_arg($e, $t['elem']), $v);
+    }
+    return $v;
+}
+function __sig_result($v, $t) {
+    if (!is_array($v) || !in_array($t['kind'], ['array', 'map'], true)) {
+        return $v;
+    }
+    $v = array_map(fn($e) => __sig_result($e, $t['elem']), $v);
+    return $t['kind'] === 'map' ? (object)$v : array_values($v);
+}
+function __sig_main($tests) {
+    $sig = json_decode('%s', true);
+    foreach ($tests as $test) {
+        ob_start();
+        $start = hrtime(true);
+        try {
+            $record = ['type' => '%s', 'test' => $test['test'], 'value' => __sig_result(%s(...array_map('__sig_arg', $test['args'], $sig['types'])), $sig['result'])];
+        } catch (Throwable $e) {
+            $record = ['type' => '%s', 'test' => $test['test'], 'value' => (string)$e];
+        }
+        $record['durationMs'] = (hrtime(true) - $start) / 1e6;
+        $stdout = ob_get_clean();
+        if ($stdout !== '') {
+            yield ['type' => '%s', 'test' => $test['test'], 'value' => $stdout];
+        }
+        yield $record;
+    }
+}
+`, phpQuote(string(meta)), protocol.TypeResult, s.Function, protocol.TypeError, protocol.TypeStdout), nil
+}
+
+func phpQuote(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
+
+// goDriverTemplate is filled with the argument declarations, the call and
+// the result normalization
+const goDriverTemplate = `package main
+
+import (
+	__bytes "bytes"
+	__json "encoding/json"
+	__fmt "fmt"
+	__os "os"
+	__runtime "runtime"
+	__time "time"
+)
+
+// __stqTests is initialized before any variable of the user's file. It
+// holds the tests, nonce included, for this file alone.
+var __stqTests = __stqRead()
+
+func __stqRead() func() []byte {
+	__in, __err := __os.ReadFile(__os.Getenv(%q))
+	__stqCheck(__err)
+	__stqCheck(__os.Remove(__os.Getenv(%q)))
+	_, __self, _, _ := __runtime.Caller(0)
+	return func() []byte {
+		if _, __file, _, _ := __runtime.Caller(1); __file != __self {
+			return nil
+		}
+		return __in
+	}
+}
+
+func main() {
+	__f, __err := __os.OpenFile(__os.Getenv(%q), __os.O_APPEND|__os.O_CREATE|__os.O_WRONLY, 0o644)
+	__stqCheck(__err)
+	for __dec := __json.NewDecoder(__bytes.NewReader(__stqTests())); __dec.More(); {
+		var __test struct {
+			Test  int
+			Args  []__json.RawMessage
+			Nonce string
+		}
+		__stqCheck(__dec.Decode(&__test))
+%s		__start := __time.Now()
+		__result := %s
+		__ms := float64(__time.Since(__start).Microseconds()) / 1000
+%s		__out, __err := __json.Marshal(__result)
+		__stqCheck(__err)
+		__line, _ := __json.Marshal(map[string]any{"v": %d, "type": %q, "test": __test.Test, "value": __json.RawMessage(__out), "durationMs": __ms, "nonce": __test.Nonce})
+		__f.Write(append(__line, '\n'))
+	}
+	__f.Close()
+}
+
+func __stqCheck(err error) {
+	if err != nil {
+		__fmt.Fprintln(__os.Stderr, err)
+		__os.Exit(1)
+	}
+}
+`
+
+// goDriver generates a separate file of package main. It runs the tests
+// from main, after every init function of the user's file, so the judge
+// renames a main function the user declares. Imports are aliased and
+// identifiers prefixed to stay clear of the user's own declarations. The
+// file must come before the user's in the build so the tests are read, and
+// the tests file removed, before the user's package variables are
+// initialized; only the file itself gets the tests back from __stqTests.
+func (s Signature) goDriver() string {
+	var decls strings.Builder
+	names := make([]string, len(s.Params))
+	for i, p := range s.Params {
+		names[i] = fmt.Sprintf("__arg%d", i)
+		fmt.Fprintf(&decls, "\t\tvar %s %s\n", names[i], p.Type.goType())
+		fmt.Fprintf(&decls, "\t\t__stqCheck(__json.Unmarshal(__test.Args[%d], &%s))\n", i, names[i])
+	}
+	// A nil slice or map is the Go way of returning an empty one
+	normalize := ""
+	if s.Result.Kind == Array || s.Result.Kind == Map {
+		normalize = fmt.Sprintf("\t\tif __result == nil {\n\t\t\t__result = make(%s)\n\t\t}\n", s.Result.goType())
+	}
+	call := fmt.Sprintf("%s(%s)\n", s.Function, strings.Join(names, ", "))
+	return fmt.Sprintf(goDriverTemplate, protocol.TestsEnv, protocol.TestsEnv, protocol.ResultsEnv, decls.String(), call, normalize,
+		protocol.Version, protocol.TypeResult)
+}
+
+func (t Type) goType() string {
+	switch t.Kind {
+	case Float:
+		return "float64"
+	case Array:
+		return "[]" + t.Elem.goType()
+	case Map:
+		return "map[string]" + t.Elem.goType()
+	}
+	return string(t.Kind)
+}
@@ -0,0 +1,211 @@
+package signature
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Kind is the kind of a value passed to or returned from a problem function
+type Kind string
+
+// Supported kinds. Arrays and maps are typed by their element, map keys are
+// always strings as in JSON objects.
+const (
+	Int    Kind = "int"
+	Float  Kind = "float"
+	String Kind = "string"
+	Bool   Kind = "bool"
+	Array  Kind = "array"
+	Map    Kind = "map"
+)
+
+// Type is a language-neutral value type such as int, []string or
+// map[string][]float
+type Type struct {
+	Kind Kind  `json:"kind"`
+	Elem *Type `json:"elem,omitempty"`
+}
+
+// Param is a named function parameter
+type Param struct {
+	Name string
+	Type Type
+}
+
+// Signature describes the function users implement, e.g.
+// "intIntoString(n: int) -> string"
+type Signature struct {
+	Function string
+	Params   []Param
+	Result   Type
+}
+
+var (
+	signatureRe = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)\s*\((.*)\)\s*->\s*(.+?)\s*$`)
+	nameRe      = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Parse reads a signature of the form "name(a: type, b: type) -> type"
+func Parse(s string) (Signature, error) {
+	m := signatureRe.FindStringSubmatch(s)
+	if m == nil {
+		return Signature{}, fmt.Errorf("invalid signature %q, expected name(arg: type) -> type", s)
+	}
+	sig := Signature{Function: m[1]}
+	if params := strings.TrimSpace(m[2]); params != "" {
+		for _, param := range strings.Split(params, ",") {
+			name, typ, ok := strings.Cut(param, ":")
+			name = strings.TrimSpace(name)
+			if !ok || !nameRe.MatchString(name) {
+				return Signature{}, fmt.Errorf("invalid parameter %q, expected name: type", strings.TrimSpace(param))
+			}
+			t, err := ParseType(typ)
+			if err != nil {
+				return Signature{}, err
+			}
+			sig.Params = append(sig.Params, Param{Name: name, Type: t})
+		}
+	}
+	result, err := ParseType(m[3])
+	if err != nil {
+		return Signature{}, err
+	}
+	sig.Result = result
+	return sig, nil
+}
+
+// ParseType reads a type: int, float, string, bool, []T or map[string]T
+func ParseType(s string) (Type, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == string(Int), s == string(Float), s == string(String), s == string(Bool):
+		return Type{Kind: Kind(s)}, nil
+	case strings.HasPrefix(s, "[]"):
+		elem, err := ParseType(s[2:])
+		if err != nil {
+			return Type{}, err
+		}
+		return Type{Kind: Array, Elem: &elem}, nil
+	case strings.HasPrefix(s, "map[string]"):
+		elem, err := ParseType(s[len("map[string]"):])
+		if err != nil {
+			return Type{}, err
+		}
+		return Type{Kind: Map, Elem: &elem}, nil
+	}
+	return Type{}, fmt.Errorf("unknown type %q", s)
+}
+
+// String returns the type in signature syntax
+func (t Type) String() string {
+	switch t.Kind {
+	case Array:
+		return "[]" + t.Elem.String()
+	case Map:
+		return "map[string]" + t.Elem.String()
+	}
+	return string(t.Kind)
+}
+
+// String returns the signature in the syntax accepted by Parse
+func (s Signature) String() string {
+	params := make([]string, len(s.Params))
+	for i, p := range s.Params {
+		params[i] = p.Name + ": " + p.Type.String()
+	}
+	return fmt.Sprintf("%s(%s) -> %s", s.Function, strings.Join(params, ", "), s.Result)
+}
+
+// Check verifies that test data matches the signature
+func (s Signature) Check(args []json.RawMessage, expected json.RawMessage) error {
+	if err := s.CheckArgs(args); err != nil {
+		return err
+	}
+	if err := s.Result.Check(expected); err != nil {
+		return fmt.Errorf("expected result: %v", err)
+	}
+	return nil
+}
+
+// CheckArgs checks only the arguments of a test case
+func (s Signature) CheckArgs(args []json.RawMessage) error {
+	if len(args) != len(s.Params) {
+		return fmt.Errorf("%s takes %d arguments, got %d", s.Function, len(s.Params), len(args))
+	}
+	for i, arg := range args {
+		if err := s.Params[i].Type.Check(arg); err != nil {
+			return fmt.Errorf("argument %s: %v", s.Params[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// Check verifies that a JSON value has the type
+func (t Type) Check(raw json.RawMessage) error {
+	v, err := decode(raw)
+	if err != nil {
+		return err
+	}
+	return t.check(v)
+}
+
+func (t Type) check(v any) error {
+	switch t.Kind {
+	case Int:
+		if n, ok := v.(json.Number); ok {
+			if _, err := n.Int64(); err == nil {
+				return nil
+			}
+		}
+	case Float:
+		if _, ok := v.(json.Number); ok {
+			return nil
+		}
+	case String:
+		if _, ok := v.(string); ok {
+			return nil
+		}
+	case Bool:
+		if _, ok := v.(bool); ok {
+			return nil
+		}
+	case Array:
+		if list, ok := v.([]any); ok {
+			for _, e := range list {
+				if err := t.Elem.check(e); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case Map:
+		if m, ok := v.(map[string]any); ok {
+			for _, e := range m {
+				if err := t.Elem.check(e); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not a %s", compact(v), t)
+}
+
+// decode parses JSON keeping numbers exact
+func decode(raw json.RawMessage) (any, error) {
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func compact(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,269 @@
+package signature
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/afman42/go-web-code-interactive/internal/protocol"
+)
+
+func TestParse(t *testing.T) {
+	sig, err := Parse("merge(a: []int, m: map[string][]float, ok: bool) -> map[string]string")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if sig.Function != "merge" || len(sig.Params) != 3 {
+		t.Fatalf("Unexpected signature: %+v", sig)
+	}
+	if got := sig.Params[1].Type.String(); got != "map[string][]float" {
+		t.Errorf("Expected nested map type, but got %s", got)
+	}
+	if got := sig.String(); got != "merge(a: []int, m: map[string][]float, ok: bool) -> map[string]string" {
+		t.Errorf("Expected signature to round trip, but got %s", got)
+	}
+
+	if sig, err := Parse("now() -> int"); err != nil || len(sig.Params) != 0 {
+		t.Errorf("Expected function without parameters, but got %+v, %v", sig, err)
+	}
+
+	invalid := []string{
+		"intIntoString(n: int)",
+		"intIntoString(n int) -> string",
+		"intIntoString(n: integer) -> string",
+		"f(m: map[int]string) -> int",
+		"f(1n: int) -> int",
+	}
+	for _, s := range invalid {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Expected error for %q, but got none", s)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	sig, err := Parse("f(n: int, xs: []float, m: map[string]bool) -> []string")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	args := func(raw ...string) []json.RawMessage {
+		list := make([]json.RawMessage, len(raw))
+		for i, r := range raw {
+			list[i] = json.RawMessage(r)
+		}
+		return list
+	}
+
+	if err := sig.Check(args(`1`, `[1, 2.5]`, `{"a": true}`), json.RawMessage(`["x"]`)); err != nil {
+		t.Errorf("Expected valid test data, but got: %v", err)
+	}
+	testCases := map[string]struct {
+		args     []json.RawMessage
+		expected string
+	}{
+		"argument count": {args(`1`), `[]`},
+		"float for int":  {args(`1.5`, `[]`, `{}`), `[]`},
+		"string element": {args(`1`, `["1"]`, `{}`), `[]`},
+		"map value":      {args(`1`, `[]`, `{"a": 1}`), `[]`},
+		"result type":    {args(`1`, `[]`, `{}`), `"x"`},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if err := sig.Check(tc.args, json.RawMessage(tc.expected)); err == nil {
+				t.Error("Expected error, but got none")
+			}
+		})
+	}
+}
+
+func TestStub(t *testing.T) {
+	sig, _ := Parse("sum(xs: []int, scale: float) -> float")
+	expected := map[string]string{
+		"node": "function sum(xs, scale) {",
+		"php":  "function sum($xs, $scale) {",
+		"go":   "func sum(xs []int, scale float64) float64 {",
+	}
+	for lang, want := range expected {
+		stub, err := sig.Stub(lang)
+		if err != nil {
+			t.Fatalf("Stub(%s) failed: %v", lang, err)
+		}
+		if !strings.Contains(stub, want) {
+			t.Errorf("Expected %s stub to contain %q, but got:\n%s", lang, want, stub)
+		}
+	}
+	if _, err := sig.Stub("python"); err == nil {
+		t.Error("Expected error for unsupported language, but got none")
+	}
+}
+
+// TestDriver runs generated drivers end to end over two tests with a
+// solution that echoes its nested arguments back as the result
+func TestDriver(t *testing.T) {
+	sig, err := Parse("mirror(n: int, x: float, s: string, b: bool, m: map[string][][]int) -> map[string][][]int")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	args := []json.RawMessage{
+		json.RawMessage(`7`),
+		json.RawMessage(`2`),
+		json.RawMessage(`"quote \" and \\ and \n"`),
+		json.RawMessage(`true`),
+		json.RawMessage(`{"a": [[1, 2], []], "b": []}`),
+	}
+	solutions := map[string]string{
+		"node": `function mirror(n, x, s, b, m) {
+	if (n !== 7 || x !== 2 || s !== "quote \" and \\ and \n" || b !== true) return {};
+	return m;
+}
+`,
+		"php": `<?php
+function mirror($n, $x, $s, $b, $m) {
+	if ($n !== 7 || $x !== 2.0 || $s !== "quote \" and \\ and \n" || $b !== true) return [];
+	return $m;
+}
+`,
+		"go": `package main
+
+func mirror(n int, x float64, s string, b bool, m map[string][][]int) map[string][][]int {
+	if n != 7 || x != 2 || s != "quote \" and \\ and \n" || !b {
+		return nil
+	}
+	return m
+}
+`,
+	}
+	var expected any
+	json.Unmarshal([]byte(`{"a": [[1, 2], []], "b": []}`), &expected)
+
+	for lang, solution := range solutions {
+		t.Run(lang, func(t *testing.T) {
+			if _, err := exec.LookPath(lang); err != nil {
+				t.Skipf("%s is not installed", lang)
+			}
+			driver, err := sig.Driver(lang)
+			if err != nil {
+				t.Fatalf("Driver failed: %v", err)
+			}
+			tests, _ := protocol.EncodeInputs([]protocol.Input{{Test: 0, Args: args}, {Test: 1, Args: args}})
+			results := runProgram(t, lang, solution, driver, tests)
+			records, err := protocol.Parse(results)
+			if err != nil || len(records) != 2 {
+				t.Fatalf("Expected two result records, but got %q, %v", results, err)
+			}
+			for i, record := range records {
+				if record.Type != protocol.TypeResult || record.Test != i {
+					t.Errorf("Expected the result of test %d, but got %+v", i, record)
+				}
+				var actual any
+				json.Unmarshal(record.Value, &actual)
+				if !reflect.DeepEqual(actual, expected) {
+					t.Errorf("Expected %v, but got %v", expected, actual)
+				}
+			}
+		})
+	}
+
+	if _, err := sig.Driver("ruby"); err == nil {
+		t.Error("Expected error for unsupported language, but got none")
+	}
+}
+
+// TestDriverCapture checks that interpreted drivers keep running after an
+// exception and report what each test printed
+func TestDriverCapture(t *testing.T) {
+	sig, err := Parse("half(n: int) -> int")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	solutions := map[string]string{
+		"node": "function half(n) {\n  if (n % 2) throw new Error('odd');\n  console.log('halving', n);\n  return n / 2;\n}\n",
+		"php":  "<?php\nfunction half($n) {\n  if ($n % 2) throw new Exception('odd');\n  echo \"halving $n\\n\";\n  return intdiv($n, 2);\n}\n",
+	}
+	for lang, solution := range solutions {
+		t.Run(lang, func(t *testing.T) {
+			if _, err := exec.LookPath(lang); err != nil {
+				t.Skipf("%s is not installed", lang)
+			}
+			driver, _ := sig.Driver(lang)
+			tests, _ := protocol.EncodeInputs([]protocol.Input{
+				{Test: 0, Args: []json.RawMessage{json.RawMessage("3")}},
+				{Test: 1, Args: []json.RawMessage{json.RawMessage("4")}},
+			})
+			records, err := protocol.Parse(runProgram(t, lang, solution, driver, tests))
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			failed, ok := protocol.Find(records, protocol.TypeError, 0)
+			if !ok || !strings.Contains(string(failed.Value), "odd") {
+				t.Errorf("Expected the exception of test 0, but got %+v", records)
+			}
+			stdout, ok := protocol.Find(records, protocol.TypeStdout, 1)
+			if !ok || string(stdout.Value) != `"halving 4\n"` {
+				t.Errorf("Expected the output of test 1, but got %+v", records)
+			}
+			result, ok := protocol.Find(records, protocol.TypeResult, 1)
+			if !ok || string(result.Value) != "2" {
+				t.Errorf("Expected result 2 for test 1, but got %+v", records)
+			}
+		})
+	}
+}
+
+// Test loaders standing in for the judge's: they hand the Node driver the
+// tests and a writer, and write the records the PHP driver yields
+const (
+	nodeTestLoader = `
+{
+  const __fs = require("fs");
+  const __tests = __fs.readFileSync(process.env.STQ_TESTS, "utf8").split("\n").filter((line) => line.trim()).map((line) => JSON.parse(line));
+  const __write = (record) => __fs.appendFileSync(process.env.STQ_RESULTS, JSON.stringify({ v: 1, ...record }) + "\n");
+  (function (__tests, __write, __now) {
+%s
+  })(__tests, __write, () => performance.now());
+}
+`
+	phpTestLoader = `
+$__tests = array_map(fn($line) => json_decode($line, true), file(getenv('STQ_TESTS'), FILE_IGNORE_NEW_LINES | FILE_SKIP_EMPTY_LINES));
+foreach (__sig_main($__tests) as $__record) {
+    file_put_contents(getenv('STQ_RESULTS'), json_encode(['v' => 1] + $__record) . "\n", FILE_APPEND);
+}
+`
+)
+
+// runProgram runs the driver after the solution, as a second file for Go,
+// on the given tests file and returns the results file
+func runProgram(t *testing.T, lang, solution, driver string, tests []byte) []byte {
+	t.Helper()
+	dir := t.TempDir()
+	write := func(name, code string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(code), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	var args []string
+	switch lang {
+	case "go":
+		args = []string{"run", write("solution.go", solution), write("driver.go", driver)}
+	case "node":
+		args = []string{write("main.js", solution+fmt.Sprintf(nodeTestLoader, driver))}
+	case "php":
+		args = []string{write("main.php", solution+driver+phpTestLoader)}
+	}
+	results := filepath.Join(dir, protocol.ResultsFile)
+	cmd := exec.Command(lang, args...)
+	cmd.Env = append(os.Environ(), protocol.ResultsEnv+"="+results, protocol.TestsEnv+"="+write(protocol.TestsFile, string(tests)))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Running generated program failed: %v\n%s\n%s", err, out, driver)
+	}
+	data, _ := os.ReadFile(results)
+	return data
+}
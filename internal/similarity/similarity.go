@@ -0,0 +1,247 @@
+// Package similarity detects suspiciously similar submissions. Code is
+// reduced to a normalized token stream, hashed in overlapping k-grams and
+// winnowed to a small set of fingerprints, as in MOSS; submissions sharing
+// enough fingerprints are reported with the regions where their token
+// streams line up.
+package similarity
+
+import (
+	"hash/fnv"
+	"sort"
+)
+
+// Defaults of Options
+const (
+	// DefaultK is the k-gram length in tokens, the shortest match noticed
+	DefaultK = 8
+	// DefaultWindow is the winnowing window: any match at least
+	// DefaultK+DefaultWindow-1 tokens long is guaranteed to be found
+	DefaultWindow = 4
+	// DefaultThreshold is the similarity from which a pair is reported
+	DefaultThreshold = 0.5
+)
+
+// Fingerprint is the hash of the k-gram starting at token Pos
+type Fingerprint struct {
+	Hash uint64
+	Pos  int
+}
+
+// Fingerprints winnows the k-grams of a token stream: of every window of w
+// consecutive k-gram hashes the rightmost minimum is kept
+func Fingerprints(tokens []Token, k, w int) []Fingerprint {
+	if len(tokens) < k {
+		return nil
+	}
+	hashes := make([]uint64, len(tokens)-k+1)
+	for i := range hashes {
+		h := fnv.New64a()
+		for _, t := range tokens[i : i+k] {
+			h.Write([]byte(t.Text))
+			h.Write([]byte{0})
+		}
+		hashes[i] = h.Sum64()
+	}
+	var prints []Fingerprint
+	last := -1
+	// Fewer hashes than a window make a single window
+	for start := 0; start < max(1, len(hashes)-w+1); start++ {
+		best := start
+		for i := start; i < min(start+w, len(hashes)); i++ {
+			if hashes[i] <= hashes[best] {
+				best = i
+			}
+		}
+		if best != last {
+			prints = append(prints, Fingerprint{Hash: hashes[best], Pos: best})
+			last = best
+		}
+	}
+	return prints
+}
+
+// Document is a submission to compare
+type Document struct {
+	ID       string
+	Owner    string
+	Language string
+	Code     string
+}
+
+// Options tune Detect. Zero values take the defaults.
+type Options struct {
+	K         int
+	Window    int
+	Threshold float64
+	// Base holds code every submission may share per language, such as the
+	// starter code; its fingerprints never count as a match
+	Base map[string]string
+}
+
+// Lines is a range of lines, both ends included
+type Lines struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Region is a stretch of identical normalized tokens in two documents
+type Region struct {
+	A      Lines `json:"a"`
+	B      Lines `json:"b"`
+	Tokens int   `json:"tokens"`
+}
+
+// Pair is two documents of different owners found similar
+type Pair struct {
+	A        string `json:"a"`
+	B        string `json:"b"`
+	OwnerA   string `json:"ownerA"`
+	OwnerB   string `json:"ownerB"`
+	Language string `json:"lang"`
+	// Similarity is the share of the smaller document's fingerprints also
+	// found in the other, from 0 to 1
+	Similarity float64  `json:"similarity"`
+	Regions    []Region `json:"regions"`
+}
+
+type indexed struct {
+	Document
+	tokens []Token
+	// prints maps each fingerprint hash to the positions it occurs at
+	prints map[uint64][]int
+}
+
+// Detect compares every two documents of the same language made by
+// different owners and returns the pairs at or above the threshold, most
+// similar first
+func Detect(docs []Document, opts Options) []Pair {
+	if opts.K <= 0 {
+		opts.K = DefaultK
+	}
+	if opts.Window <= 0 {
+		opts.Window = DefaultWindow
+	}
+	if opts.Threshold <= 0 {
+		opts.Threshold = DefaultThreshold
+	}
+	base := map[string]map[uint64]bool{}
+	for lang, code := range opts.Base {
+		base[lang] = map[uint64]bool{}
+		for _, f := range Fingerprints(Tokenize(lang, code), opts.K, opts.Window) {
+			base[lang][f.Hash] = true
+		}
+	}
+
+	list := make([]indexed, 0, len(docs))
+	// postings maps each fingerprint hash to the documents holding it
+	postings := map[uint64][]int{}
+	for _, d := range docs {
+		x := indexed{Document: d, tokens: Tokenize(d.Language, d.Code), prints: map[uint64][]int{}}
+		for _, f := range Fingerprints(x.tokens, opts.K, opts.Window) {
+			if base[d.Language][f.Hash] {
+				continue
+			}
+			if len(x.prints[f.Hash]) == 0 {
+				postings[f.Hash] = append(postings[f.Hash], len(list))
+			}
+			x.prints[f.Hash] = append(x.prints[f.Hash], f.Pos)
+		}
+		list = append(list, x)
+	}
+
+	// Only documents sharing a fingerprint are compared
+	shared := map[[2]int]int{}
+	for _, holders := range postings {
+		for i, a := range holders {
+			for _, b := range holders[i+1:] {
+				if list[a].Owner != list[b].Owner && list[a].Language == list[b].Language {
+					shared[[2]int{a, b}]++
+				}
+			}
+		}
+	}
+	pairs := []Pair{}
+	for key, n := range shared {
+		a, b := list[key[0]], list[key[1]]
+		similarity := float64(n) / float64(min(len(a.prints), len(b.prints)))
+		if similarity < opts.Threshold {
+			continue
+		}
+		pairs = append(pairs, Pair{
+			A: a.ID, B: b.ID, OwnerA: a.Owner, OwnerB: b.Owner, Language: a.Language,
+			Similarity: similarity,
+			Regions:    align(a, b, opts.K),
+		})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Similarity != pairs[j].Similarity {
+			return pairs[i].Similarity > pairs[j].Similarity
+		}
+		if pairs[i].A != pairs[j].A {
+			return pairs[i].A < pairs[j].A
+		}
+		return pairs[i].B < pairs[j].B
+	})
+	return pairs
+}
+
+// span is a stretch of matching tokens, [a, a+n) and [b, b+n)
+type span struct{ a, b, n int }
+
+// align finds the regions where the token streams of two documents match.
+// Each shared fingerprint seeds a match that is extended in both directions
+// as far as the tokens agree; the longest matches that overlap no longer
+// one are kept.
+func align(x, y indexed, k int) []Region {
+	var spans []span
+	seen := map[span]bool{}
+	for hash, positions := range x.prints {
+		for _, pa := range positions {
+			for _, pb := range y.prints[hash] {
+				s := span{pa, pb, 0}
+				for s.a > 0 && s.b > 0 && x.tokens[s.a-1].Text == y.tokens[s.b-1].Text {
+					s.a, s.b = s.a-1, s.b-1
+				}
+				for s.a+s.n < len(x.tokens) && s.b+s.n < len(y.tokens) && x.tokens[s.a+s.n].Text == y.tokens[s.b+s.n].Text {
+					s.n++
+				}
+				if s.n >= k && !seen[s] {
+					seen[s] = true
+					spans = append(spans, s)
+				}
+			}
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].n != spans[j].n {
+			return spans[i].n > spans[j].n
+		}
+		if spans[i].a != spans[j].a {
+			return spans[i].a < spans[j].a
+		}
+		return spans[i].b < spans[j].b
+	})
+	var kept []span
+	for _, s := range spans {
+		overlaps := false
+		for _, o := range kept {
+			if s.a < o.a+o.n && o.a < s.a+s.n || s.b < o.b+o.n && o.b < s.b+s.n {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			kept = append(kept, s)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].a < kept[j].a })
+	regions := []Region{}
+	for _, s := range kept {
+		regions = append(regions, Region{
+			A:      Lines{x.tokens[s.a].Line, x.tokens[s.a+s.n-1].Line},
+			B:      Lines{y.tokens[s.b].Line, y.tokens[s.b+s.n-1].Line},
+			Tokens: s.n,
+		})
+	}
+	return regions
+}
@@ -0,0 +1,137 @@
+package similarity
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func texts(tokens []Token) string {
+	var list []string
+	for _, t := range tokens {
+		list = append(list, t.Text)
+	}
+	return strings.Join(list, " ")
+}
+
+func TestTokenize(t *testing.T) {
+	testCases := []struct {
+		lang string
+		code string
+		want string
+	}{
+		{"node", "// sum\nfunction sum(xs) { return xs.reduce((a, b) => a + b, 0) /* done */ }",
+			"function I ( I ) { return I . I ( ( I , I ) => I + I , N ) }"},
+		{"php", "<?php\n# sum\nFUNCTION total($list) { return array_sum($list) . 'x'; }",
+			"function I ( I ) { return I ( I ) . S }"},
+		{"go", "func f(s string) int {\n\tx := `raw\\`\n\treturn len(s) + 0x1f\n}",
+			"func I ( I I ) I { I := S return I ( I ) + N }"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.lang, func(t *testing.T) {
+			if got := texts(Tokenize(tc.lang, tc.code)); got != tc.want {
+				t.Errorf("Expected %q, but got %q", tc.want, got)
+			}
+		})
+	}
+
+	lines := []int{}
+	for _, tok := range Tokenize("node", "a\n\"multi\\\nline\" b\n\nc") {
+		lines = append(lines, tok.Line)
+	}
+	if want := []int{1, 2, 3, 5}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("Expected lines %v, but got %v", want, lines)
+	}
+}
+
+func TestFingerprints(t *testing.T) {
+	tokens := Tokenize("node", "a = b + c * d - e / f % g")
+	prints := Fingerprints(tokens, 3, 4)
+	if len(prints) == 0 || len(prints) > len(tokens)-2 {
+		t.Fatalf("Expected a winnowed subset of the k-grams, but got %d", len(prints))
+	}
+	// Every window of 4 k-grams holds a fingerprint
+	for start := 0; start+4 <= len(tokens)-2; start++ {
+		found := false
+		for _, f := range prints {
+			found = found || f.Pos >= start && f.Pos < start+4
+		}
+		if !found {
+			t.Errorf("Expected a fingerprint in the window at %d", start)
+		}
+	}
+	if got := Fingerprints(tokens[:2], 3, 4); got != nil {
+		t.Errorf("Expected no fingerprint for fewer than k tokens, but got %v", got)
+	}
+	if got := Fingerprints(tokens[:4], 3, 4); len(got) != 1 {
+		t.Errorf("Expected one fingerprint for a partial window, but got %v", got)
+	}
+}
+
+const original = `function intIntoString(n) {
+	// convert the number
+	if (n < 0) {
+		return "-" + intIntoString(-n)
+	}
+	let digits = []
+	do {
+		digits.unshift(n % 10)
+		n = Math.floor(n / 10)
+	} while (n > 0)
+	return digits.join("")
+}
+`
+
+// renamed is original with other names, comments and spacing
+const renamed = `function intIntoString(value){
+  if(value<0){ return '-'+intIntoString(-value) }
+
+
+  let out=[]
+  do { out.unshift(value%10); value=Math.floor(value/10) } while(value>0)
+  return out.join('')
+}
+`
+
+const different = `function intIntoString(n) {
+	return String(n)
+}
+`
+
+func TestDetect(t *testing.T) {
+	docs := []Document{
+		{ID: "1", Owner: "alice", Language: "node", Code: original},
+		{ID: "2", Owner: "bob", Language: "node", Code: renamed},
+		{ID: "3", Owner: "carol", Language: "node", Code: different},
+		// The same owner, or another language, is never compared
+		{ID: "4", Owner: "alice", Language: "node", Code: original},
+		{ID: "5", Owner: "dave", Language: "php", Code: original},
+	}
+	pairs := Detect(docs, Options{})
+	if len(pairs) != 2 {
+		t.Fatalf("Expected alice's two submissions to match bob's, but got %+v", pairs)
+	}
+	p := pairs[0]
+	if p.A != "1" || p.B != "2" || p.Similarity < 0.9 || p.OwnerB != "bob" || p.Language != "node" {
+		t.Errorf("Expected the renamed copy, but got %+v", p)
+	}
+	if len(p.Regions) != 1 || p.Regions[0].A != (Lines{1, 12}) || p.Regions[0].B != (Lines{1, 8}) {
+		t.Errorf("Expected the whole function to align, but got %+v", p.Regions)
+	}
+
+	// Starter code shared by everyone does not count
+	starter := "function intIntoString(n){\n\treturn\n}\n"
+	boilerplate := []Document{
+		{ID: "a", Owner: "x", Language: "node", Code: starter},
+		{ID: "b", Owner: "y", Language: "node", Code: starter},
+	}
+	if pairs := Detect(boilerplate, Options{K: 3, Window: 2}); len(pairs) != 1 {
+		t.Fatalf("Expected identical starters to match, but got %+v", pairs)
+	}
+	if pairs := Detect(boilerplate, Options{K: 3, Window: 2, Base: map[string]string{"node": starter}}); len(pairs) != 0 {
+		t.Errorf("Expected the starter code to be ignored, but got %+v", pairs)
+	}
+	if pairs := Detect(docs, Options{Threshold: 1.01}); len(pairs) != 0 {
+		t.Errorf("Expected nothing above the threshold, but got %+v", pairs)
+	}
+}
@@ -0,0 +1,138 @@
+package similarity
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Token is a normalized lexical token. Identifiers become "I", numbers "N"
+// and string literals "S", while keywords, operators and punctuation keep
+// their text, so renaming variables or changing literals leaves the stream
+// unchanged. Comments, whitespace and semicolons are dropped.
+type Token struct {
+	Text string
+	// Line is the 1-based line the token starts on
+	Line int
+}
+
+// keywords holds the words kept as they are per language
+var keywords = map[string]map[string]bool{
+	"go": set(`break case chan const continue default defer else fallthrough for func go goto if import
+		interface map package range return select struct switch type var`),
+	"node": set(`async await break case catch class const continue debugger default delete do else export
+		extends false finally for function if import in instanceof let new null of return super switch this
+		throw true try typeof undefined var void while with yield`),
+	"php": set(`abstract and array as break callable case catch class clone const continue declare default do
+		echo else elseif empty enddeclare endfor endforeach endif endswitch endwhile extends false final finally
+		fn for foreach function global goto if implements include include_once instanceof insteadof interface
+		isset list match namespace new null or print private protected public require require_once return
+		static switch throw trait true try unset use var while xor yield`),
+}
+
+func set(words string) map[string]bool {
+	m := map[string]bool{}
+	for _, w := range strings.Fields(words) {
+		m[w] = true
+	}
+	return m
+}
+
+// operators holds the multi-character operators, longest first
+var operators = []string{
+	"<<=", ">>=", "===", "!==", "...", "**=", "&^=", ">>>", "??=",
+	"=>", "->", "::", "==", "!=", "<=", ">=", "&&", "||", "++", "--", "+=", "-=", "*=", "/=", "%=",
+	"&=", "|=", "^=", "<<", ">>", ":=", "<-", "??", "?.", "&^", "**",
+}
+
+// Tokenize splits the code of a language ("node", "php" or "go") into
+// normalized tokens
+func Tokenize(language, code string) []Token {
+	kw := keywords[language]
+	var tokens []Token
+	line := 1
+	emit := func(text string) {
+		tokens = append(tokens, Token{Text: text, Line: line})
+	}
+	// advance moves past n bytes, counting the lines they span
+	i := 0
+	advance := func(n int) {
+		line += strings.Count(code[i:i+n], "\n")
+		i += n
+	}
+	for i < len(code) {
+		c := code[i]
+		rest := code[i:]
+		switch {
+		case c == '\n' || c == ' ' || c == '\t' || c == '\r' || c == ';':
+			// Semicolons are optional in Node and Go, so they are dropped
+			// like whitespace
+			advance(1)
+		case strings.HasPrefix(rest, "<?php"):
+			advance(5)
+		case strings.HasPrefix(rest, "?>") && language == "php":
+			advance(2)
+		case strings.HasPrefix(rest, "//") || c == '#' && language == "php":
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			advance(end)
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				advance(len(rest))
+			} else {
+				advance(end + 4)
+			}
+		case c == '"' || c == '\'' || c == '`':
+			start := line
+			n := 1
+			for n < len(rest) && rest[n] != c {
+				// Go raw strings have no escapes
+				if rest[n] == '\\' && !(c == '`' && language == "go") {
+					n++
+				}
+				n++
+			}
+			advance(min(n+1, len(rest)))
+			tokens = append(tokens, Token{Text: "S", Line: start})
+		case c >= '0' && c <= '9' || c == '.' && len(rest) > 1 && rest[1] >= '0' && rest[1] <= '9':
+			n := 1
+			for n < len(rest) && (isWord(rune(rest[n])) || rest[n] == '.') {
+				n++
+			}
+			emit("N")
+			advance(n)
+		case isWord(rune(c)) || c == '$' || c >= 0x80:
+			n := 1
+			for n < len(rest) && (isWord(rune(rest[n])) || rest[n] == '$' || rest[n] >= 0x80) {
+				n++
+			}
+			word := rest[:n]
+			if language == "php" {
+				word = strings.ToLower(word)
+			}
+			if kw[word] {
+				emit(word)
+			} else {
+				emit("I")
+			}
+			advance(n)
+		default:
+			op := rest[:1]
+			for _, o := range operators {
+				if strings.HasPrefix(rest, o) {
+					op = o
+					break
+				}
+			}
+			emit(op)
+			advance(len(op))
+		}
+	}
+	return tokens
+}
+
+func isWord(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,97 @@
+// Package statement renders problem statements for the page: the Markdown
+// statement as sanitized HTML followed by the problem's sample tests as
+// worked examples. Renders are cached per problem version, so a statement
+// is rendered once however often it is served.
+package statement
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+
+	"github.com/afman42/go-web-code-interactive/internal/markdown"
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+	"github.com/afman42/go-web-code-interactive/internal/signature"
+)
+
+// Render returns the HTML of a problem's statement and its examples
+func Render(p problem.Problem) string {
+	var b strings.Builder
+	b.WriteString(markdown.Render(p.Statement))
+	samples := p.Samples()
+	if len(samples) == 0 {
+		return b.String()
+	}
+	var params []signature.Param
+	if sig, err := signature.Parse(p.Signature); err == nil {
+		params = sig.Params
+	}
+	b.WriteString("<h3>Examples</h3>\n")
+	for i, tc := range samples {
+		fmt.Fprintf(&b, "<div class=\"example\">\n<h4>Example %d</h4>\n", i+1)
+		b.WriteString("<pre><strong>Input:</strong> " + html.EscapeString(input(p.Function, params, tc.Args)) + "\n")
+		b.WriteString("<strong>Output:</strong> " + html.EscapeString(compact(tc.Expected)) + "</pre>\n</div>\n")
+	}
+	return b.String()
+}
+
+// input formats the arguments of a test as name = value pairs, or as a call
+// of the function when the parameters are not known
+func input(function string, params []signature.Param, args []json.RawMessage) string {
+	values := make([]string, len(args))
+	for i, arg := range args {
+		values[i] = compact(arg)
+	}
+	if len(params) != len(args) {
+		return function + "(" + strings.Join(values, ", ") + ")"
+	}
+	for i, p := range params {
+		values[i] = p.Name + " = " + values[i]
+	}
+	return strings.Join(values, ", ")
+}
+
+func compact(raw json.RawMessage) string {
+	var b bytes.Buffer
+	if err := json.Compact(&b, raw); err != nil {
+		return string(raw)
+	}
+	return b.String()
+}
+
+type entry struct {
+	version int
+	html    string
+}
+
+// Cache holds the rendered statement of each problem's latest version
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+// NewCache creates an empty cache
+func NewCache() *Cache {
+	return &Cache{entries: make(map[string]entry)}
+}
+
+// HTML returns the rendered statement of a problem, rendering it if the
+// cache holds none for its version
+func (c *Cache) HTML(p problem.Problem) string {
+	c.mu.Lock()
+	e, ok := c.entries[p.ID]
+	c.mu.Unlock()
+	if ok && e.version == p.Version {
+		return e.html
+	}
+	e = entry{version: p.Version, html: Render(p)}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.entries[p.ID]; !ok || old.version <= e.version {
+		c.entries[p.ID] = e
+	}
+	return e.html
+}
@@ -0,0 +1,59 @@
+package statement
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+)
+
+func TestRender(t *testing.T) {
+	p := problem.Problem{
+		ID:        "two-sum",
+		Function:  "twoSum",
+		Signature: "twoSum(nums: []int, target: int) -> []int",
+		Statement: "Return the indices of the two numbers adding up to `target`.",
+		Tests: []problem.TestCase{
+			{Args: []json.RawMessage{json.RawMessage(`[2, 7, 11]`), json.RawMessage(`9`)}, Expected: json.RawMessage(`[0, 1]`)},
+			{Args: []json.RawMessage{json.RawMessage(`[1]`), json.RawMessage(`1`)}, Expected: json.RawMessage(`[]`), Hidden: true},
+		},
+	}
+	want := "<p>Return the indices of the two numbers adding up to <code>target</code>.</p>\n" +
+		"<h3>Examples</h3>\n<div class=\"example\">\n<h4>Example 1</h4>\n" +
+		"<pre><strong>Input:</strong> nums = [2,7,11], target = 9\n<strong>Output:</strong> [0,1]</pre>\n</div>\n"
+	if got := Render(p); got != want {
+		t.Errorf("Expected %q, but got %q", want, got)
+	}
+
+	t.Run("Without signature", func(t *testing.T) {
+		p := problem.Problem{Function: "echo", Statement: "Echo.", Tests: []problem.TestCase{
+			{Args: []json.RawMessage{json.RawMessage(`"<b>"`)}, Expected: json.RawMessage(`"<b>"`)},
+		}}
+		if got := Render(p); !strings.Contains(got, "<strong>Input:</strong> echo(&#34;&lt;b&gt;&#34;)\n<strong>Output:</strong> &#34;&lt;b&gt;&#34;") {
+			t.Errorf("Expected the escaped call, but got %q", got)
+		}
+	})
+
+	t.Run("Without samples", func(t *testing.T) {
+		if got := Render(problem.Problem{Statement: "Hi"}); got != "<p>Hi</p>\n" {
+			t.Errorf("Expected no examples, but got %q", got)
+		}
+	})
+}
+
+func TestCache(t *testing.T) {
+	c := NewCache()
+	p := problem.Problem{ID: "p", Version: 1, Statement: "One"}
+	if got := c.HTML(p); got != "<p>One</p>\n" {
+		t.Fatalf("Expected the rendered statement, but got %q", got)
+	}
+	p.Statement = "Changed"
+	if got := c.HTML(p); got != "<p>One</p>\n" {
+		t.Errorf("Expected the cached render of version 1, but got %q", got)
+	}
+	p.Version = 2
+	if got := c.HTML(p); got != "<p>Changed</p>\n" {
+		t.Errorf("Expected a new version to be rendered, but got %q", got)
+	}
+}
@@ -0,0 +1,138 @@
+package submission
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// LogFile is the name of the file store's log in its directory
+const LogFile = "submissions.jsonl"
+
+// FileStore keeps submissions in an append-only log of JSON lines, read
+// into memory when the store is opened. It is pure Go, so builds without
+// cgo keep working.
+type FileStore struct {
+	mu   sync.RWMutex
+	file *os.File
+	// all holds every submission, oldest first, and byID indexes it
+	all  []Submission
+	byID map[string]int
+}
+
+// OpenFile opens the store in dir, creating the directory and log if
+// needed. A log cut short by a crash loses only its last line.
+func OpenFile(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, LogFile), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileStore{file: f, byID: make(map[string]int)}
+	reader := bufio.NewReader(f)
+	var offset int64
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			f.Close()
+			return nil, err
+		}
+		if len(bytes.TrimSpace(data)) == 0 {
+			if err == io.EOF {
+				break
+			}
+			offset += int64(len(data))
+			continue
+		}
+		sub, jsonErr := decode(data)
+		if jsonErr != nil {
+			if err != io.EOF {
+				f.Close()
+				return nil, fmt.Errorf("%s line %d: %v", LogFile, line, jsonErr)
+			}
+			// A write cut short, the next one would continue its line
+			if err := f.Truncate(offset); err != nil {
+				f.Close()
+				return nil, err
+			}
+			break
+		}
+		s.add(sub)
+		offset += int64(len(data))
+		if err == io.EOF {
+			if _, err := f.Write([]byte("\n")); err != nil {
+				f.Close()
+				return nil, err
+			}
+			break
+		}
+	}
+	return s, nil
+}
+
+func (s *FileStore) add(sub Submission) {
+	s.byID[sub.ID] = len(s.all)
+	s.all = append(s.all, sub)
+}
+
+// Save appends a submission to the log
+func (s *FileStore) Save(sub Submission) (Submission, error) {
+	sub = prepare(sub)
+	data, err := encode(sub)
+	if err != nil {
+		return Submission{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.byID[sub.ID]; exists {
+		return Submission{}, fmt.Errorf("duplicate submission id %s", sub.ID)
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return Submission{}, err
+	}
+	s.add(sub)
+	return sub, nil
+}
+
+// Get returns a submission by ID
+func (s *FileStore) Get(id string) (Submission, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i, ok := s.byID[id]
+	if !ok {
+		return Submission{}, ErrNotFound
+	}
+	return s.all[i], nil
+}
+
+// List returns the matching submissions, newest first
+func (s *FileStore) List(f Filter) ([]Submission, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	matches := []Submission{}
+	for i := len(s.all) - 1; i >= 0; i-- {
+		if f.Match(s.all[i]) {
+			matches = append(matches, s.all[i])
+		}
+	}
+	// The log is in save order, which may differ from creation order for
+	// imported submissions
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Created.After(matches[j].Created) })
+	if f.Offset >= len(matches) {
+		return []Submission{}, nil
+	}
+	matches = matches[f.Offset:]
+	return matches[:min(len(matches), f.limit())], nil
+}
+
+// Close closes the log
+func (s *FileStore) Close() error {
+	return s.file.Close()
+}
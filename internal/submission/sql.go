@@ -0,0 +1,157 @@
+package submission
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// schema creates the submissions table. The filtered fields have columns of
+// their own, the whole submission is kept as JSON in data.
+const schema = `CREATE TABLE IF NOT EXISTS submissions (
+	id VARCHAR(32) PRIMARY KEY,
+	owner VARCHAR(255) NOT NULL,
+	language VARCHAR(16) NOT NULL,
+	problem_id VARCHAR(255) NOT NULL,
+	verdict VARCHAR(64) NOT NULL,
+	created_ns BIGINT NOT NULL,
+	data TEXT NOT NULL
+)`
+
+// numberedDrivers use $1, $2, ... placeholders instead of ?
+var numberedDrivers = map[string]bool{"postgres": true, "pgx": true, "cloudsqlpostgres": true}
+
+// SQLStore keeps submissions in a table of any database/sql database. The
+// driver is not part of this module, a deployment links it into the binary
+// with a blank import in a file of package main behind a build tag, such as
+// one with "//go:build postgres" importing _ "github.com/lib/pq", built with
+// go build -tags postgres after go get github.com/lib/pq.
+type SQLStore struct {
+	db       *sql.DB
+	numbered bool
+}
+
+// OpenSQL connects to the database with the named driver and creates the
+// submissions table if needed
+func OpenSQL(driver, dsn string) (*SQLStore, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	s, err := NewSQL(db, driver)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewSQL uses an open database, creating the submissions table if needed
+func NewSQL(db *sql.DB, driver string) (*SQLStore, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("creating submissions table: %v", err)
+	}
+	return &SQLStore{db: db, numbered: numberedDrivers[driver]}, nil
+}
+
+// rebind rewrites ? placeholders for drivers that number them
+func (s *SQLStore) rebind(query string) string {
+	if !s.numbered {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Save inserts a submission
+func (s *SQLStore) Save(sub Submission) (Submission, error) {
+	sub = prepare(sub)
+	data, err := encode(sub)
+	if err != nil {
+		return Submission{}, err
+	}
+	_, err = s.db.Exec(s.rebind(`INSERT INTO submissions (id, owner, language, problem_id, verdict, created_ns, data) VALUES (?, ?, ?, ?, ?, ?, ?)`),
+		sub.ID, sub.Owner, sub.Language, sub.ProblemID, sub.Verdict, sub.Created.UnixNano(), string(data))
+	if err != nil {
+		return Submission{}, err
+	}
+	return sub, nil
+}
+
+// Get returns a submission by ID
+func (s *SQLStore) Get(id string) (Submission, error) {
+	var data string
+	err := s.db.QueryRow(s.rebind(`SELECT data FROM submissions WHERE id = ?`), id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Submission{}, ErrNotFound
+	}
+	if err != nil {
+		return Submission{}, err
+	}
+	return decode([]byte(data))
+}
+
+// List returns the matching submissions, newest first
+func (s *SQLStore) List(f Filter) ([]Submission, error) {
+	query, args := listQuery(f)
+	rows, err := s.db.Query(s.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []Submission{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		sub, err := decode([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, sub)
+	}
+	return list, rows.Err()
+}
+
+// listQuery builds the query selecting the submissions of a filter
+func listQuery(f Filter) (string, []any) {
+	var where []string
+	var args []any
+	for _, c := range []struct{ column, value string }{{"owner", f.Owner}, {"problem_id", f.ProblemID}, {"language", f.Language}} {
+		if c.value != "" {
+			where = append(where, c.column+" = ?")
+			args = append(args, c.value)
+		}
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "created_ns >= ?")
+		args = append(args, f.Since.UnixNano())
+	}
+	if !f.Until.IsZero() {
+		where = append(where, "created_ns < ?")
+		args = append(args, f.Until.UnixNano())
+	}
+	query := "SELECT data FROM submissions"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY created_ns DESC LIMIT ? OFFSET ?"
+	return query, append(args, f.limit(), max(f.Offset, 0))
+}
+
+// Close closes the database
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,145 @@
+package submission
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDriver is an in-memory database understanding exactly the statements
+// of SQLStore, rows are maps from column to value
+type fakeDriver struct {
+	mu   sync.Mutex
+	rows []map[string]driver.Value
+}
+
+var fakeDB = &fakeDriver{}
+
+func init() {
+	sql.Register("submissionfake", fakeDB)
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.d, query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)                 { return nil, fmt.Errorf("not supported") }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return strings.Count(s.query, "?") }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if strings.HasPrefix(s.query, "INSERT") {
+		columns := strings.Split(s.query[strings.Index(s.query, "(")+1:strings.Index(s.query, ")")], ", ")
+		row := map[string]driver.Value{}
+		for i, column := range columns {
+			row[column] = args[i]
+		}
+		s.d.rows = append(s.d.rows, row)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+// Query evaluates "column op ?" conditions joined by AND, with an optional
+// ORDER BY created_ns DESC LIMIT ? OFFSET ?
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	query, limit, offset := s.query, -1, 0
+	if i := strings.Index(query, " ORDER BY"); i >= 0 {
+		query = query[:i]
+		limit, offset = int(args[len(args)-2].(int64)), int(args[len(args)-1].(int64))
+		args = args[:len(args)-2]
+	}
+	var conditions []string
+	if _, where, ok := strings.Cut(query, " WHERE "); ok {
+		conditions = strings.Split(where, " AND ")
+	}
+	matches := [][]driver.Value{}
+	var created []int64
+	for _, row := range s.d.rows {
+		match := true
+		for i, condition := range conditions {
+			fields := strings.Fields(condition)
+			value := row[fields[0]]
+			switch fields[1] {
+			case "=":
+				match = match && value == args[i]
+			case ">=":
+				match = match && value.(int64) >= args[i].(int64)
+			case "<":
+				match = match && value.(int64) < args[i].(int64)
+			}
+		}
+		if match {
+			matches = append(matches, []driver.Value{row["data"]})
+			created = append(created, row["created_ns"].(int64))
+		}
+	}
+	order := make([]int, len(matches))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return created[order[i]] > created[order[j]] })
+	sorted := [][]driver.Value{}
+	for _, i := range order {
+		sorted = append(sorted, matches[i])
+	}
+	if offset > len(sorted) {
+		offset = len(sorted)
+	}
+	sorted = sorted[offset:]
+	if limit >= 0 && limit < len(sorted) {
+		sorted = sorted[:limit]
+	}
+	return &fakeRows{rows: sorted}, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return []string{"data"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestSQLStore(t *testing.T) {
+	store, err := OpenSQL("submissionfake", "")
+	if err != nil {
+		t.Fatalf("OpenSQL failed: %v", err)
+	}
+	defer store.Close()
+	testStore(t, store)
+}
+
+func TestRebind(t *testing.T) {
+	query := "SELECT data FROM submissions WHERE owner = ? AND language = ? LIMIT ?"
+	if got := (&SQLStore{}).rebind(query); got != query {
+		t.Errorf("Expected ? placeholders to stay, but got %q", got)
+	}
+	want := "SELECT data FROM submissions WHERE owner = $1 AND language = $2 LIMIT $3"
+	if got := (&SQLStore{numbered: true}).rebind(query); got != want {
+		t.Errorf("Expected %q, but got %q", want, got)
+	}
+}
@@ -0,0 +1,147 @@
+// Package submission keeps the history of executed code. A Store persists
+// submissions; the file store is the default and needs nothing but the
+// filesystem, the SQL store works with any database/sql driver linked into
+// the binary.
+package submission
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/judge"
+	"github.com/afman42/go-web-code-interactive/internal/unittest"
+)
+
+// ErrNotFound is returned for an unknown submission ID
+var ErrNotFound = errors.New("submission not found")
+
+// DefaultLimit is how many submissions a listing returns unless it asks for
+// fewer
+const DefaultLimit = 50
+
+// Submission is one execution of code along with what it produced
+type Submission struct {
+	ID string `json:"id"`
+	// Owner identifies the client that sent the code, the stores keep it
+	// but the API leaves it out
+	Owner    string `json:"-"`
+	Language string `json:"lang"`
+	Type     string `json:"type"`
+	Code     string `json:"txt"`
+	Stdin    string `json:"stdin,omitempty"`
+	// ProblemID and ProblemVersion name the exact problem an STQ submission
+	// was graded against
+	ProblemID      string `json:"problemId,omitempty"`
+	ProblemVersion int    `json:"problemVersion,omitempty"`
+	// ContestID names the contest the submission was made to
+	ContestID string `json:"contestId,omitempty"`
+	Mode      string `json:"mode,omitempty"`
+	Stdout    string `json:"out"`
+	Stderr    string `json:"errout"`
+	// Verdict, Passed, Total and Score summarize the Result of an STQ
+	// submission
+	Verdict string        `json:"verdict,omitempty"`
+	Passed  int           `json:"passed"`
+	Total   int           `json:"total"`
+	Score   int           `json:"score"`
+	Result  *judge.Result `json:"result,omitempty"`
+	// Tests and TestReport are the user's own tests of a test submission and
+	// their results; Passed and Total count them
+	Tests      string           `json:"tests,omitempty"`
+	TestReport *unittest.Report `json:"testReport,omitempty"`
+	DurationMs int64            `json:"durationMs"`
+	Warm       bool             `json:"warm"`
+	Created    time.Time        `json:"created"`
+}
+
+// stored is a submission as the stores write it, with its owner
+type stored struct {
+	Submission
+	Owner string `json:"owner"`
+}
+
+// encode marshals a submission for a store
+func encode(s Submission) ([]byte, error) {
+	return json.Marshal(stored{Submission: s, Owner: s.Owner})
+}
+
+// decode unmarshals a submission written by encode
+func decode(data []byte) (Submission, error) {
+	var s stored
+	err := json.Unmarshal(data, &s)
+	s.Submission.Owner = s.Owner
+	return s.Submission, err
+}
+
+// Summary returns the submission without its code, output and per-test
+// results, for listings
+func (s Submission) Summary() Submission {
+	s.Code, s.Stdin, s.Stdout, s.Stderr, s.Result = "", "", "", "", nil
+	s.Tests, s.TestReport = "", nil
+	return s
+}
+
+// Filter selects submissions in a listing. Empty fields match everything.
+type Filter struct {
+	Owner     string
+	ProblemID string
+	Language  string
+	// Since and Until bound the creation time, zero values are unbounded
+	Since time.Time
+	Until time.Time
+	// Limit and Offset page through the matches, newest first
+	Limit  int
+	Offset int
+}
+
+// Match reports whether a submission passes the filter
+func (f Filter) Match(s Submission) bool {
+	return (f.Owner == "" || s.Owner == f.Owner) &&
+		(f.ProblemID == "" || s.ProblemID == f.ProblemID) &&
+		(f.Language == "" || s.Language == f.Language) &&
+		(f.Since.IsZero() || !s.Created.Before(f.Since)) &&
+		(f.Until.IsZero() || s.Created.Before(f.Until))
+}
+
+// limit returns the page size of the filter
+func (f Filter) limit() int {
+	if f.Limit <= 0 {
+		return DefaultLimit
+	}
+	return f.Limit
+}
+
+// Store persists submissions
+type Store interface {
+	// Save stores a new submission, assigning its ID and creation time
+	// unless they are set
+	Save(s Submission) (Submission, error)
+	// Get returns a submission by ID or ErrNotFound
+	Get(id string) (Submission, error)
+	// List returns the submissions matching the filter, newest first
+	List(f Filter) ([]Submission, error)
+	Close() error
+}
+
+// prepare assigns the ID and creation time of a new submission
+func prepare(s Submission) Submission {
+	if s.ID == "" {
+		b := make([]byte, 8)
+		rand.Read(b)
+		s.ID = hex.EncodeToString(b)
+	}
+	if s.Created.IsZero() {
+		s.Created = time.Now()
+	}
+	s.Created = s.Created.UTC()
+	if s.Result != nil {
+		s.Verdict, s.Passed, s.Total, s.Score = s.Result.Verdict, s.Result.Passed, s.Result.Total, s.Result.Score
+	}
+	if s.TestReport != nil {
+		s.Passed, s.Total = s.TestReport.Passed, s.TestReport.Total
+	}
+	return s
+}
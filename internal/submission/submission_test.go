@@ -0,0 +1,117 @@
+package submission
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/judge"
+)
+
+// testStore runs the behavior every Store shares
+func testStore(t *testing.T, store Store) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	saved := []Submission{}
+	for i, s := range []Submission{
+		{Owner: "a", Language: "node", Type: "repl", Code: "1"},
+		{Owner: "a", Language: "go", Type: "stq", Code: "2", ProblemID: "sum", ProblemVersion: 2,
+			Result: &judge.Result{Verdict: judge.WrongAnswer, Passed: 1, Total: 2, Score: 50}},
+		{Owner: "b", Language: "node", Type: "stq", Code: "3", ProblemID: "sum"},
+	} {
+		s.Created = base.Add(time.Duration(i) * time.Minute)
+		s, err := store.Save(s)
+		if err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		if s.ID == "" {
+			t.Fatal("Expected an ID to be assigned")
+		}
+		saved = append(saved, s)
+	}
+
+	got, err := store.Get(saved[1].ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got.Owner != "a" || got.Code != "2" || got.ProblemVersion != 2 || got.Verdict != judge.WrongAnswer || got.Score != 50 || got.Result == nil || !got.Created.Equal(saved[1].Created) {
+		t.Errorf("Expected the saved submission with its summary, but got %+v", got)
+	}
+	if _, err := store.Get("missing"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, but got %v", err)
+	}
+
+	testCases := []struct {
+		name   string
+		filter Filter
+		codes  []string
+	}{
+		{"all newest first", Filter{}, []string{"3", "2", "1"}},
+		{"owner", Filter{Owner: "a"}, []string{"2", "1"}},
+		{"problem and language", Filter{ProblemID: "sum", Language: "node"}, []string{"3"}},
+		{"time window", Filter{Since: base.Add(time.Minute), Until: base.Add(2 * time.Minute)}, []string{"2"}},
+		{"page", Filter{Limit: 1, Offset: 1}, []string{"2"}},
+		{"past the end", Filter{Offset: 5}, []string{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			list, err := store.List(tc.filter)
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			codes := []string{}
+			for _, s := range list {
+				codes = append(codes, s.Code)
+			}
+			if len(codes) != len(tc.codes) {
+				t.Fatalf("Expected %v, but got %v", tc.codes, codes)
+			}
+			for i := range codes {
+				if codes[i] != tc.codes[i] {
+					t.Errorf("Expected %v, but got %v", tc.codes, codes)
+				}
+			}
+		})
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenFile(dir)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	testStore(t, store)
+	store.Close()
+
+	// A write cut short by a crash is dropped when the log is reopened
+	f, _ := os.OpenFile(filepath.Join(dir, LogFile), os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString(`{"id": "partial", "txt": "4`)
+	f.Close()
+	store, err = OpenFile(dir)
+	if err != nil {
+		t.Fatalf("Expected the log to reopen, but got %v", err)
+	}
+	if list, _ := store.List(Filter{}); len(list) != 3 {
+		t.Errorf("Expected the 3 saved submissions after reopening, but got %d", len(list))
+	}
+	if _, err := store.Save(Submission{Code: "5"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	store.Close()
+	store, err = OpenFile(dir)
+	if err != nil {
+		t.Fatalf("Expected the repaired log to reopen, but got %v", err)
+	}
+	defer store.Close()
+	if list, _ := store.List(Filter{}); len(list) != 4 || list[0].Code != "5" {
+		t.Errorf("Expected the new submission after the repaired log, but got %+v", list)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	s := Submission{ID: "x", Code: "secret", Stdout: "out", Result: &judge.Result{}}.Summary()
+	if s.ID != "x" || s.Code != "" || s.Stdout != "" || s.Result != nil {
+		t.Errorf("Expected the summary without code and output, but got %+v", s)
+	}
+}
@@ -0,0 +1,86 @@
+package unittest
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// goEvent is a line of `go test -json`, see `go doc test2json`
+type goEvent struct {
+	Action  string
+	Test    string
+	Elapsed float64
+	Output  string
+	// OutputType is "frame" for the lines the testing package writes about
+	// a test and "error" for those of t.Error and friends
+	OutputType string
+}
+
+// parseGoTest reads the events of `go test -json`. The lines a test logged
+// through t.Error, t.Fatal or t.Skip explain its failure or skip, the rest
+// are its output.
+func parseGoTest(stdout string) Report {
+	var r Report
+	var build strings.Builder
+	index := map[string]int{}
+	failures := map[string]*strings.Builder{}
+	outputs := map[string]*strings.Builder{}
+	var global strings.Builder
+	for _, line := range strings.Split(stdout, "\n") {
+		var e goEvent
+		if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &e) != nil {
+			continue
+		}
+		switch e.Action {
+		case "build-output":
+			// The first line only names the package
+			if !strings.HasPrefix(e.Output, "# ") {
+				build.WriteString(e.Output)
+			}
+		case "run":
+			if _, ok := index[e.Test]; !ok && e.Test != "" {
+				index[e.Test] = len(r.Tests)
+				r.Tests = append(r.Tests, Case{Name: e.Test})
+				failures[e.Test], outputs[e.Test] = &strings.Builder{}, &strings.Builder{}
+			}
+		case "output":
+			if e.OutputType == "frame" {
+				continue
+			}
+			if e.Test == "" {
+				global.WriteString(e.Output)
+			} else if _, ok := index[e.Test]; ok {
+				text := strings.TrimPrefix(e.Output, "    ")
+				if e.OutputType == "error" || e.OutputType == "error-continue" {
+					failures[e.Test].WriteString(text)
+				} else {
+					outputs[e.Test].WriteString(text)
+				}
+			}
+		case "pass", "fail", "skip":
+			i, ok := index[e.Test]
+			if !ok {
+				continue
+			}
+			c := &r.Tests[i]
+			c.Status = e.Action
+			c.DurationMs = e.Elapsed * 1000
+			c.Failure = strings.TrimRight(failures[e.Test].String(), "\n")
+			c.Output = strings.TrimRight(outputs[e.Test].String(), "\n")
+			// Skip messages are logged like any output
+			if e.Action == Skip && c.Failure == "" {
+				c.Failure, c.Output = c.Output, ""
+			}
+		}
+	}
+	// Tests cut short by a timeout or a crash never finish
+	for i := range r.Tests {
+		if r.Tests[i].Status == "" {
+			r.Tests[i].Status = Fail
+			r.Tests[i].Failure = strings.TrimRight(failures[r.Tests[i].Name].String()+outputs[r.Tests[i].Name].String(), "\n")
+		}
+	}
+	r.BuildError = strings.TrimRight(build.String(), "\n")
+	r.Output = strings.TrimRight(global.String(), "\n")
+	return summarize(r)
+}
@@ -0,0 +1,54 @@
+package unittest
+
+import (
+	"reflect"
+	"testing"
+)
+
+const goTestOutput = `{"Action":"start","Package":"command-line-arguments"}
+{"Action":"run","Test":"TestSum"}
+{"Action":"output","Test":"TestSum","Output":"=== RUN   TestSum\n","OutputType":"frame"}
+{"Action":"output","Test":"TestSum","Output":"out\n"}
+{"Action":"output","Test":"TestSum","Output":"    main_test.go:5: log\n"}
+{"Action":"output","Test":"TestSum","Output":"--- PASS: TestSum (0.01s)\n","OutputType":"frame"}
+{"Action":"pass","Test":"TestSum","Elapsed":0.01}
+{"Action":"run","Test":"TestBad"}
+{"Action":"run","Test":"TestBad/sub"}
+{"Action":"output","Test":"TestBad/sub","Output":"    main_test.go:6: want 4\n","OutputType":"error"}
+{"Action":"fail","Test":"TestBad/sub","Elapsed":0}
+{"Action":"fail","Test":"TestBad","Elapsed":0}
+{"Action":"run","Test":"TestSkip"}
+{"Action":"output","Test":"TestSkip","Output":"    main_test.go:7: later\n"}
+{"Action":"skip","Test":"TestSkip","Elapsed":0}
+{"Action":"run","Test":"TestLoop"}
+{"Action":"output","Test":"TestLoop","Output":"started\n"}
+{"Action":"output","Output":"init\n"}
+{"Action":"output","Output":"FAIL\n","OutputType":"frame"}
+{"Action":"fail","Elapsed":0.004}
+`
+
+func TestParseGoTest(t *testing.T) {
+	r := parseGoTest(goTestOutput)
+	want := []Case{
+		{Name: "TestSum", Status: Pass, DurationMs: 10, Output: "out\nmain_test.go:5: log"},
+		{Name: "TestBad", Status: Fail},
+		{Name: "TestBad/sub", Status: Fail, Failure: "main_test.go:6: want 4"},
+		{Name: "TestSkip", Status: Skip, Failure: "main_test.go:7: later"},
+		// A test that never finished, killed by the timeout
+		{Name: "TestLoop", Status: Fail, Failure: "started"},
+	}
+	if !reflect.DeepEqual(r.Tests, want) {
+		t.Errorf("Expected %+v, but got %+v", want, r.Tests)
+	}
+	if r.Passed != 1 || r.Failed != 3 || r.Skipped != 1 || r.Total != 5 || r.Output != "init" {
+		t.Errorf("Expected the counts and the package output, but got %+v", r)
+	}
+
+	build := parseGoTest(`{"ImportPath":"x","Action":"build-output","Output":"# command-line-arguments\n"}
+{"ImportPath":"x","Action":"build-output","Output":"main.go:2:30: undefined: b\n"}
+{"ImportPath":"x","Action":"build-fail"}
+{"Action":"fail","Elapsed":0,"FailedBuild":"x"}`)
+	if build.BuildError != "main.go:2:30: undefined: b" || build.Total != 0 || build.Tests == nil {
+		t.Errorf("Expected the compiler errors, but got %+v", build)
+	}
+}
@@ -0,0 +1,180 @@
+package unittest
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/afman42/go-web-code-interactive/internal/protocol"
+)
+
+// phpRunnerFile is the name of the bundled runner in a PHP suite
+const phpRunnerFile = "stq_runner.php"
+
+// phpRunner loads the user's code and tests, then runs the public test*
+// methods of every concrete TestCase subclass, each on a fresh instance
+// between setUp and tearDown. TestCase is declared as PHPUnit's, so tests
+// written for PHPUnit with `use PHPUnit\Framework\TestCase` run unchanged
+// as long as they stick to the common assertions. Each test is reported as a
+// record of the results file, with what it printed.
+const phpRunner = `<?php
+namespace PHPUnit\Framework {
+    class AssertionFailedError extends \Exception {}
+    class SkippedTestError extends \Exception {}
+
+    abstract class TestCase {
+        private $expectedException = null;
+
+        protected function setUp(): void {}
+        protected function tearDown(): void {}
+
+        public function expectException(string $class): void { $this->expectedException = $class; }
+
+        // stqRun runs one test between setUp and tearDown for the runner
+        public function stqRun(string $method): void {
+            $this->setUp();
+            try {
+                $thrown = null;
+                try {
+                    $this->$method();
+                } catch (AssertionFailedError | SkippedTestError $e) {
+                    throw $e;
+                } catch (\Throwable $e) {
+                    $thrown = $e;
+                }
+                $expected = $this->expectedException;
+                if ($thrown !== null && ($expected === null || !($thrown instanceof $expected))) {
+                    throw $thrown;
+                }
+                if ($thrown === null && $expected !== null) {
+                    throw new AssertionFailedError('Failed asserting that exception of type "' . $expected . '" is thrown.');
+                }
+            } finally {
+                $this->tearDown();
+            }
+        }
+
+        public static function fail(string $message = 'Failed'): void { throw new AssertionFailedError($message); }
+        public static function markTestSkipped(string $message = ''): void { throw new SkippedTestError($message); }
+
+        private static function check(bool $ok, string $message, string $default): void {
+            if (!$ok) {
+                throw new AssertionFailedError($message !== '' ? $message . "\n" . $default : $default);
+            }
+        }
+        private static function show($value): string { return var_export($value, true); }
+
+        public static function assertTrue($actual, string $message = ''): void {
+            self::check($actual === true, $message, 'Failed asserting that ' . self::show($actual) . ' is true.');
+        }
+        public static function assertFalse($actual, string $message = ''): void {
+            self::check($actual === false, $message, 'Failed asserting that ' . self::show($actual) . ' is false.');
+        }
+        public static function assertNull($actual, string $message = ''): void {
+            self::check($actual === null, $message, 'Failed asserting that ' . self::show($actual) . ' is null.');
+        }
+        public static function assertEquals($expected, $actual, string $message = ''): void {
+            self::check($expected == $actual, $message, 'Failed asserting that ' . self::show($actual) . ' matches expected ' . self::show($expected) . '.');
+        }
+        public static function assertNotEquals($expected, $actual, string $message = ''): void {
+            self::check($expected != $actual, $message, 'Failed asserting that ' . self::show($actual) . ' is not equal to ' . self::show($expected) . '.');
+        }
+        public static function assertSame($expected, $actual, string $message = ''): void {
+            self::check($expected === $actual, $message, 'Failed asserting that ' . self::show($actual) . ' is identical to ' . self::show($expected) . '.');
+        }
+        public static function assertNotSame($expected, $actual, string $message = ''): void {
+            self::check($expected !== $actual, $message, 'Failed asserting that ' . self::show($actual) . ' is not identical to ' . self::show($expected) . '.');
+        }
+        public static function assertCount(int $expected, $haystack, string $message = ''): void {
+            self::check(count($haystack) === $expected, $message, 'Failed asserting that actual size ' . count($haystack) . ' matches expected size ' . $expected . '.');
+        }
+        public static function assertContains($needle, $haystack, string $message = ''): void {
+            self::check(in_array($needle, $haystack, true), $message, 'Failed asserting that an array contains ' . self::show($needle) . '.');
+        }
+        public static function assertStringContainsString(string $needle, string $haystack, string $message = ''): void {
+            self::check(strpos($haystack, $needle) !== false, $message, 'Failed asserting that ' . self::show($haystack) . ' contains ' . self::show($needle) . '.');
+        }
+        public static function assertInstanceOf(string $expected, $actual, string $message = ''): void {
+            self::check($actual instanceof $expected, $message, 'Failed asserting that ' . self::show($actual) . ' is an instance of ' . $expected . '.');
+        }
+    }
+}
+
+namespace {
+    class_alias('PHPUnit\Framework\TestCase', 'TestCase');
+
+    require __DIR__ . '/main.php';
+    require __DIR__ . '/main_test.php';
+
+    $stqResults = getenv('STQ_RESULTS');
+    $stqIndex = 0;
+    foreach (get_declared_classes() as $stqClass) {
+        $stqRef = new ReflectionClass($stqClass);
+        if (!$stqRef->isSubclassOf('PHPUnit\Framework\TestCase') || $stqRef->isAbstract()) {
+            continue;
+        }
+        foreach ($stqRef->getMethods(ReflectionMethod::IS_PUBLIC) as $stqMethod) {
+            if (strpos($stqMethod->name, 'test') !== 0 || $stqMethod->isStatic()) {
+                continue;
+            }
+            $stqStatus = 'pass';
+            $stqFailure = '';
+            ob_start();
+            $stqStart = hrtime(true);
+            try {
+                (new $stqClass())->stqRun($stqMethod->name);
+            } catch (PHPUnit\Framework\SkippedTestError $e) {
+                $stqStatus = 'skip';
+                $stqFailure = $e->getMessage();
+            } catch (PHPUnit\Framework\AssertionFailedError $e) {
+                $stqStatus = 'fail';
+                $stqFailure = $e->getMessage();
+            } catch (Throwable $e) {
+                $stqStatus = 'fail';
+                $stqFailure = get_class($e) . ': ' . $e->getMessage() . ' in ' . basename($e->getFile()) . ':' . $e->getLine();
+            }
+            $stqDuration = (hrtime(true) - $stqStart) / 1e6;
+            $stqOutput = ob_get_clean();
+            file_put_contents($stqResults, json_encode([
+                'v' => 1,
+                'type' => 'test',
+                'test' => $stqIndex++,
+                'value' => ['name' => $stqClass . '::' . $stqMethod->name, 'status' => $stqStatus, 'failure' => $stqFailure, 'output' => $stqOutput],
+                'durationMs' => $stqDuration,
+            ], JSON_INVALID_UTF8_SUBSTITUTE) . "\n", FILE_APPEND);
+        }
+    }
+}
+`
+
+// phpTest is the value of a test record of the PHP runner
+type phpTest struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Failure string `json:"failure"`
+	Output  string `json:"output"`
+}
+
+// parsePHP reads the test records the PHP runner wrote. What the code
+// printed outside of the tests, such as a fatal error, is the suite's
+// output.
+func parsePHP(stdout string, results []byte) (Report, error) {
+	r := Report{Output: strings.TrimRight(stdout, "\n")}
+	records, err := protocol.Parse(results)
+	if err != nil {
+		return r, err
+	}
+	for _, rec := range records {
+		if rec.Type != protocol.TypeTest {
+			continue
+		}
+		var t phpTest
+		if err := json.Unmarshal(rec.Value, &t); err != nil {
+			return r, err
+		}
+		r.Tests = append(r.Tests, Case{
+			Name: t.Name, Status: t.Status, DurationMs: rec.DurationMs,
+			Failure: t.Failure, Output: strings.TrimRight(t.Output, "\n"),
+		})
+	}
+	return summarize(r), nil
+}
@@ -0,0 +1,101 @@
+package unittest
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// tapResult matches a test line: "ok 1 - name # SKIP reason"
+var tapResult = regexp.MustCompile(`^(not ok|ok)\b\s*\d*\s*(?:- )?(.*)$`)
+
+// tapDirective matches the SKIP or TODO directive ending a test line
+var tapDirective = regexp.MustCompile(`(?i)\s+#\s*(skip|todo)\b\s*(.*)$`)
+
+// parseTAP reads the TAP stream of `node --test --test-reporter=tap`.
+// Subtests are indented by four spaces under a "# Subtest: name" line, and
+// each result is followed by a YAML block with its duration and error.
+// Comments are what the tests printed; node reports them before the result
+// of the test that printed them.
+func parseTAP(stdout string) Report {
+	var r Report
+	var parents []string
+	var pending []string
+	lines := strings.Split(strings.ReplaceAll(stdout, "\r\n", "\n"), "\n")
+	done := false
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimLeft(line, " ")
+		depth := (len(line) - len(trimmed)) / 4
+		switch {
+		case done || trimmed == "" || strings.HasPrefix(trimmed, "TAP version"):
+		case strings.HasPrefix(trimmed, "# Subtest: "):
+			parents = append(parents[:min(depth, len(parents))], unescapeTAP(trimmed[len("# Subtest: "):]))
+		case strings.HasPrefix(trimmed, "#"):
+			pending = append(pending, strings.TrimPrefix(strings.TrimPrefix(trimmed, "#"), " "))
+		case strings.HasPrefix(trimmed, "1.."):
+			// The plan of the whole run comes last, followed by its totals
+			done = depth == 0
+		default:
+			m := tapResult.FindStringSubmatch(trimmed)
+			if m == nil {
+				continue
+			}
+			c := Case{Status: Pass, Output: strings.Join(pending, "\n")}
+			pending = nil
+			name := m[2]
+			if d := tapDirective.FindStringSubmatch(name); d != nil {
+				name = name[:len(name)-len(d[0])]
+				c.Status, c.Failure = Skip, d[2]
+			} else if m[1] == "not ok" {
+				c.Status = Fail
+			}
+			c.Name = strings.Join(append(parents[:min(depth, len(parents)):min(depth, len(parents))], unescapeTAP(name)), "/")
+			i = readTAPBlock(lines, i+1, depth*4+2, &c)
+			r.Tests = append(r.Tests, c)
+		}
+	}
+	// Output printed after the last test
+	r.Output = strings.Join(pending, "\n")
+	return summarize(r)
+}
+
+// readTAPBlock reads the YAML block following a test line, if any, and
+// returns the index of the block's last line
+func readTAPBlock(lines []string, i, indent int, c *Case) int {
+	prefix := strings.Repeat(" ", indent)
+	if i >= len(lines) || lines[i] != prefix+"---" {
+		return i - 1
+	}
+	for i++; i < len(lines) && lines[i] != prefix+"..."; i++ {
+		key, value, ok := strings.Cut(strings.TrimPrefix(lines[i], prefix), ": ")
+		if !ok || strings.HasPrefix(key, " ") {
+			continue
+		}
+		if value == "|-" || value == "|" {
+			// A block scalar spans the lines indented further
+			var block []string
+			for i+1 < len(lines) && (strings.HasPrefix(lines[i+1], prefix+"  ") || strings.TrimSpace(lines[i+1]) == "") && lines[i+1] != prefix+"..." {
+				i++
+				block = append(block, strings.TrimPrefix(lines[i], prefix+"  "))
+			}
+			value = strings.TrimRight(strings.Join(block, "\n"), "\n ")
+		} else if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+		}
+		switch key {
+		case "duration_ms":
+			c.DurationMs, _ = strconv.ParseFloat(value, 64)
+		case "error":
+			if c.Status == Fail {
+				c.Failure = value
+			}
+		}
+	}
+	return i
+}
+
+// unescapeTAP undoes the escaping of # and \ in test names
+func unescapeTAP(name string) string {
+	return strings.NewReplacer(`\#`, "#", `\\`, `\`).Replace(strings.TrimSpace(name))
+}
@@ -0,0 +1,76 @@
+package unittest
+
+import (
+	"reflect"
+	"testing"
+)
+
+// tapOutput is what node --test --test-reporter=tap prints, trimmed
+const tapOutput = `TAP version 13
+# hello
+# Subtest: adds
+ok 1 - adds
+  ---
+  duration_ms: 5.5
+  ...
+# Subtest: fails
+not ok 2 - fails
+  ---
+  duration_ms: 2
+  location: 'main.test.js:5:1'
+  failureType: 'testCodeFailure'
+  error: |-
+    Expected values to be strictly equal:
+    
+    2 !== 3
+    
+  code: 'ERR_ASSERTION'
+  stack: |-
+    TestContext.<anonymous> (main.test.js:5:30)
+  ...
+# Subtest: skipped
+ok 3 - skipped # SKIP later
+  ---
+  duration_ms: 0.25
+  ...
+# Subtest: group
+    # Subtest: inner \# ok
+    ok 1 - inner \# ok
+      ---
+      duration_ms: 0.5
+      ...
+    # Subtest: inner bad
+    not ok 2 - inner bad
+      ---
+      duration_ms: 0.125
+      error: 'it''s broken'
+      ...
+    1..2
+not ok 4 - group
+  ---
+  duration_ms: 1
+  error: '1 subtest failed'
+  ...
+# bye
+1..4
+# tests 6
+# pass 2
+`
+
+func TestParseTAP(t *testing.T) {
+	r := parseTAP(tapOutput)
+	want := []Case{
+		{Name: "adds", Status: Pass, DurationMs: 5.5, Output: "hello"},
+		{Name: "fails", Status: Fail, DurationMs: 2, Failure: "Expected values to be strictly equal:\n\n2 !== 3"},
+		{Name: "skipped", Status: Skip, DurationMs: 0.25, Failure: "later"},
+		{Name: "group/inner # ok", Status: Pass, DurationMs: 0.5},
+		{Name: "group/inner bad", Status: Fail, DurationMs: 0.125, Failure: "it's broken"},
+		{Name: "group", Status: Fail, DurationMs: 1, Failure: "1 subtest failed"},
+	}
+	if !reflect.DeepEqual(r.Tests, want) {
+		t.Errorf("Expected %+v, but got %+v", want, r.Tests)
+	}
+	if r.Passed != 2 || r.Failed != 3 || r.Skipped != 1 || r.Total != 6 || r.Output != "bye" {
+		t.Errorf("Expected the counts and the trailing output, but got %+v", r)
+	}
+}
@@ -0,0 +1,121 @@
+// Package unittest runs test suites users write for their own code: `go
+// test -json` for Go, `node --test` with the TAP reporter for Node and a
+// bundled PHPUnit-style runner for PHP. Each tool's output is parsed into
+// the same per-test report.
+package unittest
+
+import (
+	"path/filepath"
+
+	"github.com/afman42/go-web-code-interactive/internal/coverage"
+	"github.com/afman42/go-web-code-interactive/internal/judge"
+)
+
+// Test statuses
+const (
+	Pass = "pass"
+	Fail = "fail"
+	Skip = "skip"
+)
+
+// Case is the outcome of one test. Subtests are cases of their own, named
+// after their parents as in Go: "parent/child".
+type Case struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	DurationMs float64 `json:"durationMs"`
+	// Failure explains why the test failed or was skipped
+	Failure string `json:"failure,omitempty"`
+	// Output is what the test printed or logged
+	Output string `json:"output,omitempty"`
+}
+
+// Report is the outcome of a test suite
+type Report struct {
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+	Total   int `json:"total"`
+	// BuildError holds the compiler errors of a Go suite that did not build
+	BuildError string `json:"buildError,omitempty"`
+	// Output is what the suite printed outside of any test
+	Output string `json:"output,omitempty"`
+	Tests  []Case `json:"tests"`
+}
+
+// File names of the user's code and tests per language
+var files = map[string][2]string{
+	"go":   {"main.go", "main_test.go"},
+	"node": {"main.js", "main.test.js"},
+	"php":  {"main.php", "main_test.php"},
+}
+
+// Build lays out the user's code and tests in files of their own, so errors
+// point at the user's line numbers. Node tests load the code with
+// require('./main.js'); PHP and Go tests see it directly.
+func Build(language, code, tests string) judge.Program {
+	names := files[language]
+	prog := judge.Program{Files: map[string]string{names[0]: code, names[1]: tests}, Entry: names[1]}
+	if language == "php" {
+		prog.Files[phpRunnerFile] = phpRunner
+		prog.Entry = phpRunnerFile
+	}
+	return prog
+}
+
+// CodeFile returns the name of the file holding the user's code in a suite
+func CodeFile(language string) string {
+	return files[language][0]
+}
+
+// Command returns the command running a suite built by Build and written to
+// dir. With cover, Go writes its coverage profile to coverage.ProfileFile in
+// dir.
+func Command(language, dir string, cover bool) []string {
+	names := files[language]
+	switch language {
+	case "go":
+		command := []string{"go", "test", "-json"}
+		if cover {
+			command = append(command, "-covermode=count", "-coverprofile="+filepath.Join(dir, coverage.ProfileFile))
+		}
+		return append(command, filepath.Join(dir, names[0]), filepath.Join(dir, names[1]))
+	case "node":
+		return []string{"node", "--test", "--test-reporter=tap", filepath.Join(dir, names[1])}
+	default:
+		return []string{"php", filepath.Join(dir, phpRunnerFile)}
+	}
+}
+
+// Parse reads the report of a suite from what its command printed and, for
+// PHP, the records of the results file
+func Parse(language, stdout string, results []byte) (Report, error) {
+	switch language {
+	case "go":
+		return parseGoTest(stdout), nil
+	case "node":
+		return parseTAP(stdout), nil
+	default:
+		return parsePHP(stdout, results)
+	}
+}
+
+// summarize counts the cases of a report by status
+func summarize(r Report) Report {
+	r.Passed, r.Failed, r.Skipped = 0, 0, 0
+	if r.Tests == nil {
+		r.Tests = []Case{}
+	}
+	for _, c := range r.Tests {
+		switch c.Status {
+		case Pass:
+			r.Passed++
+		case Fail:
+			r.Failed++
+		case Skip:
+			r.Skipped++
+		}
+	}
+	r.Total = len(r.Tests)
+	return r
+}
@@ -0,0 +1,67 @@
+package unittest
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBuild(t *testing.T) {
+	testCases := []struct {
+		lang    string
+		files   []string
+		command []string
+	}{
+		{"go", []string{"main.go", "main_test.go"}, []string{"go", "test", "-json", filepath.Join("dir", "main.go"), filepath.Join("dir", "main_test.go")}},
+		{"node", []string{"main.js", "main.test.js"}, []string{"node", "--test", "--test-reporter=tap", filepath.Join("dir", "main.test.js")}},
+		{"php", []string{"main.php", "main_test.php", "stq_runner.php"}, []string{"php", filepath.Join("dir", "stq_runner.php")}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.lang, func(t *testing.T) {
+			prog := Build(tc.lang, "code", "tests")
+			if !reflect.DeepEqual(prog.Names(), tc.files) {
+				t.Errorf("Expected files %v, but got %v", tc.files, prog.Names())
+			}
+			if prog.Files[tc.files[0]] != "code" || prog.Files[tc.files[1]] != "tests" {
+				t.Errorf("Expected the code and tests in files of their own, but got %v", prog.Files)
+			}
+			if got := Command(tc.lang, "dir", false); !reflect.DeepEqual(got, tc.command) {
+				t.Errorf("Expected command %v, but got %v", tc.command, got)
+			}
+			if CodeFile(tc.lang) != tc.files[0] {
+				t.Errorf("Expected the code in %s, but got %s", tc.files[0], CodeFile(tc.lang))
+			}
+		})
+	}
+}
+
+func TestParsePHP(t *testing.T) {
+	results := `{"v":1,"type":"test","test":0,"value":{"name":"SumTest::testAdds","status":"pass","failure":"","output":"hi\n"},"durationMs":0.5}
+{"v":1,"type":"test","test":1,"value":{"name":"SumTest::testFails","status":"fail","failure":"Failed asserting that 2 matches expected 3.","output":""},"durationMs":1}
+`
+	r, err := Parse("php", "PHP Warning:  oops\n", []byte(results))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Case{
+		{Name: "SumTest::testAdds", Status: Pass, DurationMs: 0.5, Output: "hi"},
+		{Name: "SumTest::testFails", Status: Fail, DurationMs: 1, Failure: "Failed asserting that 2 matches expected 3."},
+	}
+	if !reflect.DeepEqual(r.Tests, want) || r.Passed != 1 || r.Failed != 1 || r.Output != "PHP Warning:  oops" {
+		t.Errorf("Expected the runner's records, but got %+v", r)
+	}
+	if _, err := Parse("php", "", []byte(`{"v":9}`)); err == nil {
+		t.Error("Expected error for a record of another version, but got none")
+	}
+}
+
+func TestCommandCover(t *testing.T) {
+	want := []string{"go", "test", "-json", "-covermode=count", "-coverprofile=" + filepath.Join("dir", "stq_cover.out"), filepath.Join("dir", "main.go"), filepath.Join("dir", "main_test.go")}
+	if got := Command("go", "dir", true); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected command %v, but got %v", want, got)
+	}
+	// Node writes its coverage wherever NODE_V8_COVERAGE says
+	if got := Command("node", "dir", true); !reflect.DeepEqual(got, Command("node", "dir", false)) {
+		t.Errorf("Expected the same Node command, but got %v", got)
+	}
+}
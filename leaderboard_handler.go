@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/leaderboard"
+	"github.com/afman42/go-web-code-interactive/internal/ratelimiter"
+)
+
+// playerKey keys the player names, so they cannot be reversed by hashing
+// every IP address. Set from PLAYER_SECRET in main to keep the names across
+// restarts, otherwise drawn at random.
+var playerKey = newPlayerKey()
+
+func newPlayerKey() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
+
+// player is the public name of a client on the leaderboards, which never
+// show the IP address itself
+func player(owner string) string {
+	mac := hmac.New(sha256.New, playerKey)
+	mac.Write([]byte(owner))
+	return "player-" + hex.EncodeToString(mac.Sum(nil)[:4])
+}
+
+// StandingView is a row of a problem's leaderboard
+type StandingView struct {
+	leaderboard.Standing
+	Player string `json:"player"`
+	// You marks the requesting client's own row
+	You bool `json:"you,omitempty"`
+}
+
+// RankingView is a row of the global leaderboard
+type RankingView struct {
+	leaderboard.Ranking
+	Player string `json:"player"`
+	You    bool   `json:"you,omitempty"`
+}
+
+// leaderboardFilter reads lang, since and until (RFC 3339) and limit from
+// the query string
+func leaderboardFilter(r *http.Request) (leaderboard.Filter, error) {
+	q := r.URL.Query()
+	f := leaderboard.Filter{Language: q.Get("lang")}
+	var err error
+	for name, t := range map[string]*time.Time{"since": &f.Since, "until": &f.Until} {
+		if v := q.Get(name); v != "" {
+			if *t, err = time.Parse(time.RFC3339, v); err != nil {
+				return f, fmt.Errorf("invalid %s", name)
+			}
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil || f.Limit < 0 {
+			return f, fmt.Errorf("invalid limit")
+		}
+	}
+	return f, nil
+}
+
+// problemLeaderboard handles GET /api/problems/{id}/leaderboard, each
+// client's best submission ranked by pass rate, runtime and submission time
+func problemLeaderboard(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, "GET")
+		return
+	}
+	id := r.PathValue("id")
+	if _, ok := problemBank.Get(id); !ok {
+		writeError(w, http.StatusNotFound, "problem not found")
+		return
+	}
+	f, err := leaderboardFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	me := ratelimiter.GetVisitorIP(r)
+	list := []StandingView{}
+	for _, s := range leaderboards.Problem(id, f) {
+		list = append(list, StandingView{Standing: s, Player: player(s.Owner), You: s.Owner == me})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// globalLeaderboard handles GET /api/leaderboard, clients ranked by problems
+// solved, total score, total runtime and when they got there
+func globalLeaderboard(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, "GET")
+		return
+	}
+	f, err := leaderboardFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	me := ratelimiter.GetVisitorIP(r)
+	list := []RankingView{}
+	for _, rk := range leaderboards.Global(f) {
+		list = append(list, RankingView{Ranking: rk, Player: player(rk.Owner), You: rk.Owner == me})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
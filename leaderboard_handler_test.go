@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/afman42/go-web-code-interactive/internal/judge"
+	"github.com/afman42/go-web-code-interactive/internal/leaderboard"
+	"github.com/afman42/go-web-code-interactive/internal/submission"
+)
+
+func TestLeaderboardHandlers(t *testing.T) {
+	store, err := submission.OpenFile(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	oldStore, oldBoard := submissionStore, leaderboards
+	submissionStore, leaderboards = store, leaderboard.New()
+	defer func() { submissionStore, leaderboards = oldStore, oldBoard }()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/problems/{id}/leaderboard", problemLeaderboard)
+	mux.HandleFunc("/api/leaderboard", globalLeaderboard)
+	get := func(url, ip string, v any) int {
+		req := httptest.NewRequest("GET", url, nil)
+		req.Header.Set("X-Real-IP", ip)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		json.NewDecoder(rr.Body).Decode(v)
+		return rr.Code
+	}
+	submit := func(ip, lang string, passed int, durationMs int64) {
+		req := httptest.NewRequest("POST", "/", nil)
+		req.Header.Set("X-Real-IP", ip)
+		recordSubmission(req, Data{Language: lang, Tipe: "stq", ProblemID: DefaultProblemID, Mode: "submit",
+			// The request's own duration, builds included, does not count
+			Meta:    &Meta{DurationMs: 1000 - durationMs},
+			Verdict: &judge.Result{Passed: passed, Total: 4, Score: passed * 25, DurationMs: durationMs}})
+	}
+	submit("10.0.0.1", "go", 2, 10)
+	submit("10.0.0.2", "node", 4, 30)
+	submit("10.0.0.1", "node", 4, 20)
+
+	t.Run("Problem", func(t *testing.T) {
+		var list []StandingView
+		if code := get("/api/problems/"+DefaultProblemID+"/leaderboard", "10.0.0.2", &list); code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", code, http.StatusOK)
+		}
+		if len(list) != 2 || list[0].Player != player("10.0.0.1") || list[0].DurationMs != 20 || list[1].Player != player("10.0.0.2") {
+			t.Fatalf("Expected 10.0.0.1's faster full pass first, but got %+v", list)
+		}
+		if list[0].You || !list[1].You {
+			t.Errorf("Expected only the caller's row to be marked, but got %+v", list)
+		}
+		list = nil
+		get("/api/problems/"+DefaultProblemID+"/leaderboard?lang=go", "10.0.0.2", &list)
+		if len(list) != 1 || list[0].PassRate != 0.5 {
+			t.Errorf("Expected only the go submission, but got %+v", list)
+		}
+		if code := get("/api/problems/missing/leaderboard", "10.0.0.2", &list); code != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", code, http.StatusNotFound)
+		}
+		if code := get("/api/problems/"+DefaultProblemID+"/leaderboard?since=now", "10.0.0.2", &list); code != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Global", func(t *testing.T) {
+		var list []RankingView
+		if code := get("/api/leaderboard?limit=1", "10.0.0.1", &list); code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", code, http.StatusOK)
+		}
+		if len(list) != 1 || !list[0].You || list[0].Solved != 1 || list[0].Rank != 1 {
+			t.Errorf("Expected the caller alone on the first page, but got %+v", list)
+		}
+	})
+
+	t.Run("Startup", func(t *testing.T) {
+		leaderboards = leaderboard.New()
+		if err := replayHistory(); err != nil {
+			t.Fatalf("replayHistory failed: %v", err)
+		}
+		var list []StandingView
+		get("/api/problems/"+DefaultProblemID+"/leaderboard", "10.0.0.2", &list)
+		if len(list) != 2 {
+			t.Errorf("Expected the stored submissions to be ranked again, but got %+v", list)
+		}
+	})
+}
@@ -12,17 +12,38 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/afman42/go-web-code-interactive/internal/catalog"
+	"github.com/afman42/go-web-code-interactive/internal/contest"
+	"github.com/afman42/go-web-code-interactive/internal/coverage"
+	"github.com/afman42/go-web-code-interactive/internal/gocache"
+	"github.com/afman42/go-web-code-interactive/internal/hint"
+	"github.com/afman42/go-web-code-interactive/internal/interp"
+	"github.com/afman42/go-web-code-interactive/internal/judge"
+	"github.com/afman42/go-web-code-interactive/internal/leaderboard"
+	"github.com/afman42/go-web-code-interactive/internal/notebook"
+	"github.com/afman42/go-web-code-interactive/internal/pool"
+	"github.com/afman42/go-web-code-interactive/internal/problem"
 	"github.com/afman42/go-web-code-interactive/internal/ratelimiter"
+	"github.com/afman42/go-web-code-interactive/internal/resultcache"
 	"github.com/afman42/go-web-code-interactive/internal/security"
+	"github.com/afman42/go-web-code-interactive/internal/session"
+	"github.com/afman42/go-web-code-interactive/internal/statement"
+	"github.com/afman42/go-web-code-interactive/internal/submission"
+	"github.com/afman42/go-web-code-interactive/internal/unittest"
 	"github.com/afman42/go-web-code-interactive/utils"
 )
 
 //go:embed web/dist
 var WebContent embed.FS
 
+//go:embed problems
+var ProblemContent embed.FS
+
 // Constants
 const (
 	ModeDev     = "dev"
@@ -36,6 +57,18 @@ const (
 	// Cleanup intervals for rate limiter
 	CleanupInterval = 10 * time.Minute
 	MaxInactivityDuration = 30 * time.Minute
+
+	// Interactive sessions
+	SessionIdleTimeout = 15 * time.Minute
+	MaxSessionsPerIP   = 3
+
+	// Warm worker pools
+	ExecTimeout     = 10 * time.Second
+	DefaultPoolSize = 2
+
+	// Result cache for identical submissions
+	ResultCacheSize = 500
+	ResultCacheTTL  = 10 * time.Minute
 	
 	// Default environment files
 	EnvLocal = ".env.local"
@@ -44,6 +77,15 @@ const (
 	
 	// File paths
 	TempDir = "./tmp"
+	DefaultNotebookDir = "./data/notebooks"
+	DefaultGoCacheDir = "./data/gocache"
+	DefaultProblemStoreDir = "./data/problems"
+	DefaultSubmissionDir = "./data/submissions"
+	DefaultContestDir = "./data/contests"
+	DefaultHintDir = "./data/hints"
+
+	// STQ problem served when a request does not name one
+	DefaultProblemID = "int-into-string"
 	
 	// HTTP status codes
 	StatusTooManyRequests = 429
@@ -51,10 +93,41 @@ const (
 
 var (
 	IPCors string
+	// Bearer token of the admin API, which is disabled while it is empty
+	adminToken string
 	// Initialize security validator
 	securityValidator = security.NewSecurityValidator()
 	// Initialize rate limiter with constants
 	rateLimiter = ratelimiter.NewRateLimiter(DefaultRateLimitWindow, DefaultRateLimitMaxRequests)
+	// Initialize session manager for stateful REPL sessions
+	sessionManager = session.NewManager(SessionIdleTimeout, MaxSessionsPerIP)
+	// Initialize result cache for identical deterministic submissions
+	resultCache = resultcache.New(ResultCacheSize, ResultCacheTTL)
+	// Challenge bank, loaded from the embedded problems or PROBLEMS_DIR in main
+	problemBank = problem.MustLoad(ProblemContent, "problems")
+	// Problems authored over the admin API, created in main
+	problemStore *problem.Store
+	// Submission history, opened in main from SUBMISSION_STORE
+	submissionStore submission.Store
+	// Full-text index of the problem bank, synced on each search
+	problemIndex = catalog.New()
+	// Rendered problem statements, cached per problem version
+	statements = statement.NewCache()
+	// Leaderboards of graded submissions, filled from the history in main
+	leaderboards = leaderboard.New()
+	// Contests, created in main, with the graded attempts of each and
+	// their submission quotas
+	contestStore    *contest.Store
+	contestAttempts = contest.NewAttempts()
+	contestQuotas   = ratelimiter.NewQuotas()
+	// Hints revealed to each client, opened in main
+	hintStore *hint.Store
+	// Notebook runner, created in main once the storage directory is known
+	notebookRunner *notebook.Runner
+	// Warm worker pools per interpreted language, created in main
+	workerPools = map[string]*pool.Pool{}
+	// Shared prewarmed Go build cache, created in main
+	goBuildCache *gocache.Cache
 )
 
 func main() {
@@ -76,8 +149,18 @@ func main() {
 	if err != nil {
 		log.Fatal("Error loading " + env + " file")
 	}
+	if handled, err := runCommand(flag.Args()); handled {
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
 	IPCors = os.Getenv("CORS_DOMAIN")
+	adminToken = os.Getenv("ADMIN_TOKEN")
+	if secret := os.Getenv("PLAYER_SECRET"); secret != "" {
+		playerKey = []byte(secret)
+	}
 	Port := os.Getenv("APP_PORT")
 	if _, err := os.Stat(TempDir); err != nil {
 		if os.IsNotExist(err) {
@@ -96,6 +179,47 @@ func main() {
 			panic(err)
 		}
 	}
+	notebookStore, err := notebook.NewStore(getEnv("NOTEBOOK_DIR", DefaultNotebookDir))
+	if err != nil {
+		log.Fatal(err)
+	}
+	notebookRunner = notebook.NewRunner(notebookStore)
+	if err := loadProblems(); err != nil {
+		log.Fatal(err)
+	}
+	submissionStore, err = openSubmissionStore()
+	if err != nil {
+		log.Fatal("error opening submission store: ", err)
+	}
+	contestStore, err = contest.NewStore(getEnv("CONTEST_DIR", DefaultContestDir))
+	if err != nil {
+		log.Fatal(err)
+	}
+	hintStore, err = hint.Open(getEnv("HINT_DIR", DefaultHintDir))
+	if err != nil {
+		log.Fatal("error opening hint store: ", err)
+	}
+	if err := replayHistory(); err != nil {
+		log.Fatal("error loading submission history: ", err)
+	}
+	goBuildCache = gocache.New(getEnv("GO_CACHE_DIR", DefaultGoCacheDir))
+	interp.GoBuildCache = goBuildCache
+	go func() {
+		start := time.Now()
+		if err := goBuildCache.Warm(gocache.StdPackages); err != nil {
+			log.Printf("error warming go build cache: %v\n", err)
+			return
+		}
+		log.Printf("go build cache warm in %v\n", time.Since(start))
+	}()
+	poolSize, err := strconv.Atoi(getEnv("POOL_SIZE", strconv.Itoa(DefaultPoolSize)))
+	if err != nil {
+		log.Fatal("Invalid POOL_SIZE: ", err)
+	}
+	if poolSize > 0 {
+		workerPools["node"] = pool.New("node", poolSize)
+		workerPools["php"] = pool.New("php", poolSize)
+	}
 	dist, err := fs.Sub(WebContent, "web/dist")
 	if err != nil {
 		log.Fatal(err)
@@ -103,19 +227,38 @@ func main() {
 	}
 	mux := http.NewServeMux()
 
-	// Wrap the index handler with logging
-	originalHandler := utils.WrapHandlerWithLogging(http.HandlerFunc(index))
-
-	// Apply rate limiting only to POST requests (code execution)
-	mux.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost {
-			// Apply rate limiting to POST requests
-			rateLimiter.RateLimitHandler(originalHandler).ServeHTTP(w, r)
-		} else {
-			// No rate limiting for GET requests
-			originalHandler.ServeHTTP(w, r)
-		}
-	}))
+	// Every route is logged, POST requests (code execution) are rate limited
+	mux.Handle("/", withMiddleware(index))
+	mux.Handle("/api/sessions", withMiddleware(sessions))
+	mux.Handle("/api/sessions/{id}", withMiddleware(sessionByID))
+	mux.Handle("/api/sessions/{id}/eval", withMiddleware(sessionEval))
+	mux.Handle("/api/notebooks", withMiddleware(notebooks))
+	mux.Handle("/api/notebooks/{id}", withMiddleware(notebookByID))
+	mux.Handle("/api/notebooks/{id}/run", withMiddleware(notebookAction))
+	mux.Handle("/api/notebooks/{id}/restart", withMiddleware(notebookAction))
+	mux.Handle("/api/notebooks/{id}/cells/{cell}/run", withMiddleware(notebookAction))
+	mux.Handle("/api/metrics/pool", withMiddleware(poolMetrics))
+	mux.Handle("/api/problems", withMiddleware(problems))
+	mux.Handle("/api/problems/{id}", withMiddleware(problemByID))
+	mux.Handle("/api/admin/problems", withMiddleware(adminProblems))
+	mux.Handle("/api/admin/problems/{id}", withMiddleware(adminProblemByID))
+	mux.Handle("/api/admin/problems/{id}/versions", withMiddleware(adminProblemVersions))
+	mux.Handle("/api/admin/problems/import", withMiddleware(adminProblemImport))
+	mux.Handle("/api/admin/problems/{id}/package", withMiddleware(adminProblemPackage))
+	mux.Handle("/api/admin/problems/{id}/stress", withMiddleware(adminProblemStress))
+	mux.Handle("/api/admin/problems/{id}/similarity", withMiddleware(adminProblemSimilarity))
+	mux.Handle("/api/submissions", withMiddleware(submissions))
+	mux.Handle("/api/submissions/{id}", withMiddleware(submissionByID))
+	mux.Handle("/api/admin/submissions", withMiddleware(adminSubmissions))
+	mux.Handle("/api/problems/{id}/leaderboard", withMiddleware(problemLeaderboard))
+	mux.Handle("/api/problems/{id}/hints", withMiddleware(problemHints))
+	mux.Handle("/api/problems/{id}/editorial", withMiddleware(problemEditorial))
+	mux.Handle("/api/leaderboard", withMiddleware(globalLeaderboard))
+	mux.Handle("/api/contests", withMiddleware(contests))
+	mux.Handle("/api/contests/{id}", withMiddleware(contestByID))
+	mux.Handle("/api/contests/{id}/scoreboard", withMiddleware(contestScoreboard))
+	mux.Handle("/api/admin/contests", withMiddleware(adminContests))
+	mux.Handle("/api/admin/contests/{id}", withMiddleware(adminContestByID))
 
 	if Mode == ModePreview || Mode == ModeProd {
 		mux.Handle("/assets/", http.FileServer(http.FS(dist)))
@@ -146,6 +289,34 @@ type Data struct {
 	StatusCode int    `json:"statusCode"`
 	Language   string `json:"lang"`
 	Tipe       string `json:"type"`
+	ProblemID  string `json:"problemId,omitempty"`
+	// ContestID submits an STQ solution to a running contest
+	ContestID string `json:"contestId,omitempty"`
+	// ProblemVersion is the version of the problem the verdict was graded on
+	ProblemVersion int `json:"problemVersion,omitempty"`
+	// Mode is "run" for the sample tests or "submit" for every STQ test
+	Mode       string `json:"mode,omitempty"`
+	Stdin      string `json:"stdin,omitempty"`
+	NoCache    bool   `json:"noCache,omitempty"`
+	Meta       *Meta  `json:"meta,omitempty"`
+	// Verdict holds the per-test results of an STQ submission
+	Verdict *judge.Result `json:"verdict,omitempty"`
+	// SubmissionID names the stored submission, see /api/submissions/{id}
+	SubmissionID string `json:"submissionId,omitempty"`
+	// Tests is the user's test code run against Txt by the test type
+	Tests string `json:"tests,omitempty"`
+	// TestReport holds the per-test results of the user's tests
+	TestReport *unittest.Report `json:"testReport,omitempty"`
+	// Coverage asks for the lines of Txt that STQ tests or the user's tests
+	// ran, returned in CoverageReport
+	Coverage       bool             `json:"coverage,omitempty"`
+	CoverageReport *coverage.Report `json:"coverageReport,omitempty"`
+}
+
+// Meta describes how a submission was executed
+type Meta struct {
+	Warm       bool  `json:"warm"`
+	DurationMs int64 `json:"durationMs"`
 }
 
 // https://www.alexedwards.net/blog/which-go-router-should-i-use
@@ -174,7 +345,6 @@ func index(w http.ResponseWriter, r *http.Request) {
 	case http.MethodPost:
 		var (
 			data Data
-			args = "-"
 		)
 		err := json.NewDecoder(r.Body).Decode(&data)
 		if err != nil {
@@ -207,7 +377,7 @@ func index(w http.ResponseWriter, r *http.Request) {
 			})
 			return
 		}
-		if !utils.CheckIsNotData([]string{"repl", "stq"}, data.Tipe) {
+		if !utils.CheckIsNotData([]string{"repl", "stq", "test"}, data.Tipe) {
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(struct {
 				StatusCode int    `json:"statusCode"`
@@ -219,8 +389,28 @@ func index(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if data.Tipe == "test" {
+			if strings.TrimSpace(data.Tests) == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(struct {
+					StatusCode int    `json:"statusCode"`
+					Message    string `json:"message"`
+				}{
+					StatusCode: http.StatusBadRequest,
+					Message:    "Something Went Wrong, Check Tests Field Empty",
+				})
+				return
+			}
+			// The tests are the user's own, so they never count toward a
+			// problem or contest
+			data.ProblemID, data.ContestID, data.Mode = "", "", ""
+		}
+
 		// Validate the code for security issues before execution
 		validationError := securityValidator.ValidateCode(data.Txt, data.Language)
+		if validationError == nil && data.Tipe == "test" {
+			validationError = securityValidator.ValidateCode(data.Tests, data.Language)
+		}
 		if validationError != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(struct {
@@ -233,76 +423,202 @@ func index(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		filename := "index-" + utils.StringWithCharset(5) + ".js"
-		if data.Language == "php" {
-			filename = "index-" + utils.StringWithCharset(5) + ".php"
+		var prob problem.Problem
+		if data.Tipe == "stq" {
+			if data.ProblemID == "" {
+				data.ProblemID = DefaultProblemID
+			}
+			if data.Mode == "" {
+				data.Mode = judge.ModeRun
+			}
+			if !utils.CheckIsNotData([]string{judge.ModeRun, judge.ModeSubmit}, data.Mode) {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(struct {
+					StatusCode int    `json:"statusCode"`
+					Message    string `json:"message"`
+				}{
+					StatusCode: http.StatusBadRequest,
+					Message:    "Something Went Wrong, Check Mode is exist",
+				})
+				return
+			}
+			var ok bool
+			prob, ok = problemBank.Get(data.ProblemID)
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(struct {
+					StatusCode int    `json:"statusCode"`
+					Message    string `json:"message"`
+				}{
+					StatusCode: http.StatusNotFound,
+					Message:    "Something Went Wrong, Check Problem is exist",
+				})
+				return
+			}
+			data.ProblemVersion = prob.Version
+			if !slices.Contains(prob.Languages(), data.Language) {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(struct {
+					StatusCode int    `json:"statusCode"`
+					Message    string `json:"message"`
+				}{
+					StatusCode: http.StatusBadRequest,
+					Message:    "Something Went Wrong, Problem does not support this language",
+				})
+				return
+			}
 		}
-		if data.Language == "go" {
-			args = "run"
-			filename = "main-" + utils.StringWithCharset(5) + ".go"
+		if data.Coverage {
+			if message := checkCoverage(data); message != "" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(struct {
+					StatusCode int    `json:"statusCode"`
+					Message    string `json:"message"`
+				}{
+					StatusCode: http.StatusBadRequest,
+					Message:    "Something Went Wrong, " + message,
+				})
+				return
+			}
 		}
+		if data.ContestID != "" {
+			if status, message := checkContestSubmission(r, data); status != http.StatusOK {
+				w.WriteHeader(status)
+				json.NewEncoder(w).Encode(struct {
+					StatusCode int    `json:"statusCode"`
+					Message    string `json:"message"`
+				}{
+					StatusCode: status,
+					Message:    "Something Went Wrong, " + message,
+				})
+				return
+			}
+		}
+		// Deterministic runs are served from the result cache unless the
+		// client opts out for programs with non-deterministic output
+		cacheKey := ""
+		if data.NoCache {
+			w.Header().Set("X-Cache", "BYPASS")
+		} else {
+			cacheKey = resultcache.Key(struct {
+				Txt       string
+				Language  string
+				Tipe      string
+				ProblemID string
+				Version   int
+				Mode      string
+				Stdin     string
+				Tests     string
+				Coverage  bool
+			}{data.Txt, data.Language, data.Tipe, data.ProblemID, data.ProblemVersion, data.Mode, data.Stdin, data.Tests, data.Coverage})
+			if cached, ok := resultCache.Get(cacheKey); ok {
+				w.Header().Set("X-Cache", "HIT")
+				hit := cached.(Data)
+				// The result is shared by everyone sending the same code, the
+				// contest it counts toward is the requester's own
+				hit.ContestID = data.ContestID
+				hit.SubmissionID = recordSubmission(r, hit)
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(hit)
+				return
+			}
+			w.Header().Set("X-Cache", "MISS")
+		}
+
+		// STQ builds the user's code once, with the harness in its own files
+		// next to it, runs the selected test cases of the problem against it
+		// and answers with a verdict
 		if data.Tipe == "stq" {
-			if data.Language == "go" {
-				data.Txt = data.Txt + utils.TxtGo
+			start := time.Now()
+			warm := false
+			var cov *coverage.Profile
+			if data.Coverage {
+				cov = coverage.New(data.Txt)
+			}
+			verdict, err := judge.Run(prob, data.Language, data.Txt, data.Mode, coveragePreparer(&warm, cov))
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(struct {
+					StatusCode int    `json:"statusCode"`
+					Message    string `json:"message"`
+				}{
+					StatusCode: http.StatusBadRequest,
+					Message:    "Something Went Wrong, " + err.Error(),
+				})
+				return
 			}
-			if data.Language == "php" {
-				data.Txt = data.Txt + utils.TxtPHP
+			data.Verdict = &verdict
+			if cov != nil {
+				report := cov.Report()
+				data.CoverageReport = &report
 			}
-			if data.Language == "node" {
-				data.Txt = data.Txt + utils.TxtJS
+			data.Meta = &Meta{Warm: warm, DurationMs: time.Since(start).Milliseconds()}
+			data.StatusCode = http.StatusOK
+			if cacheKey != "" && verdict.Verdict != judge.TimeLimitExceeded {
+				resultCache.Add(cacheKey, data)
 			}
+			data.SubmissionID = recordSubmission(r, data)
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(data)
+			return
+		}
 
-			// Re-validate the combined code after adding template
-			validationError = securityValidator.ValidateCode(data.Txt, data.Language)
-			if validationError != nil {
+		// Test runs the user's own tests against their code and answers with
+		// the result of every test
+		if data.Tipe == "test" {
+			var cov *coverage.Profile
+			if data.Coverage {
+				cov = coverage.New(data.Txt)
+			}
+			report, res, err := runTests(data.Language, data.Txt, data.Tests, ExecTimeout, cov)
+			if err != nil {
 				w.WriteHeader(http.StatusBadRequest)
 				json.NewEncoder(w).Encode(struct {
 					StatusCode int    `json:"statusCode"`
 					Message    string `json:"message"`
 				}{
 					StatusCode: http.StatusBadRequest,
-					Message:    "Security validation failed: " + validationError.Error(),
+					Message:    "Something Went Wrong, " + err.Error(),
 				})
 				return
 			}
-		}
-		err = os.WriteFile(filename, []byte(data.Txt), 0o755)
-		if err != nil {
-			log.Printf("unable to write file: %v", err.Error())
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(struct {
-				StatusCode int    `json:"statusCode"`
-				Message    string `json:"message"`
-			}{
-				StatusCode: http.StatusBadRequest,
-				Message:    "Something Went Wrong, unable to write file",
-			})
+			data.TestReport = &report
+			if cov != nil {
+				lines := cov.Report()
+				data.CoverageReport = &lines
+			}
+			data.Stderr = res.Stderr
+			data.Meta = &Meta{Warm: res.Warm, DurationMs: res.Duration.Milliseconds()}
+			data.StatusCode = http.StatusOK
+			if cacheKey != "" && !res.TimedOut {
+				resultCache.Add(cacheKey, data)
+			}
+			data.SubmissionID = recordSubmission(r, data)
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(data)
 			return
 		}
-		err = utils.MoveFile(filename, utils.PathFileTemp(filename))
+
+		res, err := execute(data.Language, data.Txt, data.Stdin, ExecTimeout)
 		if err != nil {
-			log.Printf("error movefile: %v", err)
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(struct {
 				StatusCode int    `json:"statusCode"`
 				Message    string `json:"message"`
 			}{
 				StatusCode: http.StatusBadRequest,
-				Message:    "Something Went Wrong, error movefile",
+				Message:    "Something Went Wrong, " + err.Error(),
 			})
 			return
 		}
-
-		out, errout, err := utils.Shellout(data.Language, utils.PathFileTemp(filename))
-		if data.Language == "go" {
-			out, errout, err = utils.Shellout(data.Language, args, utils.PathFileTemp(filename))
-		}
-		if err != nil {
-			log.Printf("error shell: %v\n", err)
-		}
-		data.Stdout = out
-		data.Stderr = errout
+		data.Stdout = res.Stdout
+		data.Stderr = res.Stderr
+		data.Meta = &Meta{Warm: res.Warm, DurationMs: res.Duration.Milliseconds()}
 		data.StatusCode = http.StatusOK
+		if cacheKey != "" && !res.TimedOut {
+			resultCache.Add(cacheKey, data)
+		}
+		data.SubmissionID = recordSubmission(r, data)
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(data)
 		return
@@ -352,3 +668,31 @@ func loadEnvFile(filename string) error {
 	// Check for scanning errors
 	return scanner.Err()
 }
+
+// loadProblems replaces the embedded problems with PROBLEMS_DIR when set and
+// restores the problems saved in PROBLEM_STORE_DIR
+func loadProblems() error {
+	var err error
+	if dir := os.Getenv("PROBLEMS_DIR"); dir != "" {
+		problemBank, err = problem.Load(os.DirFS(dir), ".")
+		if err != nil {
+			return fmt.Errorf("error loading problems: %v", err)
+		}
+	}
+	problemStore, err = problem.NewStore(getEnv("PROBLEM_STORE_DIR", DefaultProblemStoreDir))
+	if err != nil {
+		return err
+	}
+	if err := problemStore.Restore(problemBank); err != nil {
+		return fmt.Errorf("error restoring problems: %v", err)
+	}
+	return nil
+}
+
+// getEnv returns the environment variable or fallback when it is unset
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
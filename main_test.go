@@ -8,6 +8,10 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/coverage"
+	"github.com/afman42/go-web-code-interactive/internal/pool"
 )
 
 // Setup: Set environment variables required for the tests.
@@ -85,6 +89,133 @@ func TestIndexHandler(t *testing.T) {
 		if status := rr.Code; status != http.StatusOK {
 			t.Errorf("handler returned wrong status code for STQ: got %v want %v", status, http.StatusOK)
 		}
+		var data Data
+		if err := json.Unmarshal(rr.Body.Bytes(), &data); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if data.Verdict == nil || data.Verdict.Verdict != "Accepted" || data.Verdict.Passed != data.Verdict.Total || data.Verdict.Score != 100 {
+			t.Errorf("Expected every test to pass, but got: %+v", data.Verdict)
+		}
+	})
+
+	t.Run("POST Request - STQ Debug Output", func(t *testing.T) {
+		jsonData := `{"txt": "function intIntoString(n) { console.log(JSON.stringify('0')); return String(n); }", "lang": "node", "type": "stq", "noCache": true}`
+		req, err := http.NewRequest("POST", "/", bytes.NewBufferString(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(index).ServeHTTP(rr, req)
+
+		var data Data
+		if err := json.Unmarshal(rr.Body.Bytes(), &data); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		// Prints that look like a result must not be taken for one
+		if data.Verdict == nil || data.Verdict.Verdict != "Accepted" {
+			t.Fatalf("Expected every test to pass, but got: %s", rr.Body.String())
+		}
+		if data.Verdict.Tests[0].Stdout != "\"0\"\n" {
+			t.Errorf("Expected the user's print as stdout, but got %q", data.Verdict.Tests[0].Stdout)
+		}
+	})
+
+	t.Run("POST Request - STQ Wrong Answer", func(t *testing.T) {
+		jsonData := `{"txt": "function intIntoString(n) { return n > 0 ? n.toString() : 'zero'; }", "lang": "node", "type": "stq", "noCache": true}`
+		req, err := http.NewRequest("POST", "/", bytes.NewBufferString(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(index).ServeHTTP(rr, req)
+
+		var data Data
+		if err := json.Unmarshal(rr.Body.Bytes(), &data); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if data.Verdict == nil || data.Verdict.Verdict != "Wrong Answer" {
+			t.Fatalf("Expected wrong answer, but got: %+v", data.Verdict)
+		}
+		if data.Verdict.Mode != "run" || data.Verdict.Passed != 1 || data.Verdict.Total != 2 || data.Verdict.Score != 50 {
+			t.Errorf("Expected 1 of 2 sample tests to pass, but got: %+v", data.Verdict)
+		}
+		failed := data.Verdict.Tests[1]
+		if failed.Passed || failed.Expected != `"0"` || failed.Actual != `"zero"` || len(failed.Diff) == 0 {
+			t.Errorf("Unexpected failed test: %+v", failed)
+		}
+	})
+
+	t.Run("POST Request - STQ Submit", func(t *testing.T) {
+		jsonData := `{"txt": "function intIntoString(n) { return n >= 0 ? n.toString() : 'negative'; }", "lang": "node", "type": "stq", "mode": "submit", "noCache": true}`
+		req, err := http.NewRequest("POST", "/", bytes.NewBufferString(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(index).ServeHTTP(rr, req)
+
+		var data Data
+		if err := json.Unmarshal(rr.Body.Bytes(), &data); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if data.Verdict == nil || data.Verdict.Passed != 3 || data.Verdict.Total != 4 || len(data.Verdict.Tests) != 0 {
+			t.Fatalf("Expected 3 of 4 tests to pass without details, but got: %+v", data.Verdict)
+		}
+		failure := data.Verdict.FirstFailure
+		if failure == nil || !failure.Hidden || failure.Verdict != "Wrong Answer" {
+			t.Fatalf("Expected a hidden wrong answer, but got: %+v", failure)
+		}
+		if strings.Contains(rr.Body.String(), "-42") {
+			t.Errorf("Expected hidden input to stay on the server, got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("POST Request - STQ Go Harness Files", func(t *testing.T) {
+		testCases := []struct {
+			name    string
+			code    string
+			verdict string
+			errorAt string
+		}{
+			{"own main and imports", "package main\n\nimport \"strconv\"\n\nfunc intIntoString(n int) string {\n\treturn strconv.Itoa(n)\n}\n\nfunc main() {}\n", "Accepted", ""},
+			{"compile error", "package main\n\nfunc intIntoString(n int) string {\n\treturn fmt.Sprint(n)\n}\n", "Compilation Error", "solution.go:4"},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				payload, _ := json.Marshal(map[string]any{"txt": tc.code, "lang": "go", "type": "stq", "noCache": true})
+				req, err := http.NewRequest("POST", "/", bytes.NewBuffer(payload))
+				if err != nil {
+					t.Fatal(err)
+				}
+				rr := httptest.NewRecorder()
+				http.HandlerFunc(index).ServeHTTP(rr, req)
+
+				var data Data
+				if err := json.Unmarshal(rr.Body.Bytes(), &data); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if data.Verdict == nil || data.Verdict.Verdict != tc.verdict {
+					t.Fatalf("Expected verdict %s, but got: %s", tc.verdict, rr.Body.String())
+				}
+				stderr := data.Verdict.CompileOutput + data.Verdict.Tests[0].Stderr
+				if !strings.Contains(stderr, tc.errorAt) || strings.Contains(stderr, "run-") {
+					t.Errorf("Expected error at the user's line %q, but got %q", tc.errorAt, stderr)
+				}
+			})
+		}
+	})
+
+	t.Run("POST Request - STQ Invalid Mode", func(t *testing.T) {
+		jsonData := `{"txt": "function intIntoString(n) {}", "lang": "node", "type": "stq", "mode": "peek"}`
+		req, err := http.NewRequest("POST", "/", bytes.NewBufferString(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(index).ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+		}
 	})
 
 	// Test POST request with invalid language
@@ -337,3 +468,208 @@ func TestDataStruct(t *testing.T) {
 		t.Errorf("Data struct marshaling/unmarshaling failed: got %+v, want %+v", unmarshaledData, data)
 	}
 }
+
+// TestIndexHandlerWorkerPool checks that node code runs on a warm worker
+func TestIndexHandlerWorkerPool(t *testing.T) {
+	workerPools["node"] = pool.New("node", 1)
+	defer func() {
+		workerPools["node"].Close()
+		delete(workerPools, "node")
+	}()
+
+	var data Data
+	for i := 0; i < 50 && (data.Meta == nil || !data.Meta.Warm); i++ {
+		time.Sleep(20 * time.Millisecond)
+		jsonData := `{"txt": "console.log(\"hello world\");", "lang": "node", "type": "repl", "noCache": true}`
+		req, err := http.NewRequest("POST", "/", bytes.NewBufferString(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(index)
+		handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &data); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+
+	if data.Meta == nil || !data.Meta.Warm {
+		t.Errorf("Expected a warm worker to be used, got meta %+v", data.Meta)
+	}
+	if data.Stdout != "hello world\n" {
+		t.Errorf("Expected stdout 'hello world\\n', got %q", data.Stdout)
+	}
+	if m := workerPools["node"].Metrics(); m.Hits == 0 {
+		t.Errorf("Expected pool hits to be counted, got %+v", m)
+	}
+}
+
+// TestIndexHandlerResultCache checks identical submissions are served from the cache
+func TestIndexHandlerResultCache(t *testing.T) {
+	post := func(jsonData string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "/", bytes.NewBufferString(jsonData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(index)
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	jsonData := `{"txt": "console.log(\"cached\");", "lang": "node", "type": "repl", "stdin": "x"}`
+	first := post(jsonData)
+	if got := first.Header().Get("X-Cache"); got != "MISS" {
+		t.Errorf("Expected X-Cache MISS on first run, got %q", got)
+	}
+	second := post(jsonData)
+	if got := second.Header().Get("X-Cache"); got != "HIT" {
+		t.Errorf("Expected X-Cache HIT on second run, got %q", got)
+	}
+	if first.Body.String() != second.Body.String() {
+		t.Errorf("Expected cached body to match, got %s and %s", first.Body.String(), second.Body.String())
+	}
+
+	// Different stdin is a different key
+	if got := post(`{"txt": "console.log(\"cached\");", "lang": "node", "type": "repl", "stdin": "y"}`).Header().Get("X-Cache"); got != "MISS" {
+		t.Errorf("Expected X-Cache MISS for different stdin, got %q", got)
+	}
+
+	// Opting out always executes
+	noCache := post(`{"txt": "console.log(\"cached\");", "lang": "node", "type": "repl", "stdin": "x", "noCache": true}`)
+	if got := noCache.Header().Get("X-Cache"); got != "BYPASS" {
+		t.Errorf("Expected X-Cache BYPASS with noCache, got %q", got)
+	}
+}
+
+// TestIndexHandlerTestType runs user-written tests against their code
+func TestIndexHandlerTestType(t *testing.T) {
+	post := func(data Data) (*httptest.ResponseRecorder, Data) {
+		body, _ := json.Marshal(data)
+		req := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(index).ServeHTTP(rr, req)
+		var res Data
+		json.NewDecoder(bytes.NewReader(rr.Body.Bytes())).Decode(&res)
+		return rr, res
+	}
+
+	t.Run("Go", func(t *testing.T) {
+		code := "package main\n\nfunc Sum(a, b int) int { return a + b }\n"
+		tests := "package main\n\nimport \"testing\"\n\nfunc TestSum(t *testing.T) {\n\tif Sum(1, 2) != 3 {\n\t\tt.Fatal(\"bad\")\n\t}\n}\n\nfunc TestWrong(t *testing.T) {\n\tt.Errorf(\"got %d\", Sum(1, 1))\n}\n"
+		rr, res := post(Data{Txt: code, Tests: tests, Language: "go", Tipe: "test", NoCache: true})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		r := res.TestReport
+		if r == nil || r.Total != 2 || r.Passed != 1 || r.Tests[1].Name != "TestWrong" || r.Tests[1].Failure != "main_test.go:12: got 2" {
+			t.Errorf("Expected one passing and one failing test, but got %+v", r)
+		}
+
+		_, res = post(Data{Txt: "package main\n\nfunc Sum(a int) int { return b }\n", Tests: tests, Language: "go", Tipe: "test", NoCache: true})
+		if res.TestReport == nil || !strings.Contains(res.TestReport.BuildError, "main.go:3:30: undefined: b") {
+			t.Errorf("Expected the compiler errors, but got %+v", res.TestReport)
+		}
+	})
+
+	t.Run("Node", func(t *testing.T) {
+		code := "function sum(a, b) { return a + b }\nmodule.exports = { sum }\n"
+		tests := "const test = require('node:test');\nconst assert = require('node:assert');\nconst { sum } = require('./main.js');\n" +
+			"test('adds', () => { console.log('hi'); assert.strictEqual(sum(1, 2), 3) });\n" +
+			"test('skips', { skip: 'later' }, () => {});\n"
+		rr, res := post(Data{Txt: code, Tests: tests, Language: "node", Tipe: "test", ProblemID: DefaultProblemID, Mode: "submit", NoCache: true})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		r := res.TestReport
+		if r == nil || r.Total != 2 || r.Passed != 1 || r.Skipped != 1 || r.Tests[0].Output != "hi" {
+			t.Errorf("Expected a passing and a skipped test, but got %+v", r)
+		}
+		if res.ProblemID != "" || res.Mode != "" {
+			t.Errorf("Expected the tests not to count toward a problem, but got %+v", res)
+		}
+	})
+
+	t.Run("Missing Tests", func(t *testing.T) {
+		if rr, _ := post(Data{Txt: "1", Language: "node", Tipe: "test"}); rr.Code != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+// TestIndexHandlerCoverage returns the lines of the user's code the tests ran
+func TestIndexHandlerCoverage(t *testing.T) {
+	post := func(data Data) (*httptest.ResponseRecorder, Data) {
+		data.Coverage, data.NoCache = true, true
+		body, _ := json.Marshal(data)
+		req := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(index).ServeHTTP(rr, req)
+		var res Data
+		json.NewDecoder(bytes.NewReader(rr.Body.Bytes())).Decode(&res)
+		return rr, res
+	}
+	hits := func(r *coverage.Report) map[int]int {
+		m := map[int]int{}
+		if r != nil {
+			for _, l := range r.Lines {
+				m[l.Line] = l.Hits
+			}
+		}
+		return m
+	}
+
+	t.Run("STQ", func(t *testing.T) {
+		solutions := map[string]string{
+			"node": "function intIntoString(n) {\n  if (n < 0) {\n    return 'negative'\n  }\n  return String(n)\n}\n",
+			"go":   "package main\n\nimport \"strconv\"\n\nfunc intIntoString(n int) string {\n\tif n < 0 {\n\t\treturn \"negative\"\n\t}\n\treturn strconv.Itoa(n)\n}\n",
+		}
+		for lang, code := range solutions {
+			rr, res := post(Data{Txt: code, Language: lang, Tipe: "stq"})
+			if rr.Code != http.StatusOK {
+				t.Fatalf("handler returned wrong status code for %s: got %v want %v: %s", lang, rr.Code, http.StatusOK, rr.Body.String())
+			}
+			// The negative branch is never taken by the sample tests
+			m := hits(res.CoverageReport)
+			negative, other := 3, 5
+			if lang == "go" {
+				negative, other = 7, 9
+			}
+			if m[negative] != 0 || m[other] == 0 || res.CoverageReport.Covered == res.CoverageReport.Total {
+				t.Errorf("Expected line %d of %s to be missed and line %d to run, but got %+v", negative, lang, other, res.CoverageReport)
+			}
+		}
+	})
+
+	t.Run("Tests", func(t *testing.T) {
+		code := "function sign(n) {\n  if (n < 0) {\n    return -1\n  }\n  return 1\n}\nmodule.exports = { sign }\n"
+		tests := "const test = require('node:test');\nconst assert = require('node:assert');\nconst { sign } = require('./main.js');\ntest('positive', () => assert.strictEqual(sign(2), 1));\n"
+		_, res := post(Data{Txt: code, Tests: tests, Language: "node", Tipe: "test"})
+		if m := hits(res.CoverageReport); m[3] != 0 || m[5] != 1 || m[7] != 1 {
+			t.Errorf("Expected the negative branch to be missed, but got %+v", res.CoverageReport)
+		}
+
+		code = "package main\n\nfunc Sign(n int) int {\n\tif n < 0 {\n\t\treturn -1\n\t}\n\treturn 1\n}\n"
+		tests = "package main\n\nimport \"testing\"\n\nfunc TestSign(t *testing.T) {\n\tif Sign(2) != 1 {\n\t\tt.Fail()\n\t}\n}\n"
+		_, res = post(Data{Txt: code, Tests: tests, Language: "go", Tipe: "test"})
+		if m := hits(res.CoverageReport); m[5] != 0 || m[7] != 1 || len(m) == 0 {
+			t.Errorf("Expected the negative branch to be missed, but got %+v", res.CoverageReport)
+		}
+	})
+
+	t.Run("Unavailable", func(t *testing.T) {
+		for _, data := range []Data{
+			{Txt: "<?php echo 1;", Tests: "<?php", Language: "php", Tipe: "test"},
+			{Txt: "console.log(1)", Language: "node", Tipe: "repl"},
+			{Txt: "function intIntoString(n) { return '' }", Language: "node", Tipe: "stq", Mode: "submit"},
+		} {
+			if rr, _ := post(data); rr.Code != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code for %s %s: got %v want %v", data.Language, data.Tipe, rr.Code, http.StatusBadRequest)
+			}
+		}
+	})
+}
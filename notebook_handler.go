@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/afman42/go-web-code-interactive/internal/notebook"
+	"github.com/afman42/go-web-code-interactive/internal/ratelimiter"
+	"github.com/afman42/go-web-code-interactive/internal/session"
+	"github.com/afman42/go-web-code-interactive/utils"
+)
+
+// sessionKernel runs notebook cells in sessions owned by the requesting
+// client. Sessions of other clients are never used, the runner replaces them
+// with a session of the caller.
+type sessionKernel struct {
+	owner string
+}
+
+func (k sessionKernel) Start(language string) (string, error) {
+	s, err := sessionManager.Create(k.owner, language)
+	if err != nil {
+		return "", err
+	}
+	return s.ID, nil
+}
+
+func (k sessionKernel) Eval(sessionID, code string) (notebook.Output, error) {
+	s, err := sessionManager.Get(sessionID)
+	if err == session.ErrNotFound || err == nil && s.Owner != k.owner {
+		return notebook.Output{}, notebook.ErrKernelGone
+	}
+	if err := securityValidator.ValidateCode(code, s.Language); err != nil {
+		return notebook.Output{Error: "Security validation failed: " + err.Error()}, nil
+	}
+	cell, err := sessionManager.Eval(sessionID, code)
+	if err == session.ErrNotFound {
+		return notebook.Output{}, notebook.ErrKernelGone
+	}
+	return notebook.Output{
+		Stdout:     cell.Stdout,
+		Stderr:     cell.Stderr,
+		Value:      cell.Value,
+		Error:      cell.Error,
+		DurationMs: cell.DurationMs,
+	}, err
+}
+
+func (k sessionKernel) Stop(sessionID string) error {
+	s, err := sessionManager.Get(sessionID)
+	if err != nil || s.Owner != k.owner {
+		return session.ErrNotFound
+	}
+	return sessionManager.Close(sessionID)
+}
+
+// ownNotebook loads a notebook the client created. Other clients' notebooks
+// are not found, unless the client is an admin.
+func ownNotebook(r *http.Request, id string) (notebook.Notebook, error) {
+	nb, err := notebookRunner.Store().Get(id)
+	if err == nil && nb.Owner != ratelimiter.GetVisitorIP(r) && !isAdmin(r) {
+		return notebook.Notebook{}, notebook.ErrNotFound
+	}
+	return nb, err
+}
+
+// notebooks handles GET (list) and POST (create) on /api/notebooks. Clients
+// list the notebooks they created, admins every notebook.
+func notebooks(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	switch r.Method {
+	case http.MethodGet:
+		list, err := notebookRunner.Store().List()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !isAdmin(r) {
+			owner := ratelimiter.GetVisitorIP(r)
+			list = slices.DeleteFunc(list, func(nb notebook.Notebook) bool { return nb.Owner != owner })
+		}
+		writeJSON(w, http.StatusOK, list)
+	case http.MethodPost:
+		var nb notebook.Notebook
+		if err := json.NewDecoder(r.Body).Decode(&nb); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		nb.Language = strings.TrimSpace(nb.Language)
+		if !utils.CheckIsNotData([]string{"php", "node", "go"}, nb.Language) {
+			writeError(w, http.StatusBadRequest, "Something Went Wrong, Check Language is exist")
+			return
+		}
+		nb.Owner = ratelimiter.GetVisitorIP(r)
+		nb, err := notebookRunner.Store().Create(nb)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, nb)
+	case http.MethodOptions:
+		w.Header().Set("Allow", "GET, POST, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w, "GET, POST, OPTIONS")
+	}
+}
+
+// notebookByID handles GET, PUT and DELETE on /api/notebooks/{id}
+func notebookByID(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	id := r.PathValue("id")
+	switch r.Method {
+	case http.MethodGet:
+		nb, err := ownNotebook(r, id)
+		if err != nil {
+			writeNotebookError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, nb)
+	case http.MethodPut:
+		var body struct {
+			Title string          `json:"title"`
+			Cells []notebook.Cell `json:"cells"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if _, err := ownNotebook(r, id); err != nil {
+			writeNotebookError(w, err)
+			return
+		}
+		nb, err := notebookRunner.Store().Update(id, func(nb *notebook.Notebook) error {
+			nb.Title = body.Title
+			nb.Cells = body.Cells
+			return nil
+		})
+		if err != nil {
+			writeNotebookError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, nb)
+	case http.MethodDelete:
+		nb, err := ownNotebook(r, id)
+		if err != nil {
+			writeNotebookError(w, err)
+			return
+		}
+		if nb.SessionID != "" {
+			sessionKernel{owner: ratelimiter.GetVisitorIP(r)}.Stop(nb.SessionID)
+		}
+		if err := notebookRunner.Store().Delete(id); err != nil {
+			writeNotebookError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodOptions:
+		w.Header().Set("Allow", "GET, PUT, DELETE, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w, "GET, PUT, DELETE, OPTIONS")
+	}
+}
+
+// notebookAction handles POST /api/notebooks/{id}/run, /restart and
+// /cells/{cell}/run
+func notebookAction(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	switch r.Method {
+	case http.MethodPost:
+		k := sessionKernel{owner: ratelimiter.GetVisitorIP(r)}
+		id := r.PathValue("id")
+		nb, err := ownNotebook(r, id)
+		if err != nil {
+			writeNotebookError(w, err)
+			return
+		}
+		switch {
+		case r.PathValue("cell") != "":
+			nb, err = notebookRunner.RunCell(k, id, r.PathValue("cell"))
+		case strings.HasSuffix(r.URL.Path, "/restart"):
+			nb, err = notebookRunner.Restart(k, id)
+		default:
+			nb, err = notebookRunner.RunAll(k, id)
+		}
+		if err != nil {
+			writeNotebookError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, nb)
+	case http.MethodOptions:
+		w.Header().Set("Allow", "POST, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w, "POST, OPTIONS")
+	}
+}
+
+func writeNotebookError(w http.ResponseWriter, err error) {
+	switch err {
+	case notebook.ErrNotFound, notebook.ErrCellNotFound:
+		writeError(w, http.StatusNotFound, err.Error())
+	case notebook.ErrInvalidCell:
+		writeError(w, http.StatusBadRequest, err.Error())
+	case session.ErrTooManySessions:
+		writeError(w, http.StatusTooManyRequests, "Too many open sessions, close one first")
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
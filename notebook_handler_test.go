@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/afman42/go-web-code-interactive/internal/notebook"
+)
+
+func TestNotebookHandlers(t *testing.T) {
+	store, err := notebook.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	notebookRunner = notebook.NewRunner(store)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/notebooks", notebooks)
+	mux.HandleFunc("/api/notebooks/{id}", notebookByID)
+	mux.HandleFunc("/api/notebooks/{id}/run", notebookAction)
+	mux.HandleFunc("/api/notebooks/{id}/restart", notebookAction)
+	mux.HandleFunc("/api/notebooks/{id}/cells/{cell}/run", notebookAction)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("POST", "/api/notebooks", `{"title": "Intro", "lang": "node", "cells": [
+		{"type": "markdown", "source": "# Counting"},
+		{"type": "code", "source": "let n = 1"},
+		{"type": "code", "source": "console.log(n + 1)"}
+	]}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var nb notebook.Notebook
+	json.Unmarshal(rr.Body.Bytes(), &nb)
+	defer do("DELETE", "/api/notebooks/"+nb.ID, "")
+
+	t.Run("Run all", func(t *testing.T) {
+		rr := do("POST", "/api/notebooks/"+nb.ID+"/run", "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		var got notebook.Notebook
+		json.Unmarshal(rr.Body.Bytes(), &got)
+		if got.Cells[2].Output == nil || got.Cells[2].Output.Stdout != "2\n" {
+			t.Errorf("Unexpected output for last cell: %+v", got.Cells[2])
+		}
+	})
+
+	t.Run("Run cell reuses session state", func(t *testing.T) {
+		rr := do("POST", "/api/notebooks/"+nb.ID+"/cells/"+nb.Cells[2].ID+"/run", "")
+		var got notebook.Notebook
+		json.Unmarshal(rr.Body.Bytes(), &got)
+		if got.Cells[2].ExecutionCount != 3 || got.Cells[2].Output.Stdout != "2\n" {
+			t.Errorf("Unexpected cell after rerun: %+v", got.Cells[2])
+		}
+	})
+
+	t.Run("Other clients", func(t *testing.T) {
+		for _, req := range []*http.Request{
+			httptest.NewRequest("GET", "/api/notebooks/"+nb.ID, nil),
+			httptest.NewRequest("PUT", "/api/notebooks/"+nb.ID, bytes.NewBufferString(`{"title": "Mine"}`)),
+			httptest.NewRequest("POST", "/api/notebooks/"+nb.ID+"/run", nil),
+			httptest.NewRequest("POST", "/api/notebooks/"+nb.ID+"/restart", nil),
+			httptest.NewRequest("DELETE", "/api/notebooks/"+nb.ID, nil),
+		} {
+			req.Header.Set("X-Real-IP", "10.0.0.99")
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+			if rr.Code != http.StatusNotFound {
+				t.Errorf("Expected %s %s by another client to find no notebook, but got %v", req.Method, req.URL.Path, rr.Code)
+			}
+		}
+		req := httptest.NewRequest("GET", "/api/notebooks", nil)
+		req.Header.Set("X-Real-IP", "10.0.0.99")
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if strings.Contains(rr.Body.String(), nb.ID) {
+			t.Errorf("Expected the notebook to be left out of another client's list, got %s", rr.Body.String())
+		}
+		if rr := do("GET", "/api/notebooks", ""); !strings.Contains(rr.Body.String(), nb.ID) || strings.Contains(rr.Body.String(), "owner") {
+			t.Errorf("Expected the owner's list to hold the notebook without its owner, got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("Foreign session", func(t *testing.T) {
+		other, err := sessionManager.Create("10.0.0.99", "node")
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		defer sessionManager.Close(other.ID)
+		notebookRunner.Store().Update(nb.ID, func(stored *notebook.Notebook) error {
+			stored.SessionID = other.ID
+			return nil
+		})
+
+		// The owner's cells run in a session of their own, restored from
+		// the cells above
+		rr := do("POST", "/api/notebooks/"+nb.ID+"/cells/"+nb.Cells[2].ID+"/run", "")
+		var got notebook.Notebook
+		json.Unmarshal(rr.Body.Bytes(), &got)
+		if got.SessionID == other.ID || got.Cells[2].Output == nil || got.Cells[2].Output.Stdout != "2\n" {
+			t.Errorf("Expected the cell to run in a new session, got %+v", got)
+		}
+		if s, err := sessionManager.Get(other.ID); err != nil || s.Cells != 0 {
+			t.Errorf("Expected the other client's session to be untouched, got %+v, %v", s, err)
+		}
+	})
+
+	t.Run("Restart kernel", func(t *testing.T) {
+		rr := do("POST", "/api/notebooks/"+nb.ID+"/restart", "")
+		var got notebook.Notebook
+		json.Unmarshal(rr.Body.Bytes(), &got)
+		if got.SessionID != "" || got.Cells[2].Output != nil {
+			t.Errorf("Expected cleared notebook after restart, got %+v", got)
+		}
+
+		// Without the first cell the variable no longer exists
+		rr = do("POST", "/api/notebooks/"+nb.ID+"/cells/"+nb.Cells[2].ID+"/run", "")
+		json.Unmarshal(rr.Body.Bytes(), &got)
+		if got.Cells[2].Output == nil || got.Cells[2].Output.Error == "" {
+			t.Errorf("Expected ReferenceError after restart, got %+v", got.Cells[2])
+		}
+		do("POST", "/api/notebooks/"+nb.ID+"/restart", "")
+	})
+
+	t.Run("Unknown notebook", func(t *testing.T) {
+		rr := do("GET", "/api/notebooks/missing", "")
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+	})
+}
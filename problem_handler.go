@@ -0,0 +1,150 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+	"github.com/afman42/go-web-code-interactive/internal/ratelimiter"
+)
+
+// ProblemView is the public representation of a problem, without harness
+// code. StatementHTML is the Markdown statement rendered and sanitized, with
+// the samples appended as examples.
+type ProblemView struct {
+	ID            string   `json:"id"`
+	Title         string   `json:"title"`
+	Statement     string   `json:"statement"`
+	StatementHTML string   `json:"statementHtml"`
+	Function      string   `json:"function"`
+	Signature     string   `json:"signature,omitempty"`
+	Languages     []string `json:"languages"`
+	Tags          []string `json:"tags,omitempty"`
+	Difficulty    string   `json:"difficulty,omitempty"`
+	// Hints counts the problem's hints, revealed one at a time over the
+	// hints endpoint; HasEditorial tells whether the editorial endpoint
+	// has anything to unlock
+	Hints        int                `json:"hints"`
+	HasEditorial bool               `json:"hasEditorial"`
+	Starter      map[string]string  `json:"starter"`
+	Samples      []problem.TestCase `json:"samples"`
+	Version      int                `json:"version"`
+}
+
+func newProblemView(p problem.Problem) ProblemView {
+	return ProblemView{
+		ID:            p.ID,
+		Title:         p.Title,
+		Statement:     p.Statement,
+		StatementHTML: statements.HTML(p),
+		Function:      p.Function,
+		Signature:     p.Signature,
+		Languages:     p.Languages(),
+		Tags:          p.Tags,
+		Difficulty:    p.Difficulty,
+		Hints:         len(p.Hints),
+		HasEditorial:  p.Editorial != "",
+		Starter:       p.Starter,
+		Samples:       p.Samples(),
+		Version:       p.Version,
+	}
+}
+
+// CatalogEntry is a problem in the catalog with the requesting client's
+// progress on it and, for a search, its relevance
+type CatalogEntry struct {
+	problem.Summary
+	// Status is "solved" or "attempted" once the client made a graded
+	// submission to the problem
+	Status string  `json:"status,omitempty"`
+	Score  float64 `json:"score,omitempty"`
+}
+
+// problems handles GET /api/problems. The catalog is filtered by tag (every
+// tag given must match), difficulty, lang and status ("solved",
+// "attempted" or "new"), and q searches titles, tags and statements, most
+// relevant first.
+func problems(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, "GET")
+		return
+	}
+	q := r.URL.Query()
+	if d := q.Get("difficulty"); d != "" && !slices.Contains(problem.Difficulties, d) {
+		writeError(w, http.StatusBadRequest, "invalid difficulty")
+		return
+	}
+	if s := q.Get("status"); s != "" && !slices.Contains([]string{"solved", "attempted", "new"}, s) {
+		writeError(w, http.StatusBadRequest, "invalid status")
+		return
+	}
+	bank := problemBank.List()
+	progress := leaderboards.Progress(ratelimiter.GetVisitorIP(r))
+	entries := make([]CatalogEntry, 0, len(bank))
+	for _, p := range bank {
+		e := CatalogEntry{Summary: p.Summary()}
+		if solved, ok := progress[p.ID]; ok {
+			e.Status = "attempted"
+			if solved {
+				e.Status = "solved"
+			}
+		}
+		if catalogMatch(e, q) {
+			entries = append(entries, e)
+		}
+	}
+	if query := q.Get("q"); query != "" {
+		problemIndex.Sync(bank)
+		scores := map[string]float64{}
+		for _, hit := range problemIndex.Search(query) {
+			scores[hit.ID] = hit.Score
+		}
+		entries = slices.DeleteFunc(entries, func(e CatalogEntry) bool { return scores[e.ID] == 0 })
+		for i := range entries {
+			entries[i].Score = scores[entries[i].ID]
+		}
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].Score > entries[j].Score })
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// catalogMatch reports whether a catalog entry passes the query's filters
+func catalogMatch(e CatalogEntry, q url.Values) bool {
+	for _, tag := range q["tag"] {
+		if !slices.Contains(e.Tags, tag) {
+			return false
+		}
+	}
+	if d := q.Get("difficulty"); d != "" && e.Difficulty != d {
+		return false
+	}
+	if lang := q.Get("lang"); lang != "" && !slices.Contains(e.Languages, lang) {
+		return false
+	}
+	switch q.Get("status") {
+	case "new":
+		return e.Status == ""
+	case "":
+		return true
+	default:
+		return e.Status == q.Get("status")
+	}
+}
+
+// problemByID handles GET /api/problems/{id}
+func problemByID(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, "GET")
+		return
+	}
+	p, ok := problemBank.Get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "problem not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, newProblemView(p))
+}
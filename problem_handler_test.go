@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/afman42/go-web-code-interactive/internal/judge"
+	"github.com/afman42/go-web-code-interactive/internal/leaderboard"
+	"github.com/afman42/go-web-code-interactive/internal/problem"
+	"github.com/afman42/go-web-code-interactive/internal/submission"
+)
+
+func TestProblemHandlers(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/problems", problems)
+	mux.HandleFunc("/api/problems/{id}", problemByID)
+
+	t.Run("List", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("GET", "/api/problems", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		if !strings.Contains(rr.Body.String(), `"id":"int-into-string"`) {
+			t.Errorf("Expected default problem in list, got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("Catalog", func(t *testing.T) {
+		oldBoard := leaderboards
+		leaderboards = leaderboard.New()
+		defer func() {
+			leaderboards = oldBoard
+			problemBank.Delete("catalog-sum", nil)
+			problemBank.Delete("catalog-graph", nil)
+		}()
+		for _, p := range []problem.Problem{
+			{ID: "catalog-sum", Title: "Array Sum", Statement: "Add up every number of the array.", Signature: "arraySum(xs: []int) -> int",
+				Tags: []string{"arrays"}, Difficulty: "easy",
+				Tests: []problem.TestCase{{Args: []json.RawMessage{json.RawMessage("[1]")}, Expected: json.RawMessage("1")}}},
+			{ID: "catalog-graph", Title: "Shortest Path", Statement: "Find the shortest path through a graph given as an array of edges.", Signature: "shortestPath(edges: [][]int) -> int",
+				Tags: []string{"arrays", "graphs"}, Difficulty: "hard",
+				Tests: []problem.TestCase{{Args: []json.RawMessage{json.RawMessage("[]")}, Expected: json.RawMessage("0")}}},
+		} {
+			if _, err := problemBank.Put(p, false, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		leaderboards.Add(submission.Submission{Owner: "10.0.0.1", ProblemID: "catalog-sum", Mode: judge.ModeSubmit, Passed: 1, Total: 1})
+		leaderboards.Add(submission.Submission{Owner: "10.0.0.1", ProblemID: "catalog-graph", Mode: judge.ModeSubmit, Passed: 0, Total: 1})
+
+		list := func(query string) []CatalogEntry {
+			req := httptest.NewRequest("GET", "/api/problems?"+query, nil)
+			req.Header.Set("X-Real-IP", "10.0.0.1")
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+			var entries []CatalogEntry
+			json.NewDecoder(rr.Body).Decode(&entries)
+			return entries
+		}
+		ids := func(entries []CatalogEntry) string {
+			var list []string
+			for _, e := range entries {
+				list = append(list, e.ID)
+			}
+			return strings.Join(list, ",")
+		}
+		testCases := []struct {
+			query string
+			ids   string
+		}{
+			{"tag=arrays", "catalog-graph,catalog-sum"},
+			{"tag=arrays&tag=graphs", "catalog-graph"},
+			{"difficulty=easy", "catalog-sum,int-into-string"},
+			{"lang=node&tag=arrays", "catalog-graph,catalog-sum"},
+			{"status=solved", "catalog-sum"},
+			{"status=attempted", "catalog-graph"},
+			{"status=new", "int-into-string"},
+			{"q=array", "catalog-sum,catalog-graph"},
+			{"q=shortest+graph", "catalog-graph"},
+			{"q=integer&tag=arrays", ""},
+		}
+		for _, tc := range testCases {
+			if got := ids(list(tc.query)); got != tc.ids {
+				t.Errorf("Expected %q for %s, but got %q", tc.ids, tc.query, got)
+			}
+		}
+		entries := list("q=array")
+		if entries[0].Status != "solved" || entries[0].Score <= entries[1].Score || entries[0].Difficulty != "easy" {
+			t.Errorf("Expected the solved, more relevant problem first, but got %+v", entries)
+		}
+		for _, query := range []string{"difficulty=trivial", "status=done"} {
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, httptest.NewRequest("GET", "/api/problems?"+query, nil))
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected %s to be rejected, but got %v", query, rr.Code)
+			}
+		}
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("GET", "/api/problems/int-into-string", nil))
+		var view ProblemView
+		if err := json.Unmarshal(rr.Body.Bytes(), &view); err != nil {
+			t.Fatalf("Failed to decode problem: %v", err)
+		}
+		if view.Function != "intIntoString" || view.Starter["node"] == "" {
+			t.Errorf("Unexpected problem view: %+v", view)
+		}
+		if strings.Contains(rr.Body.String(), "harness") {
+			t.Error("Expected harness code to stay on the server")
+		}
+		if len(view.Samples) != 2 || strings.Contains(rr.Body.String(), "123456") {
+			t.Errorf("Expected only sample tests to be public, got %s", rr.Body.String())
+		}
+		if !strings.Contains(view.StatementHTML, "<code>intIntoString</code>") || !strings.Contains(view.StatementHTML, "<strong>Input:</strong> n = 0") {
+			t.Errorf("Expected the rendered statement with its examples, got %q", view.StatementHTML)
+		}
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("GET", "/api/problems/missing", nil))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestIndexHandlerUnknownProblem(t *testing.T) {
+	jsonData := `{"txt": "function f() {}", "lang": "node", "type": "stq", "problemId": "missing"}`
+	req := httptest.NewRequest("POST", "/", bytes.NewBufferString(jsonData))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(index).ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/afman42/go-web-code-interactive/internal/ratelimiter"
+	"github.com/afman42/go-web-code-interactive/internal/session"
+	"github.com/afman42/go-web-code-interactive/utils"
+)
+
+// sessions handles POST /api/sessions, which starts a new interpreter
+func sessions(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	switch r.Method {
+	case http.MethodPost:
+		var body struct {
+			Language string `json:"lang"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		body.Language = strings.TrimSpace(body.Language)
+		if !utils.CheckIsNotData([]string{"php", "node", "go"}, body.Language) {
+			writeError(w, http.StatusBadRequest, "Something Went Wrong, Check Language is exist")
+			return
+		}
+		s, err := sessionManager.Create(ratelimiter.GetVisitorIP(r), body.Language)
+		if err == session.ErrTooManySessions {
+			writeError(w, http.StatusTooManyRequests, "Too many open sessions, close one first")
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Something Went Wrong, unable to start session")
+			return
+		}
+		writeJSON(w, http.StatusCreated, s)
+	case http.MethodOptions:
+		w.Header().Set("Allow", "POST, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w, "POST, OPTIONS")
+	}
+}
+
+// sessionByID handles GET and DELETE /api/sessions/{id}
+func sessionByID(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	id := r.PathValue("id")
+	switch r.Method {
+	case http.MethodGet:
+		s, err := ownSession(r, id)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, s)
+	case http.MethodDelete:
+		_, err := ownSession(r, id)
+		if err == nil {
+			err = sessionManager.Close(id)
+		}
+		if err != nil {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodOptions:
+		w.Header().Set("Allow", "GET, DELETE, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w, "GET, DELETE, OPTIONS")
+	}
+}
+
+// sessionEval handles POST /api/sessions/{id}/eval, which runs one cell
+func sessionEval(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	switch r.Method {
+	case http.MethodPost:
+		id := r.PathValue("id")
+		s, err := ownSession(r, id)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		var body struct {
+			Txt string `json:"txt"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if strings.TrimSpace(body.Txt) == "" {
+			writeError(w, http.StatusBadRequest, "Something Went Wrong, Check Field Empty")
+			return
+		}
+		if err := securityValidator.ValidateCode(body.Txt, s.Language); err != nil {
+			writeError(w, http.StatusBadRequest, "Security validation failed: "+err.Error())
+			return
+		}
+		cell, err := sessionManager.Eval(id, body.Txt)
+		if err == session.ErrNotFound {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			// The interpreter was stopped, the client has to open a new session
+			cell.Error = err.Error()
+			writeJSON(w, http.StatusGone, cell)
+			return
+		}
+		writeJSON(w, http.StatusOK, cell)
+	case http.MethodOptions:
+		w.Header().Set("Allow", "POST, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w, "POST, OPTIONS")
+	}
+}
+
+// ownSession returns the session if it belongs to the client or the client
+// is an admin, other clients are told it does not exist
+func ownSession(r *http.Request, id string) (session.Session, error) {
+	s, err := sessionManager.Get(id)
+	if err == nil && s.Owner != ratelimiter.GetVisitorIP(r) && !isAdmin(r) {
+		return session.Session{}, session.ErrNotFound
+	}
+	return s, err
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/afman42/go-web-code-interactive/internal/session"
+)
+
+// newTestMux registers the session routes the same way main does
+func newTestMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/sessions", sessions)
+	mux.HandleFunc("/api/sessions/{id}", sessionByID)
+	mux.HandleFunc("/api/sessions/{id}/eval", sessionEval)
+	return mux
+}
+
+func TestSessionHandlers(t *testing.T) {
+	mux := newTestMux()
+
+	// Start a node session
+	req := httptest.NewRequest("POST", "/api/sessions", bytes.NewBufferString(`{"lang": "node"}`))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var s session.Session
+	if err := json.Unmarshal(rr.Body.Bytes(), &s); err != nil {
+		t.Fatalf("Failed to decode session: %v", err)
+	}
+	defer sessionManager.Close(s.ID)
+
+	t.Run("Eval keeps state", func(t *testing.T) {
+		for _, code := range []string{`let total = 40`, `total += 2`} {
+			req := httptest.NewRequest("POST", "/api/sessions/"+s.ID+"/eval", bytes.NewBufferString(`{"txt": "`+code+`"}`))
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+			}
+		}
+
+		req := httptest.NewRequest("POST", "/api/sessions/"+s.ID+"/eval", bytes.NewBufferString(`{"txt": "console.log(total)"}`))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		var cell session.Cell
+		json.Unmarshal(rr.Body.Bytes(), &cell)
+		if cell.Stdout != "42\n" || cell.Index != 3 {
+			t.Errorf("Unexpected cell: %+v", cell)
+		}
+	})
+
+	t.Run("Eval rejects forbidden code", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/sessions/"+s.ID+"/eval", bytes.NewBufferString(`{"txt": "require('fs')"}`))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "Security validation failed") {
+			t.Errorf("Expected security validation failure, got %v: %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Other clients", func(t *testing.T) {
+		for _, req := range []*http.Request{
+			httptest.NewRequest("GET", "/api/sessions/"+s.ID, nil),
+			httptest.NewRequest("POST", "/api/sessions/"+s.ID+"/eval", bytes.NewBufferString(`{"txt": "console.log(total)"}`)),
+			httptest.NewRequest("DELETE", "/api/sessions/"+s.ID, nil),
+		} {
+			req.Header.Set("X-Real-IP", "10.0.0.99")
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+			if rr.Code != http.StatusNotFound {
+				t.Errorf("Expected %s by another client to find no session, but got %v", req.Method, rr.Code)
+			}
+		}
+		if _, err := sessionManager.Get(s.ID); err != nil {
+			t.Errorf("Expected the session to stay open, but got: %v", err)
+		}
+	})
+
+	t.Run("Close session", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/api/sessions/"+s.ID, nil)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != http.StatusNoContent {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+		}
+
+		req = httptest.NewRequest("GET", "/api/sessions/"+s.ID, nil)
+		rr = httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code after close: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("Invalid language", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/sessions", bytes.NewBufferString(`{"lang": "python"}`))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+		}
+	})
+}
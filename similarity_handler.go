@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/afman42/go-web-code-interactive/internal/judge"
+	"github.com/afman42/go-web-code-interactive/internal/similarity"
+	"github.com/afman42/go-web-code-interactive/internal/submission"
+)
+
+// SimilarityReport lists the pairs of accepted submissions to a problem
+// that look alike
+type SimilarityReport struct {
+	ProblemID string  `json:"problemId"`
+	Threshold float64 `json:"threshold"`
+	// Compared counts the submissions compared, the latest accepted one of
+	// each client
+	Compared int               `json:"compared"`
+	Pairs    []similarity.Pair `json:"pairs"`
+}
+
+// similarityFilter selects the submissions a report compares
+type similarityFilter struct {
+	Language  string
+	ContestID string
+	Threshold float64
+}
+
+// similarityReport compares the latest accepted submission in store of every
+// client to a problem, ignoring what they share with the starter code
+func similarityReport(store submission.Store, problemID string, f similarityFilter) (SimilarityReport, error) {
+	if f.Threshold <= 0 {
+		f.Threshold = similarity.DefaultThreshold
+	}
+	report := SimilarityReport{ProblemID: problemID, Threshold: f.Threshold}
+	var docs []similarity.Document
+	seen := map[string]bool{}
+	for offset := 0; ; offset += HistoryReplayPage {
+		list, err := store.List(submission.Filter{ProblemID: problemID, Language: f.Language, Limit: HistoryReplayPage, Offset: offset})
+		if err != nil {
+			return report, err
+		}
+		// The list is newest first, so the first accepted submission of a
+		// client is their latest
+		for _, s := range list {
+			accepted := s.Mode == judge.ModeSubmit && s.Total > 0 && s.Passed == s.Total
+			if !accepted || seen[s.Owner] || f.ContestID != "" && s.ContestID != f.ContestID {
+				continue
+			}
+			seen[s.Owner] = true
+			docs = append(docs, similarity.Document{ID: s.ID, Owner: s.Owner, Language: s.Language, Code: s.Code})
+		}
+		if len(list) < HistoryReplayPage {
+			break
+		}
+	}
+	var base map[string]string
+	if p, ok := problemBank.Get(problemID); ok {
+		base = p.Starter
+	}
+	report.Compared = len(docs)
+	report.Pairs = similarity.Detect(docs, similarity.Options{Threshold: f.Threshold, Base: base})
+	return report, nil
+}
+
+// adminProblemSimilarity handles GET /api/admin/problems/{id}/similarity.
+// The report can be narrowed to a language with lang and to a contest's
+// submissions with contest; threshold (default 0.5) is the least
+// similarity reported.
+func adminProblemSimilarity(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, "GET, OPTIONS")
+		return
+	}
+	if submissionStore == nil {
+		writeError(w, http.StatusServiceUnavailable, "submission history is unavailable")
+		return
+	}
+	q := r.URL.Query()
+	f := similarityFilter{Language: q.Get("lang"), ContestID: q.Get("contest")}
+	if t := q.Get("threshold"); t != "" {
+		threshold, err := strconv.ParseFloat(t, 64)
+		if err != nil || threshold <= 0 || threshold > 1 {
+			writeError(w, http.StatusBadRequest, "threshold must be a number in (0, 1]")
+			return
+		}
+		f.Threshold = threshold
+	}
+	report, err := similarityReport(submissionStore, r.PathValue("id"), f)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/afman42/go-web-code-interactive/internal/judge"
+	"github.com/afman42/go-web-code-interactive/internal/submission"
+)
+
+const copiedSolution = `function intIntoString(n) {
+	if (n < 0) {
+		return "-" + intIntoString(-n)
+	}
+	let digits = []
+	do {
+		digits.unshift(n % 10)
+		n = Math.floor(n / 10)
+	} while (n > 0)
+	return digits.join("")
+}
+`
+
+func TestAdminProblemSimilarity(t *testing.T) {
+	store, err := submission.OpenFile(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	oldToken, oldStore := adminToken, submissionStore
+	adminToken, submissionStore = "secret", store
+	defer func() { adminToken, submissionStore = oldToken, oldStore }()
+
+	save := func(owner, code, mode string, passed int, contestID string) string {
+		s, err := store.Save(submission.Submission{Owner: owner, Type: "stq", Language: "node", ProblemID: DefaultProblemID,
+			Mode: mode, Passed: passed, Total: 2, ContestID: contestID, Code: code})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.ID
+	}
+	original := save("10.0.0.1", copiedSolution, judge.ModeSubmit, 2, "weekly")
+	copied := save("10.0.0.2", "// mine\n"+copiedSolution, judge.ModeSubmit, 2, "")
+	// Runs and failed submissions are never compared
+	save("10.0.0.3", copiedSolution, judge.ModeRun, 2, "")
+	save("10.0.0.4", copiedSolution, judge.ModeSubmit, 1, "")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/admin/problems/{id}/similarity", adminProblemSimilarity)
+	send := func(url, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	url := "/api/admin/problems/" + DefaultProblemID + "/similarity"
+
+	t.Run("Report", func(t *testing.T) {
+		rr := send(url, "secret")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		var report SimilarityReport
+		json.NewDecoder(rr.Body).Decode(&report)
+		if report.Compared != 2 || len(report.Pairs) != 1 {
+			t.Fatalf("Expected the two accepted submissions to match, but got %+v", report)
+		}
+		p := report.Pairs[0]
+		if p.A != copied && p.B != copied || p.A != original && p.B != original || p.Similarity < 0.9 || len(p.Regions) == 0 {
+			t.Errorf("Expected the copy with its aligned region, but got %+v", p)
+		}
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		var report SimilarityReport
+		json.NewDecoder(send(url+"?contest=weekly", "secret").Body).Decode(&report)
+		if report.Compared != 1 || len(report.Pairs) != 0 {
+			t.Errorf("Expected only the contest submission, but got %+v", report)
+		}
+		report = SimilarityReport{}
+		json.NewDecoder(send(url+"?lang=php", "secret").Body).Decode(&report)
+		if report.Compared != 0 {
+			t.Errorf("Expected no php submission, but got %+v", report)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		if rr := send(url, ""); rr.Code != http.StatusUnauthorized {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusUnauthorized)
+		}
+		for _, threshold := range []string{"x", "0", "1.5"} {
+			if rr := send(url+"?threshold="+threshold, "secret"); rr.Code != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code for %s: got %v want %v", threshold, rr.Code, http.StatusBadRequest)
+			}
+		}
+		submissionStore = nil
+		defer func() { submissionStore = store }()
+		if rr := send(url, "secret"); rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusServiceUnavailable)
+		}
+	})
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/afman42/go-web-code-interactive/internal/ratelimiter"
+	"github.com/afman42/go-web-code-interactive/internal/submission"
+)
+
+const (
+	// MaxSubmissionPage bounds the limit of a submission listing
+	MaxSubmissionPage = 200
+	// HistoryReplayPage is how many stored submissions are read at a time
+	// when the history is replayed at startup
+	HistoryReplayPage = 1000
+)
+
+// openSubmissionStore opens the store named by SUBMISSION_STORE: "file"
+// (default) keeps a log under SUBMISSION_DIR, "sql" connects with the
+// database/sql driver SUBMISSION_SQL_DRIVER to SUBMISSION_SQL_DSN
+func openSubmissionStore() (submission.Store, error) {
+	switch kind := getEnv("SUBMISSION_STORE", "file"); kind {
+	case "file":
+		return submission.OpenFile(getEnv("SUBMISSION_DIR", DefaultSubmissionDir))
+	case "sql":
+		return submission.OpenSQL(os.Getenv("SUBMISSION_SQL_DRIVER"), os.Getenv("SUBMISSION_SQL_DSN"))
+	default:
+		return nil, fmt.Errorf("unknown SUBMISSION_STORE %q, expected file or sql", kind)
+	}
+}
+
+// replayHistory ranks every stored submission on the leaderboards and
+// contest scoreboards, once at startup; after that each new submission is
+// added as it is recorded
+func replayHistory() error {
+	for offset := 0; ; offset += HistoryReplayPage {
+		list, err := submissionStore.List(submission.Filter{Limit: HistoryReplayPage, Offset: offset})
+		if err != nil {
+			return err
+		}
+		for _, s := range list {
+			rankSubmission(s)
+		}
+		if len(list) < HistoryReplayPage {
+			return nil
+		}
+	}
+}
+
+// recordSubmission stores a judged execution, an STQ or test run, ranks it
+// on the leaderboards and contest scoreboards and returns its ID. REPL and
+// playground runs are not kept. A failure to store is logged, the user
+// still gets the result.
+func recordSubmission(r *http.Request, data Data) string {
+	if submissionStore == nil || data.Tipe != "stq" && data.Tipe != "test" {
+		return ""
+	}
+	s := submission.Submission{
+		Owner:          ratelimiter.GetVisitorIP(r),
+		Language:       data.Language,
+		Type:           data.Tipe,
+		Code:           data.Txt,
+		Stdin:          data.Stdin,
+		ProblemID:      data.ProblemID,
+		ProblemVersion: data.ProblemVersion,
+		ContestID:      data.ContestID,
+		Mode:           data.Mode,
+		Stdout:         data.Stdout,
+		Stderr:         data.Stderr,
+		Result:         data.Verdict,
+		Tests:          data.Tests,
+		TestReport:     data.TestReport,
+	}
+	if data.Meta != nil {
+		s.DurationMs, s.Warm = data.Meta.DurationMs, data.Meta.Warm
+	}
+	s, err := submissionStore.Save(s)
+	if err != nil {
+		log.Printf("unable to store submission: %v", err)
+		return ""
+	}
+	rankSubmission(s)
+	return s.ID
+}
+
+// rankSubmission adds a stored submission to the leaderboards and contest
+// scoreboards. A contest's submissions stay off the problem leaderboards
+// until the contest ends, where they would give away frozen results.
+func rankSubmission(s submission.Submission) {
+	var after time.Time
+	if s.ContestID != "" && contestStore != nil {
+		if c, err := contestStore.Get(s.ContestID); err == nil {
+			after = c.End
+		}
+	}
+	leaderboards.AddAfter(s, after)
+	contestAttempts.Add(s)
+}
+
+// submissionFilter reads the filter of a listing from the query string:
+// problemId, lang, since and until (RFC 3339), limit and offset
+func submissionFilter(r *http.Request) (submission.Filter, error) {
+	q := r.URL.Query()
+	f := submission.Filter{ProblemID: q.Get("problemId"), Language: q.Get("lang")}
+	var err error
+	for name, t := range map[string]*time.Time{"since": &f.Since, "until": &f.Until} {
+		if v := q.Get(name); v != "" {
+			if *t, err = time.Parse(time.RFC3339, v); err != nil {
+				return f, fmt.Errorf("invalid %s", name)
+			}
+		}
+	}
+	for name, n := range map[string]*int{"limit": &f.Limit, "offset": &f.Offset} {
+		if v := q.Get(name); v != "" {
+			if *n, err = strconv.Atoi(v); err != nil || *n < 0 {
+				return f, fmt.Errorf("invalid %s", name)
+			}
+		}
+	}
+	f.Limit = min(f.Limit, MaxSubmissionPage)
+	return f, nil
+}
+
+// listSubmissions answers a listing without code and output
+func listSubmissions(w http.ResponseWriter, f submission.Filter) {
+	if submissionStore == nil {
+		writeError(w, http.StatusServiceUnavailable, "submission history is disabled")
+		return
+	}
+	list, err := submissionStore.List(f)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for i := range list {
+		list[i] = list[i].Summary()
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// submissions handles GET /api/submissions, the requesting client's own
+// submissions, newest first
+func submissions(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, "GET")
+		return
+	}
+	f, err := submissionFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	f.Owner = ratelimiter.GetVisitorIP(r)
+	listSubmissions(w, f)
+}
+
+// submissionByID handles GET /api/submissions/{id}, which reopens a
+// submission with its code and results. Only its owner and admins see it.
+func submissionByID(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, "GET")
+		return
+	}
+	if submissionStore == nil {
+		writeError(w, http.StatusServiceUnavailable, "submission history is disabled")
+		return
+	}
+	s, err := submissionStore.Get(r.PathValue("id"))
+	if err == nil && s.Owner != ratelimiter.GetVisitorIP(r) && !isAdmin(r) {
+		err = submission.ErrNotFound
+	}
+	if err == submission.ErrNotFound {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, s)
+}
+
+// adminSubmissions handles GET /api/admin/submissions, every client's
+// submissions with an optional ?owner= filter
+func adminSubmissions(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, "GET")
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	f, err := submissionFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	f.Owner = r.URL.Query().Get("owner")
+	listSubmissions(w, f)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/afman42/go-web-code-interactive/internal/judge"
+	"github.com/afman42/go-web-code-interactive/internal/submission"
+)
+
+func TestSubmissionHandlers(t *testing.T) {
+	store, err := submission.OpenFile(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	oldToken, oldStore := adminToken, submissionStore
+	adminToken, submissionStore = "secret", store
+	defer func() { adminToken, submissionStore = oldToken, oldStore }()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/submissions", submissions)
+	mux.HandleFunc("/api/submissions/{id}", submissionByID)
+	mux.HandleFunc("/api/admin/submissions", adminSubmissions)
+	send := func(url, ip, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		req.Header.Set("X-Real-IP", ip)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	record := func(ip string, data Data) string {
+		req := httptest.NewRequest("POST", "/", nil)
+		req.Header.Set("X-Real-IP", ip)
+		id := recordSubmission(req, data)
+		if id == "" {
+			t.Fatal("Expected the submission to be stored")
+		}
+		return id
+	}
+
+	first := record("10.0.0.1", Data{Txt: "console.log(1)", Language: "node", Tipe: "test", Stderr: "1\n", Meta: &Meta{DurationMs: 12}})
+	second := record("10.0.0.1", Data{Txt: "function f() {}", Language: "node", Tipe: "stq", ProblemID: "sum", ProblemVersion: 3,
+		Verdict: &judge.Result{Verdict: judge.Accepted, Passed: 2, Total: 2, Score: 100}})
+	record("10.0.0.2", Data{Txt: "<?php echo 1;", Language: "php", Tipe: "test"})
+
+	t.Run("REPL Runs", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/", nil)
+		req.Header.Set("X-Real-IP", "10.0.0.1")
+		if id := recordSubmission(req, Data{Txt: "1", Language: "node", Tipe: "repl"}); id != "" {
+			t.Errorf("Expected a REPL run not to be stored, but got %s", id)
+		}
+	})
+
+	t.Run("List Own", func(t *testing.T) {
+		rr := send("/api/submissions", "10.0.0.1", "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		var list []submission.Submission
+		json.NewDecoder(rr.Body).Decode(&list)
+		if len(list) != 2 || list[0].ID != second || list[1].ID != first {
+			t.Fatalf("Expected the caller's 2 submissions newest first, but got %+v", list)
+		}
+		if list[0].Code != "" || list[0].Result != nil {
+			t.Errorf("Expected a summary without code and results, but got %+v", list[0])
+		}
+		if list[0].Verdict != judge.Accepted || list[0].Score != 100 || list[0].ProblemVersion != 3 {
+			t.Errorf("Expected the verdict in the summary, but got %+v", list[0])
+		}
+	})
+
+	t.Run("List Filters", func(t *testing.T) {
+		var list []submission.Submission
+		json.NewDecoder(send("/api/submissions?problemId=sum", "10.0.0.1", "").Body).Decode(&list)
+		if len(list) != 1 || list[0].ID != second {
+			t.Errorf("Expected only the sum submission, but got %+v", list)
+		}
+		list = nil
+		json.NewDecoder(send("/api/submissions?limit=1&offset=1", "10.0.0.1", "").Body).Decode(&list)
+		if len(list) != 1 || list[0].ID != first {
+			t.Errorf("Expected the second page to hold the first submission, but got %+v", list)
+		}
+		for _, query := range []string{"since=yesterday", "limit=-1", "offset=x"} {
+			if rr := send("/api/submissions?"+query, "10.0.0.1", ""); rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected %s to be rejected, but got %v", query, rr.Code)
+			}
+		}
+	})
+
+	t.Run("Reopen", func(t *testing.T) {
+		rr := send("/api/submissions/"+first, "10.0.0.1", "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		var s submission.Submission
+		json.NewDecoder(rr.Body).Decode(&s)
+		if s.Code != "console.log(1)" || s.Stderr != "1\n" || s.DurationMs != 12 {
+			t.Errorf("Expected the full submission, but got %+v", s)
+		}
+		if rr := send("/api/submissions/"+first, "10.0.0.2", ""); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected another client's submission to be hidden, but got %v", rr.Code)
+		}
+		if rr := send("/api/submissions/"+first, "10.0.0.2", "secret"); rr.Code != http.StatusOK {
+			t.Errorf("Expected an admin to reopen any submission, but got %v", rr.Code)
+		}
+		if rr := send("/api/submissions/missing", "10.0.0.1", ""); rr.Code != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("Admin List", func(t *testing.T) {
+		if rr := send("/api/admin/submissions", "10.0.0.1", ""); rr.Code != http.StatusUnauthorized {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusUnauthorized)
+		}
+		var list []submission.Submission
+		json.NewDecoder(send("/api/admin/submissions", "", "secret").Body).Decode(&list)
+		if len(list) != 3 {
+			t.Errorf("Expected every submission, but got %d", len(list))
+		}
+		list = nil
+		json.NewDecoder(send("/api/admin/submissions?owner=10.0.0.2&lang=php", "", "secret").Body).Decode(&list)
+		if len(list) != 1 || list[0].Language != "php" {
+			t.Errorf("Expected the php submission of 10.0.0.2, but got %+v", list)
+		}
+		if rr := send("/api/admin/submissions", "", "secret"); strings.Contains(rr.Body.String(), "10.0.0.") {
+			t.Errorf("Expected no owner in the output, but got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		submissionStore = nil
+		defer func() { submissionStore = store }()
+		if rr := send("/api/submissions", "10.0.0.1", ""); rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusServiceUnavailable)
+		}
+	})
+}
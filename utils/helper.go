@@ -2,12 +2,15 @@ package utils
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"math/rand"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"os"
 	"os/exec"
@@ -21,13 +24,50 @@ const LinuxFolderTmp = "/tmp/"
 var seededRand *rand.Rand = rand.New(
 	rand.NewSource(time.Now().UnixNano()))
 
+// ShellOptions configures how ShelloutWithOptions runs a command
+type ShellOptions struct {
+	// Env is added to the server's environment
+	Env []string
+	// Stdin is passed to the command's standard input
+	Stdin string
+	// Timeout kills the command when it runs longer, zero means no limit
+	Timeout time.Duration
+}
+
+// ErrTimeout is returned when a command is killed for exceeding its timeout
+var ErrTimeout = errors.New("command timed out")
+
 func Shellout(language string, args ...string) (string, string, error) {
+	return ShelloutWithOptions(ShellOptions{}, language, args...)
+}
+
+func ShelloutWithOptions(opts ShellOptions, language string, args ...string) (string, string, error) {
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
-	cmd := exec.Command(language, args...)
+	ctx := context.Background()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, language, args...)
+	// Kill the whole process group, `go run` leaves the compiled program
+	// running otherwise
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
+	cmd.WaitDelay = time.Second
+	if len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
+	if opts.Stdin != "" {
+		cmd.Stdin = strings.NewReader(opts.Stdin)
+	}
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = ErrTimeout
+	}
 	return stdout.String(), stderr.String(), err
 }
 
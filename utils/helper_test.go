@@ -6,6 +6,7 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 // TestShellout verifies that code execution for each language works as expected.
@@ -240,3 +241,37 @@ func TestCheckIsNotData(t *testing.T) {
 		t.Error("Expected 'NODE' to not match 'node' due to case sensitivity, but it did.")
 	}
 }
+
+// TestShelloutWithOptions verifies extra environment variables reach the command.
+func TestShelloutWithOptions(t *testing.T) {
+	stdout, _, err := ShelloutWithOptions(ShellOptions{Env: []string{"SHELL_OPTIONS_TEST=hello"}}, "node", "-e", "console.log(process.env.SHELL_OPTIONS_TEST)")
+	if err != nil {
+		t.Errorf("Expected no error, but got: %v", err)
+	}
+	if strings.TrimSpace(stdout) != "hello" {
+		t.Errorf("Expected stdout 'hello', but got: %s", stdout)
+	}
+}
+
+// TestShelloutWithStdin verifies standard input is passed to the command.
+func TestShelloutWithStdin(t *testing.T) {
+	stdout, _, err := ShelloutWithOptions(ShellOptions{Stdin: "from stdin"}, "node", "-e", "process.stdin.pipe(process.stdout)")
+	if err != nil {
+		t.Errorf("Expected no error, but got: %v", err)
+	}
+	if stdout != "from stdin" {
+		t.Errorf("Expected stdout 'from stdin', but got: %s", stdout)
+	}
+}
+
+// TestShelloutTimeout verifies long running commands are killed.
+func TestShelloutTimeout(t *testing.T) {
+	start := time.Now()
+	_, _, err := ShelloutWithOptions(ShellOptions{Timeout: 200 * time.Millisecond}, "node", "-e", "while (true) {}")
+	if err != ErrTimeout {
+		t.Errorf("Expected ErrTimeout, but got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected command to be killed quickly, took %v", elapsed)
+	}
+}
@@ -25,6 +25,9 @@ export const EXECUTION_TYPES = {
   STQ: 'stq'
 } as const;
 
+// STQ problem loaded from the server's challenge bank
+export const DEFAULT_PROBLEM_ID = 'int-into-string';
+
 // Theme constants
 export const THEMES = {
   LIGHT: 'light',
@@ -41,12 +41,33 @@ export async function fetchApiPost<T>(
   }
 }
 
+export async function fetchApiGet<T>(path: string): Promise<T> {
+  // path is absolute, so drop the trailing slash of the base URL
+  const response = await fetch(envAPI.replace(/\/$/, "") + path, {
+    headers: {
+      "X-Requested-With": "XMLHttpRequest",
+      Accept: "application/json",
+    },
+  });
+
+  const contentType = response.headers.get("content-type");
+  if (!contentType || !contentType.includes("application/json")) {
+    throw new Error("Invalid response type received from server");
+  }
+  if (!response.ok) {
+    throw new Error(`Server error: ${response.status}`);
+  }
+  return (await response.json()) as T;
+}
+
 function validatePayload(payload: any): boolean {
   // Validate payload structure and content
   if (typeof payload !== "object" || payload === null) return false;
   if (typeof payload.txt !== "string") return false;
   if (typeof payload.lang !== "string") return false;
   if (typeof payload.type !== "string") return false;
+  if (payload.problemId !== undefined && typeof payload.problemId !== "string") return false;
+  if (payload.mode !== undefined && !["run", "submit"].includes(payload.mode)) return false;
 
   // Additional validation
   if (payload.txt.length > 10000) return false; // 10KB limit
@@ -1,9 +1,47 @@
 /// <reference types="svelte" />
 /// <reference types="vite/client" />
 
+interface TestVerdict {
+  index: number;
+  verdict: string;
+  passed: boolean;
+  hidden?: boolean;
+  input: string;
+  expected: string;
+  actual: string;
+  stdout?: string;
+  stderr?: string;
+  diff?: string[];
+  message?: string;
+  durationMs: number;
+}
+
+interface Verdict {
+  mode: "run" | "submit";
+  verdict: string;
+  passed: number;
+  total: number;
+  score: number;
+  tests: TestVerdict[];
+  compileOutput?: string;
+  firstFailure?: TestVerdict;
+}
+
 interface FetchData {
   txt: string;
   out: string;
   errout: string;
   statusCode: number;
+  verdict?: Verdict;
+}
+
+// Problem as served by /api/problems/{id}; statementHtml is rendered and
+// sanitized on the server
+interface ProblemData {
+  id: string;
+  title: string;
+  statement: string;
+  statementHtml: string;
+  function: string;
+  version: number;
 }